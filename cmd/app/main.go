@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -16,7 +17,14 @@ import (
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/lib/logger"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/services"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/authz"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/lockout"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/notification"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/oidc"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/password"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/registration"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/webhook"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/swagger"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
@@ -47,10 +55,170 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load the access token signing keys, creating them on first start
+	keyRing, err := middleware.NewKeyRing(cosmosService, middleware.KeyRingConfig{
+		Algorithm:     cfg.JWTAlgorithm,
+		Rotation:      cfg.JWTKeyRotation,
+		VerifyFor:     cfg.JWTExpiration + time.Minute, // Token lifetime plus clock skew
+		Issuer:        cfg.JWTIssuer,
+		EncryptionKey: cfg.JWTKeyEncryptionKey,
+	}, log)
+	if err != nil {
+		log.Error("failed to initialize signing keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if err := keyRing.Load(context.Background()); err != nil {
+		log.Error("failed to load signing keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Initialize email notifications
+	renderer, err := notification.NewRenderer(cfg.Notifications.DefaultLocale)
+	if err != nil {
+		log.Error("failed to initialize email templates", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	var emailSender notification.Sender = notification.NewLogSender(log)
+	if cfg.SMTP.Host != "" {
+		emailSender = notification.NewSMTPSender(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	}
+	outbox := notification.NewOutbox(cosmosService, emailSender, cfg.Notifications.OutboxInterval, cfg.Notifications.OutboxMaxAttempts, log)
+	notifier := notification.NewNotifier(cosmosService, renderer, cfg.FrontendURL, log)
+	digestWorker := notification.NewDigestWorker(cosmosService, renderer, cfg.FrontendURL, cfg.Notifications.DigestHour, log)
+	adminDigestWorker := notification.NewAdminDigestWorker(cosmosService, renderer, cfg.FrontendURL, cfg.Notifications.DigestHour, cfg.Notifications.AdminDigestSLA, log)
+
+	webhookService := webhook.NewService(cosmosService, webhook.NewSender(cfg.Webhooks.Timeout), cfg.Webhooks.Interval, cfg.Webhooks.MaxAttempts, log)
+
+	// Start background workers
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Go(func() {
+		keyRing.Run(workersCtx)
+	})
+	workers.Go(func() {
+		outbox.Run(workersCtx)
+	})
+	workers.Go(func() {
+		digestWorker.Run(workersCtx)
+	})
+	workers.Go(func() {
+		adminDigestWorker.Run(workersCtx)
+	})
+	workers.Go(func() {
+		webhookService.Run(workersCtx)
+	})
+	if cfg.Notifications.StatusQueue != "" {
+		workers.Go(func() {
+			if err := serviceBusService.Consume(workersCtx, cfg.Notifications.StatusQueue, notifier.HandleStatusChangedMessage); err != nil {
+				log.Error("status change consumer failed", slog.String("error", err.Error()))
+			}
+		})
+	}
+	if cfg.Notifications.EscalationQueue != "" {
+		workers.Go(func() {
+			if err := serviceBusService.Consume(workersCtx, cfg.Notifications.EscalationQueue, notifier.HandleEscalationMessage); err != nil {
+				log.Error("escalation consumer failed", slog.String("error", err.Error()))
+			}
+		})
+	}
+
+	// Revoked access tokens and token versions
+	var revocations middleware.RevocationStore = middleware.NewCosmosRevocationStore(cosmosService)
+	if cfg.JWTRevocationStore == "memory" {
+		revocations = middleware.NewMemoryRevocationStore()
+	}
+
+	registrationPolicy, err := registration.NewPolicy(cfg.Registration.AllowedDomains, cfg.Registration.DomainRoles)
+	if err != nil {
+		log.Error("invalid registration domain configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	passwordPolicy, err := password.NewPolicy(cfg.Password.MinLength, cfg.Password.MinClasses, cfg.Password.CheckBreached)
+	if err != nil {
+		log.Error("invalid password policy configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if cfg.Password.CheckBreached && cfg.Password.BreachedFile != "" {
+		breachedFile, err := os.Open(cfg.Password.BreachedFile)
+		if err == nil {
+			err = passwordPolicy.LoadBreached(breachedFile)
+			breachedFile.Close()
+		}
+		if err != nil {
+			log.Error("failed to load breached password list", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	// Failed login tracking and per-IP login rate limiting
+	var lockoutStore lockout.Store = lockout.NewCosmosStore(cosmosService)
+	if cfg.Login.LockoutStore == "memory" {
+		lockoutStore = lockout.NewMemoryStore()
+	}
+	loginGuard := lockout.NewGuard(lockoutStore, lockout.Policy{
+		MaxFailures: cfg.Login.MaxFailures,
+		BaseLockout: cfg.Login.LockoutBase,
+		MaxLockout:  cfg.Login.LockoutMax,
+		ResetAfter:  cfg.Login.ResetAfter,
+	}, log)
+	// The IP limit is counted per instance, see LOGIN_IP_LIMIT in the README
+	loginLimiter := middleware.NewRateLimiter(cfg.Login.IPLimit, cfg.Login.IPWindow)
+
+	// University single sign-on
+	var oidcProvider *oidc.Provider
+	if cfg.OIDC.IssuerURL != "" {
+		oidcProvider, err = oidc.NewProvider(oidc.Config{
+			IssuerURL:     cfg.OIDC.IssuerURL,
+			ClientID:      cfg.OIDC.ClientID,
+			ClientSecret:  cfg.OIDC.ClientSecret,
+			RedirectURL:   cfg.OIDC.RedirectURL,
+			Scopes:        cfg.OIDC.Scopes,
+			GroupsClaim:   cfg.OIDC.GroupsClaim,
+			AdminGroups:   cfg.OIDC.AdminGroups,
+			StudentGroups: cfg.OIDC.StudentGroups,
+		}, nil, log)
+		if err != nil {
+			log.Error("invalid OIDC configuration", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(cosmosService, cfg.JWTSecret, log)
-	complaintHandler := handlers.NewComplaintsHandler(cosmosService, serviceBusService, log)
+	authHandler := handlers.NewAuthHandler(cosmosService, keyRing, revocations, notifier, loginGuard, handlers.AuthConfig{
+		Registration:     registrationPolicy,
+		PasswordPolicy:   passwordPolicy,
+		AccessTokenTTL:   cfg.JWTExpiration,
+		RefreshTokenTTL:  cfg.JWTRefreshExpiration,
+		PasswordResetTTL: cfg.PasswordResetExpiration,
+		VerificationTTL:  cfg.VerificationExpiration,
+		MFAIssuer:        cfg.MFAIssuer,
+		RequireAdminMFA:  cfg.RequireAdminMFA,
+		OIDC:             oidcProvider,
+		OIDCLinkByEmail:  cfg.OIDC.LinkByEmail,
+		FrontendURL:      cfg.FrontendURL,
+	}, log)
+	complaintHandler := handlers.NewComplaintsHandler(cosmosService, serviceBusService, notifier, webhookService, handlers.ComplaintsConfig{
+		Categories:      cfg.ComplaintCategories,
+		EscalationQueue: cfg.Notifications.EscalationQueue,
+		EscalateAfter:   cfg.Notifications.AdminDigestSLA,
+	}, log)
 	userHandler := handlers.NewUserHandler(cosmosService, log)
+	notificationsHandler := handlers.NewNotificationsHandler(cosmosService, log)
+	apiKeysHandler := handlers.NewAPIKeysHandler(cosmosService, log)
+	sessionsHandler := handlers.NewSessionsHandler(cosmosService, log)
+	webhooksHandler := handlers.NewWebhooksHandler(cosmosService, webhookService, log)
+	jwksHandler := handlers.NewJWKSHandler(keyRing, log)
+	denylistHandler := handlers.NewDenylistHandler(cosmosService, log)
+	adminUsersHandler := handlers.NewAdminUsersHandler(cosmosService, revocations, notifier, loginGuard, handlers.AdminUsersConfig{
+		PasswordResetTTL: cfg.PasswordResetExpiration,
+		Categories:       cfg.ComplaintCategories,
+	}, log)
+
+	// Creating and liking complaints can require a verified email address
+	requireVerified := func(next http.Handler) http.Handler { return next }
+	if cfg.RequireVerifiedEmail {
+		requireVerified = middleware.RequireVerifiedEmail(log)
+	}
 
 	// Setup router
 	r := chi.NewRouter()
@@ -71,33 +239,115 @@ func main() {
 
 	// Public routes
 	r.Post("/api/auth/register", authHandler.Register)
-	r.Post("/api/auth/login", authHandler.Login)
-	r.Post("/api/auth/logout", authHandler.Logout)
+	r.With(middleware.RateLimit(loginLimiter, log)).Post("/api/auth/login", authHandler.Login)
+	// Logout and refresh are authenticated by their cookies, so they need the CSRF token too
+	r.With(middleware.RequireCSRF(log)).Post("/api/auth/logout", authHandler.Logout)
+	r.With(middleware.RequireCSRF(log)).Post("/api/auth/refresh", authHandler.Refresh)
+	r.Post("/api/auth/forgot-password", authHandler.ForgotPassword)
+	r.Post("/api/auth/reset-password", authHandler.ResetPassword)
+	r.Post("/api/auth/verify-email", authHandler.VerifyEmail)
+	r.Get("/api/auth/csrf-token", authHandler.CSRFToken)
+	r.With(middleware.RateLimit(loginLimiter, log)).Post("/api/auth/mfa/verify", authHandler.VerifyMFA)
+	r.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
+	if oidcProvider != nil {
+		r.Get("/api/auth/oidc/login", authHandler.OIDCLogin)
+		r.Get("/api/auth/oidc/callback", authHandler.OIDCCallback)
+	}
 	// Health check endpoint (public)
 	r.Get("/health", swagger.HealthCheck)
 
 	// Protected routes (require authentication)
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.RequireAuth(cfg.JWTSecret, log))
+		r.Use(middleware.RequireAuth(middleware.AuthOptions{Keys: keyRing, Revocations: revocations, Users: cosmosService, Sessions: cosmosService, APIKeys: cosmosService}, log))
+		r.Use(middleware.RequireCSRF(log))
+
+		// Session routes
+		r.Post("/api/auth/logout-all", authHandler.LogoutAll)
+		r.Post("/api/auth/resend-verification", authHandler.ResendVerification)
+
+		// Two-factor authentication
+		r.Post("/api/auth/mfa/enroll", authHandler.EnrollMFA)
+		r.Post("/api/auth/mfa/confirm", authHandler.ConfirmMFA)
+		r.Post("/api/auth/mfa/disable", authHandler.DisableMFA)
+		r.Post("/api/auth/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes)
 
 		// User routes
 		r.Get("/api/users/me", userHandler.GetUserInfo)
 		r.Put("/api/users/me", userHandler.UpdateUserProfile)
+		r.With(middleware.RateLimit(loginLimiter, log)).Delete("/api/users/me", authHandler.DeleteAccount)
+		r.With(middleware.RateLimit(loginLimiter, log)).Post("/api/users/me/password", authHandler.ChangePassword)
+		r.With(middleware.RateLimit(loginLimiter, log)).Post("/api/users/me/email", authHandler.ChangeEmail)
+		r.Post("/api/users/me/email/confirm", authHandler.ConfirmEmailChange)
+		r.Get("/api/users/me/export", authHandler.ExportData)
+		r.Get("/api/users/me/notification-preferences", notificationsHandler.GetNotificationPreferences)
+		r.Put("/api/users/me/notification-preferences", notificationsHandler.UpdateNotificationPreferences)
+
+		// Sessions on other devices
+		r.Get("/api/users/me/sessions", sessionsHandler.GetSessions)
+		r.Delete("/api/users/me/sessions/{id}", sessionsHandler.DeleteSession)
+
+		// Personal API keys
+		r.Post("/api/users/me/api-keys", apiKeysHandler.CreateAPIKey)
+		r.Get("/api/users/me/api-keys", apiKeysHandler.GetAPIKeys)
+		r.Delete("/api/users/me/api-keys/{id}", apiKeysHandler.RevokeAPIKey)
+
+		// Notification inbox routes
+		r.Get("/api/notifications", notificationsHandler.GetNotifications)
+		r.Post("/api/notifications/read-all", notificationsHandler.MarkAllNotificationsRead)
+		r.Post("/api/notifications/{id}/read", notificationsHandler.MarkNotificationRead)
 
 		// Complaint routes
-		r.Post("/api/complaints", complaintHandler.CreateComplaint)
+		r.With(requireVerified).Post("/api/complaints", complaintHandler.CreateComplaint)
 		r.Get("/api/complaints", complaintHandler.GetComplaints)
 		r.Get("/api/complaints/approved", complaintHandler.GetApprovedComplaints)
 		r.Delete("/api/complaints/{id}", complaintHandler.DeleteComplaint)
-		r.Post("/api/complaints/{id}/like", complaintHandler.LikeComplaint)
+		r.With(requireVerified).Post("/api/complaints/{id}/like", complaintHandler.LikeComplaint)
 		r.Delete("/api/complaints/{id}/like", complaintHandler.UnlikeComplaint)
 
-		// Admin-only routes
+		// Privileged routes, each guarded by a permission of the user's role
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.RequireAdmin(log))
+			if cfg.RequireAdminMFA {
+				r.Use(middleware.RequireMFA(log))
+			}
+			complaintsRead := middleware.RequirePermission(authz.ComplaintsRead, log)
+			complaintsModerate := middleware.RequirePermission(authz.ComplaintsModerate, log)
+			usersRead := middleware.RequirePermission(authz.UsersRead, log)
+			usersManage := middleware.RequirePermission(authz.UsersManage, log)
+			auditRead := middleware.RequirePermission(authz.AuditRead, log)
+			webhooksManage := middleware.RequirePermission(authz.WebhooksManage, log)
 
-			r.Get("/api/admin/complaints", complaintHandler.GetAllComplaintsAdmin)
-			r.Put("/api/complaints/{id}", complaintHandler.UpdateComplaint)
+			r.With(complaintsRead).Get("/api/admin/complaints", complaintHandler.GetAllComplaintsAdmin)
+			r.With(complaintsModerate).Put("/api/complaints/{id}", complaintHandler.UpdateComplaint)
+
+			// User management
+			r.With(usersRead).Get("/api/admin/users", adminUsersHandler.GetUsers)
+			r.With(usersRead).Get("/api/admin/users/{id}", adminUsersHandler.GetUser)
+			r.With(usersRead).Get("/api/admin/users/{id}/complaints", adminUsersHandler.GetUserComplaints)
+			r.With(usersManage).Put("/api/admin/users/{id}/role", adminUsersHandler.UpdateUserRole)
+			r.With(usersManage).Post("/api/admin/users/{id}/suspend", adminUsersHandler.SuspendUser)
+			r.With(usersManage).Post("/api/admin/users/{id}/reactivate", adminUsersHandler.ReactivateUser)
+			r.With(usersManage).Post("/api/admin/users/{id}/bans", adminUsersHandler.BanUser)
+			r.With(usersManage).Delete("/api/admin/users/{id}/bans/{kind}", adminUsersHandler.LiftBan)
+			r.With(usersManage).Post("/api/admin/users/{id}/password-reset", adminUsersHandler.ForcePasswordReset)
+			r.With(usersManage).Post("/api/admin/users/{id}/unlock", adminUsersHandler.UnlockUser)
+			r.With(auditRead).Get("/api/admin/audit-log", adminUsersHandler.GetAuditLog)
+
+			// Registration denylist
+			r.With(usersManage).Get("/api/admin/email-denylist", denylistHandler.GetDeniedEmails)
+			r.With(usersManage).Post("/api/admin/email-denylist", denylistHandler.AddDeniedEmail)
+			r.With(usersManage).Delete("/api/admin/email-denylist/{email}", denylistHandler.RemoveDeniedEmail)
+
+			// Outgoing webhook subscriptions
+			r.Group(func(r chi.Router) {
+				r.Use(webhooksManage)
+				r.Post("/api/admin/webhooks", webhooksHandler.CreateWebhook)
+				r.Get("/api/admin/webhooks", webhooksHandler.GetWebhooks)
+				r.Get("/api/admin/webhooks/{id}", webhooksHandler.GetWebhook)
+				r.Put("/api/admin/webhooks/{id}", webhooksHandler.UpdateWebhook)
+				r.Delete("/api/admin/webhooks/{id}", webhooksHandler.DeleteWebhook)
+				r.Get("/api/admin/webhooks/{id}/deliveries", webhooksHandler.GetWebhookDeliveries)
+				r.Post("/api/admin/webhooks/{id}/deliveries/{deliveryId}/redeliver", webhooksHandler.RedeliverWebhook)
+			})
 		})
 	})
 
@@ -133,5 +383,14 @@ func main() {
 		log.Error("server forced to shutdown", slog.String("error", err.Error()))
 	}
 
+	// Stop background workers after the server so in-flight requests can still queue work
+	stopWorkers()
+	workers.Wait()
+
+	// Close the cached Service Bus senders once nothing can send any more
+	if err := serviceBusService.Close(ctx); err != nil {
+		log.Error("failed to close service bus", slog.String("error", err.Error()))
+	}
+
 	log.Info("server stopped gracefully")
 }
@@ -3,17 +3,78 @@ package config
 import (
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	ENV                  string         `env:"ENV" env-default:"development"`
-	HTTPPort             string         `env:"HTTP_PORT" env-default:"8080"`
-	CORSAllowedOrigins   []string       `env:"CORS_ALLOWED_ORIGINS" env-separator:","`
-	CosmosDB             CosmosDBConfig `env-prefix:"COSMOS_"`
-	ServiceBusConnection string         `env:"SERVICE_BUS_CONNECTION" env-required:"true"`
-	JWTSecret            string         `env:"JWT_SECRET" env-required:"true"`
+	ENV                     string             `env:"ENV" env-default:"development"`
+	HTTPPort                string             `env:"HTTP_PORT" env-default:"8080"`
+	CORSAllowedOrigins      []string           `env:"CORS_ALLOWED_ORIGINS" env-separator:","`
+	CosmosDB                CosmosDBConfig     `env-prefix:"COSMOS_"`
+	ServiceBusConnection    string             `env:"SERVICE_BUS_CONNECTION" env-required:"true"`
+	JWTAlgorithm            string             `env:"JWT_ALGORITHM" env-default:"EdDSA"` // "EdDSA" or "RS256"
+	JWTKeyRotation          time.Duration      `env:"JWT_KEY_ROTATION" env-default:"720h"`
+	JWTKeyEncryptionKey     string             `env:"JWT_KEY_ENCRYPTION_KEY" env-required:"true"` // Base64 of 32 random bytes; encrypts the stored signing keys
+	JWTIssuer               string             `env:"JWT_ISSUER" env-default:"student-complaint-portal"`
+	JWTExpiration           time.Duration      `env:"JWT_EXPIRATION" env-default:"15m"`
+	JWTRefreshExpiration    time.Duration      `env:"JWT_REFRESH_EXPIRATION" env-default:"720h"`
+	JWTRevocationStore      string             `env:"JWT_REVOCATION_STORE" env-default:"cosmos"` // "cosmos" or "memory" (single instance only)
+	PasswordResetExpiration time.Duration      `env:"PASSWORD_RESET_EXPIRATION" env-default:"1h"`
+	VerificationExpiration  time.Duration      `env:"EMAIL_VERIFICATION_EXPIRATION" env-default:"48h"`
+	RequireVerifiedEmail    bool               `env:"REQUIRE_EMAIL_VERIFICATION" env-default:"true"`                                               // Block unverified users from creating and liking complaints
+	RequireAdminMFA         bool               `env:"REQUIRE_ADMIN_MFA" env-default:"false"`                                                       // Admin routes require a session started with two-factor authentication
+	MFAIssuer               string             `env:"MFA_ISSUER" env-default:"Student Complaint Portal"`                                           // Account issuer shown by authenticator apps
+	ComplaintCategories     []string           `env:"COMPLAINT_CATEGORIES" env-separator:"," env-default:"general,academic,facilities,it,housing"` // The first is the default for new complaints
+	Registration            RegistrationConfig `env-prefix:"REGISTRATION_"`
+	Login                   LoginConfig        `env-prefix:"LOGIN_"`
+	Password                PasswordConfig     `env-prefix:"PASSWORD_"`
+	OIDC                    OIDCConfig         `env-prefix:"OIDC_"`
+	FrontendURL             string             `env:"FRONTEND_URL" env-default:"http://localhost:4200"`
+	SMTP                    SMTPConfig         `env-prefix:"SMTP_"`
+	Notifications           NotifyConfig       `env-prefix:"NOTIFY_"`
+	Webhooks                WebhookConfig      `env-prefix:"WEBHOOK_"`
+}
+
+// RegistrationConfig restricts who may register
+type RegistrationConfig struct {
+	// AllowedDomains lists email domains ("university.edu") and subdomain wildcards ("*.university.edu"); empty allows all
+	AllowedDomains []string `env:"ALLOWED_DOMAINS" env-separator:","`
+	// DomainRoles maps domain patterns to the role of new accounts, e.g. "staff.university.edu:admin"
+	DomainRoles map[string]string `env:"DOMAIN_ROLES" env-separator:","`
+}
+
+// PasswordConfig is the policy for new passwords
+type PasswordConfig struct {
+	MinLength     int    `env:"MIN_LENGTH" env-default:"8"`
+	MinClasses    int    `env:"MIN_CLASSES" env-default:"1"`       // Of lower case, upper case, digits and symbols
+	CheckBreached bool   `env:"CHECK_BREACHED" env-default:"true"` // Reject passwords from the bundled breached password list
+	BreachedFile  string `env:"BREACHED_FILE"`                     // Extra breached password list, one per line
+}
+
+// LoginConfig configures brute-force protection of the login endpoint
+type LoginConfig struct {
+	MaxFailures  int           `env:"MAX_FAILURES" env-default:"5"`       // Failed logins before an account is locked
+	LockoutBase  time.Duration `env:"LOCKOUT_BASE" env-default:"1m"`      // First lockout, doubled with every further failure
+	LockoutMax   time.Duration `env:"LOCKOUT_MAX" env-default:"1h"`       // Longest lockout
+	ResetAfter   time.Duration `env:"RESET_AFTER" env-default:"24h"`      // Failed logins are forgotten after this long
+	LockoutStore string        `env:"LOCKOUT_STORE" env-default:"cosmos"` // "cosmos" or "memory" (single instance only)
+	IPLimit      int           `env:"IP_LIMIT" env-default:"20"`          // Login requests per client IP and window on each instance
+	IPWindow     time.Duration `env:"IP_WINDOW" env-default:"15m"`
+}
+
+// OIDCConfig configures single sign-on with the university identity provider. Empty IssuerURL disables it.
+type OIDCConfig struct {
+	IssuerURL     string   `env:"ISSUER_URL"`
+	ClientID      string   `env:"CLIENT_ID"`
+	ClientSecret  string   `env:"CLIENT_SECRET"`
+	RedirectURL   string   `env:"REDIRECT_URL"` // The portal's /api/auth/oidc/callback URL registered at the provider
+	Scopes        []string `env:"SCOPES" env-separator:"," env-default:"email,profile"`
+	GroupsClaim   string   `env:"GROUPS_CLAIM" env-default:"groups"`
+	AdminGroups   []string `env:"ADMIN_GROUPS" env-separator:","`
+	StudentGroups []string `env:"STUDENT_GROUPS" env-separator:","`  // Empty lets every user who is not an admin sign in as a student
+	LinkByEmail   bool     `env:"LINK_BY_EMAIL" env-default:"false"` // Link existing accounts to provider users with the same verified email
 }
 
 type CosmosDBConfig struct {
@@ -22,6 +83,33 @@ type CosmosDBConfig struct {
 	Database string `env:"DATABASE" env-default:"complaintportal"`
 }
 
+// SMTPConfig configures outgoing email. Emails are only logged when Host is empty.
+type SMTPConfig struct {
+	Host     string `env:"HOST"`
+	Port     int    `env:"PORT" env-default:"587"`
+	Username string `env:"USERNAME"`
+	Password string `env:"PASSWORD"`
+	From     string `env:"FROM" env-default:"Student Complaint Portal <no-reply@complaintportal.local>"`
+}
+
+// NotifyConfig configures complaint notifications
+type NotifyConfig struct {
+	StatusQueue       string        `env:"STATUS_QUEUE" env-default:"complaint-status-changed"`  // Empty disables status change emails
+	EscalationQueue   string        `env:"ESCALATION_QUEUE" env-default:"complaint-escalations"` // Empty disables escalations
+	DefaultLocale     string        `env:"DEFAULT_LOCALE" env-default:"en"`
+	OutboxInterval    time.Duration `env:"OUTBOX_INTERVAL" env-default:"30s"`
+	OutboxMaxAttempts int           `env:"OUTBOX_MAX_ATTEMPTS" env-default:"8"`
+	DigestHour        int           `env:"DIGEST_HOUR" env-default:"8"`        // UTC hour at which daily digests are sent
+	AdminDigestSLA    time.Duration `env:"ADMIN_DIGEST_SLA" env-default:"72h"` // Pending complaints older than this are reported as overdue and escalated
+}
+
+// WebhookConfig configures outgoing webhook deliveries
+type WebhookConfig struct {
+	Interval    time.Duration `env:"INTERVAL" env-default:"15s"`
+	Timeout     time.Duration `env:"TIMEOUT" env-default:"10s"`
+	MaxAttempts int           `env:"MAX_ATTEMPTS" env-default:"8"`
+}
+
 func MustLoad() *Config {
 	var cfg Config
 	log := slog.Default()
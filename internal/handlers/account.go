@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DeleteAccountRequest represents the delete account request body
+type DeleteAccountRequest struct {
+	Password string `json:"password,omitempty"` // Required unless the account has no password
+	Code     string `json:"code,omitempty"`     // Authenticator or recovery code, required with two-factor authentication
+}
+
+// ExportProfile is the profile in a data export. Password, token and two-factor secrets are left out.
+type ExportProfile struct {
+	ID              string                         `json:"id"`
+	Email           string                         `json:"email"`
+	PendingEmail    string                         `json:"pendingEmail,omitempty"`
+	Name            string                         `json:"name"`
+	UserName        string                         `json:"username"`
+	Role            string                         `json:"role"`
+	Locale          string                         `json:"locale,omitempty"`
+	Categories      []string                       `json:"categories,omitempty"`
+	CreatedAt       time.Time                      `json:"createdAt"`
+	EmailVerified   bool                           `json:"emailVerified"`
+	EmailVerifiedAt *time.Time                     `json:"emailVerifiedAt,omitempty"`
+	MFAEnabled      bool                           `json:"mfaEnabled"`
+	SingleSignOn    bool                           `json:"singleSignOn"` // Linked to the university identity provider
+	Suspension      *models.Restriction            `json:"suspension,omitempty"`
+	Bans            map[string]*models.Restriction `json:"bans,omitempty"`
+}
+
+// ExportComment is a comment the user wrote, in a data export
+type ExportComment struct {
+	ComplaintID string    `json:"complaintId"`
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// exportFile is one JSON file of a data export archive
+type exportFile struct {
+	name string
+	data any
+}
+
+// ExportData handles GET requests to download the current user's personal data
+// @Summary Export personal data
+// @Description Download a zip archive with the profile, complaints, comments, likes, notifications, notification preferences, sessions and API keys of the authenticated user
+// @Tags users
+// @Security Bearer
+// @Produce application/zip
+// @Success 200 {file} file "Zip archive of JSON files"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/export [get]
+func (h *AuthHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	files, err := h.exportFiles(r, user)
+	if err != nil {
+		h.log.Error("failed to collect personal data", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to export personal data", http.StatusInternalServerError)
+		return
+	}
+
+	// Build the archive in memory, so a failure can still be answered with an error status
+	var archive bytes.Buffer
+	if err := writeExportArchive(&archive, files); err != nil {
+		h.log.Error("failed to build data export", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to export personal data", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("personal data exported", slog.String("userId", user.ID), slog.Int("bytes", archive.Len()))
+
+	filename := "complaint-portal-export-" + time.Now().UTC().Format("2006-01-02") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := archive.WriteTo(w); err != nil {
+		h.log.Warn("failed to write data export", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// exportFiles collects the files of a user's data export
+func (h *AuthHandler) exportFiles(r *http.Request, user *models.User) ([]exportFile, error) {
+	ctx := r.Context()
+
+	complaints, err := h.cosmosService.GetComplaints(ctx, user.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	involving, err := h.cosmosService.GetComplaintsInvolvingUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := h.cosmosService.GetNotifications(ctx, user.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := h.cosmosService.GetNotificationPreferences(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		prefs = models.DefaultNotificationPreferences(user.ID)
+	}
+	prefs.WebhookSecret = ""
+	sessions, err := h.cosmosService.GetUserSessions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := h.cosmosService.GetUserAPIKeys(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Own complaints without the IDs of the users who liked them
+	complaintResponses := make([]*models.ComplaintResponse, 0, len(complaints))
+	for _, complaint := range complaints {
+		complaintResponses = append(complaintResponses, cosmos.ToComplaintResponse(&complaint, user.ID))
+	}
+
+	comments := []ExportComment{}
+	likes := []string{}
+	for _, complaint := range slices.Concat(complaints, involving) {
+		for _, comment := range complaint.Comments {
+			if comment.AdminID == user.ID {
+				comments = append(comments, ExportComment{ComplaintID: complaint.ID, Content: comment.Content, CreatedAt: comment.CreatedAt})
+			}
+		}
+		if slices.Contains(complaint.Likes, user.ID) {
+			likes = append(likes, complaint.ID)
+		}
+	}
+
+	currentID := currentSessionID(r)
+	sessionResponses := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		sessionResponses = append(sessionResponses, toSessionResponse(&session, currentID))
+	}
+	keyResponses := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		keyResponses = append(keyResponses, toAPIKeyResponse(&key))
+	}
+
+	return []exportFile{
+		{name: "profile.json", data: toExportProfile(user)},
+		{name: "complaints.json", data: complaintResponses},
+		{name: "comments.json", data: comments},
+		{name: "likes.json", data: likes},
+		{name: "notifications.json", data: notifications},
+		{name: "notification-preferences.json", data: prefs},
+		{name: "sessions.json", data: sessionResponses},
+		{name: "api-keys.json", data: keyResponses},
+	}, nil
+}
+
+// toExportProfile converts a user to the profile of a data export
+func toExportProfile(user *models.User) ExportProfile {
+	return ExportProfile{
+		ID:              user.ID,
+		Email:           user.Email,
+		PendingEmail:    user.PendingEmail,
+		Name:            user.Name,
+		UserName:        user.UserName,
+		Role:            user.Role,
+		Locale:          user.Locale,
+		Categories:      user.Categories,
+		CreatedAt:       user.CreatedAt,
+		EmailVerified:   user.EmailVerified(),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		MFAEnabled:      user.MFAEnabled(),
+		SingleSignOn:    user.OIDCSubject != "",
+		Suspension:      user.Suspension,
+		Bans:            user.Bans,
+	}
+}
+
+// writeExportArchive writes the files as indented JSON into a zip archive
+func writeExportArchive(w io.Writer, files []exportFile) error {
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// DeleteAccount handles DELETE requests to delete the current user's account
+// @Summary Delete account
+// @Description Delete the authenticated user's account and personal data. Pending complaints are deleted; reviewed complaints are kept without the author. Likes are removed and comments are kept without the author. Notifications, digest items, personal webhook deliveries, queued and sent emails, API keys and preferences are deleted
+// @Tags users
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body DeleteAccountRequest false "Password and two-factor code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Invalid Credentials"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me [delete]
+func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.log.Debug("failed to parse delete account request", slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Confirm with the same factors as a login. Single sign-on accounts have no password to ask for.
+	if user.PasswordHash != "" {
+		if req.Password == "" {
+			http.Error(w, "Password is required", http.StatusBadRequest)
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+			h.log.Info("account deletion with wrong password", slog.String("userId", user.ID), slog.String("ip", middleware.ClientIP(r)))
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+	}
+	if user.MFAEnabled() {
+		if req.Code == "" {
+			http.Error(w, "Two-factor code is required", http.StatusBadRequest)
+			return
+		}
+		// The account is deleted below, so the used code does not have to be saved
+		if useSecondFactor(user, req.Code, time.Now()) == "" {
+			http.Error(w, "Invalid code", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// Every step can be repeated, so the user can retry while the account still exists. The account and
+	// its tokens go last.
+	deleted, kept, err := h.erasePersonalData(r, user)
+	if err != nil {
+		h.log.Error("failed to erase personal data", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+	if err := revokeAllSessions(r.Context(), h.cosmosService, h.revocations, user.ID); err != nil {
+		h.log.Error("failed to revoke sessions of deleted account", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+	if err := h.cosmosService.DeleteUser(r.Context(), user.ID); err != nil && !errors.Is(err, cosmos.ErrUserNotFound) {
+		h.log.Error("failed to delete user", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+	h.clearAuthCookies(w)
+
+	// The audit log is kept, it only holds the user's ID
+	audit(r, h.cosmosService, h.log, models.AuditUserDeleted, user.ID, map[string]string{
+		"complaintsDeleted": strconv.Itoa(deleted),
+		"complaintsKept":    strconv.Itoa(kept),
+	})
+
+	h.log.Info("account deleted", slog.String("userId", user.ID), slog.Int("complaintsDeleted", deleted), slog.Int("complaintsKept", kept))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(`{"message":"Account deleted"}`)); err != nil {
+		h.log.Warn("Failed to write")
+	}
+}
+
+// erasePersonalData removes the user from complaints, notifications, digests, webhook deliveries, queued emails
+// and API keys. Pending complaints are deleted; reviewed complaints are part of the portal's record and are kept
+// under DeletedUserID.
+func (h *AuthHandler) erasePersonalData(r *http.Request, user *models.User) (deleted, kept int, err error) {
+	ctx := r.Context()
+
+	// Likes and comments on other users' complaints
+	involving, err := h.cosmosService.GetComplaintsInvolvingUser(ctx, user.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, complaint := range involving {
+		if !forgetUser(&complaint, user.ID) {
+			continue
+		}
+		if err := h.cosmosService.ReplaceComplaint(ctx, &complaint); err != nil && !errors.Is(err, cosmos.ErrComplaintNotFound) {
+			return 0, 0, err
+		}
+	}
+
+	complaints, err := h.cosmosService.GetComplaints(ctx, user.ID, "")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, complaint := range complaints {
+		if complaint.Status == models.StatusPending {
+			if err := h.cosmosService.DeleteComplaint(ctx, complaint.ID); err != nil && !errors.Is(err, cosmos.ErrComplaintNotFound) {
+				return 0, 0, err
+			}
+			deleted++
+			continue
+		}
+		forgetUser(&complaint, user.ID)
+		if err := h.cosmosService.ReassignComplaint(ctx, &complaint, models.DeletedUserID); err != nil {
+			return 0, 0, err
+		}
+		kept++
+	}
+
+	if err := h.cosmosService.DeleteUserNotifications(ctx, user.ID); err != nil {
+		return 0, 0, err
+	}
+	if err := h.cosmosService.DeleteNotificationPreferences(ctx, user.ID); err != nil {
+		return 0, 0, err
+	}
+	if err := h.cosmosService.DeleteUserWebhookDeliveries(ctx, user.ID); err != nil {
+		return 0, 0, err
+	}
+	if err := h.cosmosService.DeleteOutboxEmailsTo(ctx, user.Email, user.PendingEmail); err != nil {
+		return 0, 0, err
+	}
+	keys, err := h.cosmosService.GetUserAPIKeys(ctx, user.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, key := range keys {
+		if err := h.cosmosService.DeleteAPIKey(ctx, key.ID); err != nil && !errors.Is(err, cosmos.ErrAPIKeyNotFound) {
+			return 0, 0, err
+		}
+	}
+	if err := h.cosmosService.DeleteLoginAttempts(ctx, user.Email); err != nil {
+		return 0, 0, err
+	}
+	return deleted, kept, nil
+}
+
+// forgetUser removes the user's like from the complaint and replaces them as the author of their comments.
+// It reports whether the complaint changed.
+func forgetUser(complaint *models.Complaint, userID string) bool {
+	changed := false
+	if slices.Contains(complaint.Likes, userID) {
+		complaint.Likes = slices.DeleteFunc(complaint.Likes, func(id string) bool { return id == userID })
+		changed = true
+	}
+	complaint.LikeCount = len(complaint.Likes)
+	for i := range complaint.Comments {
+		if complaint.Comments[i].AdminID == userID {
+			complaint.Comments[i].AdminID = models.DeletedUserID
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForgetUser(t *testing.T) {
+	complaint := &models.Complaint{
+		ID:        "complaint-1",
+		UserID:    "owner",
+		Likes:     []string{"user-1", "leaving", "user-2"},
+		LikeCount: 3,
+		Comments: []models.Comment{
+			{ID: "comment-1", AdminID: "admin-1", Content: "Looking into it"},
+			{ID: "comment-2", AdminID: "leaving", Content: "Fixed"},
+		},
+	}
+
+	assert.True(t, forgetUser(complaint, "leaving"))
+	assert.Equal(t, []string{"user-1", "user-2"}, complaint.Likes)
+	assert.Equal(t, 2, complaint.LikeCount)
+	assert.Equal(t, "admin-1", complaint.Comments[0].AdminID)
+	assert.Equal(t, models.DeletedUserID, complaint.Comments[1].AdminID)
+	assert.Equal(t, "Fixed", complaint.Comments[1].Content)
+
+	// A second pass finds nothing left to change
+	assert.False(t, forgetUser(complaint, "leaving"))
+}
+
+func TestWriteExportArchive(t *testing.T) {
+	user := &models.User{
+		ID:           "user-1",
+		Email:        "student@university.edu",
+		PasswordHash: "$2a$10$secret",
+		MFASecret:    "JBSWY3DPEHPK3PXP",
+		CreatedAt:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	files := []exportFile{
+		{name: "profile.json", data: toExportProfile(user)},
+		{name: "likes.json", data: []string{"complaint-1"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeExportArchive(&buf, files))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 2)
+	assert.Equal(t, "profile.json", archive.File[0].Name)
+	assert.Equal(t, "likes.json", archive.File[1].Name)
+
+	entry, err := archive.File[0].Open()
+	require.NoError(t, err)
+	defer entry.Close()
+	var profile map[string]any
+	require.NoError(t, json.NewDecoder(entry).Decode(&profile))
+	assert.Equal(t, "student@university.edu", profile["email"])
+	assert.NotContains(t, profile, "passwordHash")
+	assert.NotContains(t, profile, "mfaSecret")
+}
@@ -0,0 +1,815 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/lockout"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/notification"
+)
+
+const (
+	// defaultListLimit and maxListLimit bound the number of users and audit entries per admin list request
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// AdminUsersConfig configures AdminUsersHandler
+type AdminUsersConfig struct {
+	PasswordResetTTL time.Duration // How long the link of a forced password reset stays valid
+	Categories       []string      // Complaint categories that can be assigned to department staff
+}
+
+// AdminUsersHandler handles admin requests to manage user accounts
+type AdminUsersHandler struct {
+	cosmosService *cosmos.Service
+	revocations   middleware.RevocationStore
+	notifier      *notification.Notifier
+	lockout       *lockout.Guard
+	config        AdminUsersConfig
+	log           *slog.Logger
+}
+
+// NewAdminUsersHandler creates a new AdminUsersHandler
+func NewAdminUsersHandler(cosmosService *cosmos.Service, revocations middleware.RevocationStore, notifier *notification.Notifier, lockout *lockout.Guard, config AdminUsersConfig, log *slog.Logger) *AdminUsersHandler {
+	const module = "adminUsersHandler"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &AdminUsersHandler{
+		cosmosService: cosmosService,
+		revocations:   revocations,
+		notifier:      notifier,
+		lockout:       lockout,
+		config:        config,
+		log:           log,
+	}
+}
+
+// AdminUserResponse is a user account as shown to admins, without password hashes and secrets
+type AdminUserResponse struct {
+	ID            string                         `json:"id"`
+	Email         string                         `json:"email"`
+	Name          string                         `json:"name"`
+	UserName      string                         `json:"username"`
+	Role          string                         `json:"role"`
+	Categories    []string                       `json:"categories,omitempty"`
+	EmailVerified bool                           `json:"emailVerified"`
+	MFAEnabled    bool                           `json:"mfaEnabled"`
+	SingleSignOn  bool                           `json:"singleSignOn"` // Linked to the university identity provider
+	Suspension    *models.Restriction            `json:"suspension,omitempty"`
+	Bans          map[string]*models.Restriction `json:"bans,omitempty"`
+	CreatedAt     time.Time                      `json:"createdAt"`
+}
+
+// toAdminUserResponse converts a user to the admin view
+func toAdminUserResponse(user *models.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		Name:          user.Name,
+		UserName:      user.UserName,
+		Role:          user.Role,
+		Categories:    user.Categories,
+		EmailVerified: user.EmailVerified(),
+		MFAEnabled:    user.MFAEnabled(),
+		SingleSignOn:  user.OIDCSubject != "",
+		Suspension:    user.Suspension,
+		Bans:          user.Bans,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+// UpdateRoleRequest represents the change role request body
+type UpdateRoleRequest struct {
+	Role       string   `json:"role"`
+	Categories []string `json:"categories,omitempty"` // Required for department staff, ignored for other roles
+}
+
+// SuspendUserRequest represents the suspend user request body
+type SuspendUserRequest struct {
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Omit to suspend until reactivated
+}
+
+// BanUserRequest represents the ban user request body
+type BanUserRequest struct {
+	Kind      string     `json:"kind"` // "posting" or "liking"
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Omit to ban until lifted
+}
+
+// GetUsers handles GET requests to list and search user accounts
+// @Summary List users (admin)
+// @Description List users, newest first. q searches the email, username and name; role filters by role
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param q query string false "Search text"
+// @Param role query string false "Role"
+// @Param limit query int false "Maximum number of users (default 50, at most 200)"
+// @Success 200 {array} AdminUserResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users [get]
+func (h *AdminUsersHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	role := r.URL.Query().Get("role")
+	if role != "" && !models.ValidRole(role) {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	limit, ok := listLimit(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := h.cosmosService.SearchUsers(r.Context(), query, role, limit)
+	if err != nil {
+		h.log.Error("failed to search users", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]AdminUserResponse, len(users))
+	for i := range users {
+		responses[i] = toAdminUserResponse(&users[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(responses); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+// GetUser handles GET requests to view a user account
+// @Summary Get a user (admin)
+// @Description Get a user account by ID
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} AdminUserResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id} [get]
+func (h *AdminUsersHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toAdminUserResponse(user)); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// GetUserComplaints handles GET requests to view the complaints of a user
+// @Summary Get a user's complaints (admin)
+// @Description Get the complaints of a user, optionally filtered by status
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Param status query string false "Complaint status"
+// @Success 200 {array} models.ComplaintResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id}/complaints [get]
+func (h *AdminUsersHandler) GetUserComplaints(w http.ResponseWriter, r *http.Request) {
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	complaints, err := h.cosmosService.GetComplaints(r.Context(), user.ID, status)
+	if err != nil {
+		h.log.Error("failed to get user complaints", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve complaints", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]models.ComplaintResponse, len(complaints))
+	for i, complaint := range complaints {
+		responses[i] = *cosmos.ToComplaintResponse(&complaint, adminId)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(responses); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// UpdateUserRole handles PUT requests to change the role of a user
+// @Summary Change a user's role (admin)
+// @Description Change the role and, for department staff, the complaint categories of a user. Access tokens
+// @Description issued before stop working, so the change applies at the next refresh
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body UpdateRoleRequest true "New role"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id}/role [put]
+func (h *AdminUsersHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to decode request body", slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	if req.Role != models.RoleStaff {
+		req.Categories = nil
+	} else if msg := h.validateCategories(req.Categories); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// Admins could otherwise lock themselves, and possibly everyone, out of user management
+	if r.PathValue("id") == adminId {
+		http.Error(w, "You cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	previousRole := user.Role
+	user.Role = req.Role
+	user.PendingRole = "" // The admin's choice replaces the role of an unverified domain
+	user.Categories = req.Categories
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "User was modified concurrently, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to change user role", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to change role", http.StatusInternalServerError)
+		return
+	}
+
+	// Access tokens carry the role. Refresh tokens stay valid and pick up the new role. A demoted user must not
+	// keep the old role's access, so the request fails and can be repeated if they cannot be revoked.
+	if _, err := h.revocations.RevokeAll(r.Context(), user.ID); err != nil {
+		h.log.Error("failed to revoke access tokens after role change", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Role was changed, but failed to log out the user's sessions", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("user role changed by admin", slog.String("userId", user.ID), slog.String("adminId", adminId), slog.String("from", previousRole), slog.String("to", user.Role))
+	audit(r, h.cosmosService, h.log, models.AuditUserRoleChanged, user.ID, map[string]string{
+		"from":       previousRole,
+		"to":         user.Role,
+		"categories": strings.Join(user.Categories, ","),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toAdminUserResponse(user)); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// SuspendUser handles POST requests to suspend a user account
+// @Summary Suspend a user (admin)
+// @Description Suspend a user account. The user is logged out of all devices and cannot log in until reactivated
+// @Description or until expiresAt. The user is notified by email
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body SuspendUserRequest true "Reason shown to the user"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id}/suspend [post]
+func (h *AdminUsersHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req SuspendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to decode request body", slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if msg := validateRestriction(req.Reason, req.ExpiresAt, time.Now()); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if r.PathValue("id") == adminId {
+		http.Error(w, "You cannot suspend your own account", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+	if user.Suspended() {
+		http.Error(w, "User is already suspended", http.StatusConflict)
+		return
+	}
+
+	user.Suspension = &models.Restriction{
+		Reason:    req.Reason,
+		CreatedBy: adminId,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "User was modified concurrently, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to suspend user", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to suspend user", http.StatusInternalServerError)
+		return
+	}
+
+	// RequireAuth rejects suspended users anyway, but this also covers deployments without the user lookup
+	if err := revokeAllSessions(r.Context(), h.cosmosService, h.revocations, user.ID); err != nil {
+		h.log.Error("failed to revoke sessions of suspended user", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+
+	h.log.Info("user suspended by admin", slog.String("userId", user.ID), slog.String("adminId", adminId))
+	audit(r, h.cosmosService, h.log, models.AuditUserSuspended, user.ID, restrictionDetails(models.RestrictionSuspension, user.Suspension))
+	h.notifyRestricted(r, user, models.RestrictionSuspension, user.Suspension)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toAdminUserResponse(user)); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// ReactivateUser handles POST requests to lift the suspension of a user account
+// @Summary Reactivate a user (admin)
+// @Description Lift the suspension of a user account
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} AdminUserResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id}/reactivate [post]
+func (h *AdminUsersHandler) ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+	if !user.Suspended() {
+		http.Error(w, "User is not suspended", http.StatusConflict)
+		return
+	}
+
+	user.Suspension = nil
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "User was modified concurrently, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to reactivate user", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to reactivate user", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("user reactivated by admin", slog.String("userId", user.ID), slog.String("adminId", adminId))
+	audit(r, h.cosmosService, h.log, models.AuditUserReactivated, user.ID, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toAdminUserResponse(user)); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// BanUser handles POST requests to ban a user from creating or liking complaints
+// @Summary Ban a user from an action (admin)
+// @Description Keep a user from creating ("posting") or liking ("liking") complaints until expiresAt or until lifted.
+// @Description A new ban of the same kind replaces the previous one. The user is notified by email
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body BanUserRequest true "Ban"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id}/bans [post]
+func (h *AdminUsersHandler) BanUser(w http.ResponseWriter, r *http.Request) {
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req BanUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to decode request body", slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidBan(req.Kind) {
+		http.Error(w, `Kind must be "posting" or "liking"`, http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if msg := validateRestriction(req.Reason, req.ExpiresAt, time.Now()); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	ban := &models.Restriction{
+		Reason:    req.Reason,
+		CreatedBy: adminId,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if user.Bans == nil {
+		user.Bans = make(map[string]*models.Restriction)
+	}
+	user.Bans[req.Kind] = ban
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "User was modified concurrently, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to ban user", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to ban user", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("user banned by admin", slog.String("userId", user.ID), slog.String("adminId", adminId), slog.String("kind", req.Kind))
+	audit(r, h.cosmosService, h.log, models.AuditUserBanned, user.ID, restrictionDetails(req.Kind, ban))
+	h.notifyRestricted(r, user, req.Kind, ban)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toAdminUserResponse(user)); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// LiftBan handles DELETE requests to lift a ban before it expires
+// @Summary Lift a ban (admin)
+// @Description Lift a user's "posting" or "liking" ban
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Param kind path string true "Ban kind"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id}/bans/{kind} [delete]
+func (h *AdminUsersHandler) LiftBan(w http.ResponseWriter, r *http.Request) {
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	kind := r.PathValue("kind")
+	if !models.ValidBan(kind) {
+		http.Error(w, `Kind must be "posting" or "liking"`, http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+	if _, banned := user.Bans[kind]; !banned {
+		http.Error(w, "User has no such ban", http.StatusNotFound)
+		return
+	}
+
+	delete(user.Bans, kind)
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "User was modified concurrently, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to lift ban", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to lift ban", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("ban lifted by admin", slog.String("userId", user.ID), slog.String("adminId", adminId), slog.String("kind", kind))
+	audit(r, h.cosmosService, h.log, models.AuditUserUnbanned, user.ID, map[string]string{"kind": kind})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toAdminUserResponse(user)); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// ForcePasswordReset handles POST requests to make a user choose a new password
+// @Summary Force a password reset (admin)
+// @Description Invalidate the user's password, log them out of all devices and email a password reset link
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 202 {object} map[string]string
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id}/password-reset [post]
+func (h *AdminUsersHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	// The old password stops working right away; the reset link is the only way back in
+	token, hash := middleware.GenerateToken()
+	expiresAt := time.Now().UTC().Add(h.config.PasswordResetTTL)
+	user.PasswordHash = ""
+	user.PasswordResetHash = hash
+	user.PasswordResetExpiresAt = &expiresAt
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "User was modified concurrently, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to force password reset", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := revokeAllSessions(r.Context(), h.cosmosService, h.revocations, user.ID); err != nil {
+		h.log.Error("failed to revoke sessions after forced password reset", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Password was invalidated, but failed to log out the user's sessions", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.notifier.SendPasswordReset(r.Context(), user, token, h.config.PasswordResetTTL); err != nil {
+		h.log.Error("failed to send forced password reset", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Password was invalidated, but failed to send the reset email", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("password reset forced by admin", slog.String("userId", user.ID), slog.String("adminId", adminId))
+	audit(r, h.cosmosService, h.log, models.AuditUserPasswordReset, user.ID, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	response := map[string]string{
+		"message": "Password reset email sent",
+		"userId":  user.ID,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+// UnlockUser handles POST requests to lift a login lockout
+// @Summary Unlock a user account (admin)
+// @Description Lift the lockout caused by failed logins and forget the failed attempts
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/users/{id}/unlock [post]
+func (h *AdminUsersHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	user, ok := h.targetUser(w, r)
+	if !ok {
+		return
+	}
+	userId := user.ID
+
+	if err := h.lockout.Unlock(r.Context(), user.Email); err != nil {
+		h.log.Error("failed to unlock user", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to unlock user", http.StatusInternalServerError)
+		return
+	}
+	h.log.Info("user unlocked by admin", slog.String("userId", userId), slog.String("adminId", adminId))
+	audit(r, h.cosmosService, h.log, models.AuditUserUnlocked, userId, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]string{
+		"message": "User unlocked",
+		"userId":  userId,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+// GetAuditLog handles GET requests to read the audit log of admin actions
+// @Summary Get the audit log (admin)
+// @Description List admin actions on user accounts, newest first
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param userId query string false "Only entries about this user"
+// @Param action query string false "Only entries with this action, e.g. user.suspended"
+// @Param limit query int false "Maximum number of entries (default 50, at most 200)"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/audit-log [get]
+func (h *AdminUsersHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit, ok := listLimit(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.cosmosService.GetAuditEntries(r.Context(), r.URL.Query().Get("userId"), r.URL.Query().Get("action"), limit)
+	if err != nil {
+		h.log.Error("failed to get audit log", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+// targetUser loads the user from the id path parameter, writing the error response if it fails
+func (h *AdminUsersHandler) targetUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	userId := r.PathValue("id")
+	user, err := h.cosmosService.GetUserByID(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to get user", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// validateCategories returns an error message if the categories cannot be assigned to department staff
+func (h *AdminUsersHandler) validateCategories(categories []string) string {
+	if len(categories) == 0 {
+		return "Department staff need at least one category"
+	}
+	for _, category := range categories {
+		if !slices.Contains(h.config.Categories, category) {
+			return "Invalid category: " + category
+		}
+	}
+	return ""
+}
+
+// validateRestriction returns an error message if a suspension or ban cannot be applied at now
+func validateRestriction(reason string, expiresAt *time.Time, now time.Time) string {
+	if reason == "" {
+		return "Reason is required"
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		return "expiresAt must be in the future"
+	}
+	return ""
+}
+
+// restrictionDetails returns the audit details of a suspension or ban
+func restrictionDetails(kind string, restriction *models.Restriction) map[string]string {
+	details := map[string]string{"kind": kind, "reason": restriction.Reason}
+	if restriction.ExpiresAt != nil {
+		details["expiresAt"] = restriction.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return details
+}
+
+// notifyRestricted emails the user about a new suspension or ban. The restriction already applies, so failures are only logged.
+func (h *AdminUsersHandler) notifyRestricted(r *http.Request, user *models.User, kind string, restriction *models.Restriction) {
+	if err := h.notifier.SendAccountRestricted(r.Context(), user, kind, restriction); err != nil {
+		h.log.Error("failed to notify user about restriction", slog.String("userId", user.ID), slog.String("kind", kind), slog.String("error", err.Error()))
+	}
+}
+
+// listLimit parses the limit query parameter of admin lists, writing the error response if it is invalid
+func listLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultListLimit, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return 0, false
+	}
+	return min(limit, maxListLimit), true
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantLimit int
+		wantOK    bool
+	}{
+		{name: "default", query: "", wantLimit: defaultListLimit, wantOK: true},
+		{name: "explicit", query: "?limit=10", wantLimit: 10, wantOK: true},
+		{name: "capped", query: "?limit=5000", wantLimit: maxListLimit, wantOK: true},
+		{name: "zero", query: "?limit=0", wantOK: false},
+		{name: "not a number", query: "?limit=all", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			limit, ok := listLimit(rec, httptest.NewRequest(http.MethodGet, "/api/admin/users"+tt.query, nil))
+
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.wantLimit, limit)
+			} else {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			}
+		})
+	}
+}
+
+func TestValidateCategories(t *testing.T) {
+	h := &AdminUsersHandler{config: AdminUsersConfig{Categories: []string{"general", "facilities", "it"}}}
+
+	assert.Empty(t, h.validateCategories([]string{"facilities", "it"}))
+	assert.NotEmpty(t, h.validateCategories(nil), "staff need a category")
+	assert.NotEmpty(t, h.validateCategories([]string{"parking"}))
+}
+
+func TestValidateRestriction(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(24 * time.Hour)
+
+	assert.Empty(t, validateRestriction("Spam", nil, now))
+	assert.Empty(t, validateRestriction("Spam", &future, now))
+	assert.NotEmpty(t, validateRestriction("", nil, now), "a reason is required")
+	assert.NotEmpty(t, validateRestriction("Spam", &past, now))
+}
+
+func TestRestrictionDetails(t *testing.T) {
+	expiresAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	details := restrictionDetails(models.RestrictionPosting, &models.Restriction{Reason: "Spam", ExpiresAt: &expiresAt})
+	assert.Equal(t, map[string]string{"kind": "posting", "reason": "Spam", "expiresAt": "2026-03-01T12:00:00Z"}, details)
+}
+
+func TestToAdminUserResponse(t *testing.T) {
+	now := time.Now()
+	user := &models.User{
+		ID:                "user-1",
+		Email:             "student@example.edu",
+		Role:              models.RoleStudent,
+		PasswordHash:      "$2a$10$hash",
+		PasswordResetHash: "reset",
+		MFASecret:         "SECRET",
+		MFAEnabledAt:      &now,
+		OIDCSubject:       "sub-1",
+		Suspension:        &models.Restriction{Reason: "Spam", CreatedBy: "admin-1", CreatedAt: now},
+	}
+
+	response := toAdminUserResponse(user)
+	assert.True(t, response.MFAEnabled)
+	assert.True(t, response.SingleSignOn)
+	assert.True(t, response.EmailVerified)
+	assert.Equal(t, "Spam", response.Suspension.Reason)
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/authz"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+const (
+	// defaultAPIKeyTTL is how long API keys created without an expiry are valid
+	defaultAPIKeyTTL = 90 * 24 * time.Hour
+	// maxAPIKeyTTL is the longest an API key may be valid
+	maxAPIKeyTTL = 365 * 24 * time.Hour
+	// maxAPIKeysPerUser limits how many API keys a user may have at once
+	maxAPIKeysPerUser = 20
+	// maxAPIKeyNameLength is the longest name of an API key
+	maxAPIKeyNameLength = 100
+)
+
+// APIKeysHandler handles requests to manage the current user's personal API keys
+type APIKeysHandler struct {
+	cosmosService *cosmos.Service
+	log           *slog.Logger
+}
+
+// NewAPIKeysHandler creates a new APIKeysHandler
+func NewAPIKeysHandler(cosmosService *cosmos.Service, log *slog.Logger) *APIKeysHandler {
+	const module = "apiKeysHandler"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &APIKeysHandler{
+		cosmosService: cosmosService,
+		log:           log,
+	}
+}
+
+// CreateAPIKeyRequest represents the create API key request body
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Defaults to 90 days, at most a year
+}
+
+// APIKeyResponse represents a personal API key. The key itself is only returned when it was created.
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Key        string     `json:"key,omitempty"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	LastUsedIP string     `json:"lastUsedIp,omitempty"`
+}
+
+// CreateAPIKey handles POST requests to create a personal API key
+// @Summary Create API key
+// @Description Create a personal API key for integrations. The key is only shown in this response; send it in the X-API-Key header
+// @Tags users
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body CreateAPIKeyRequest true "Name, scopes and expiry of the key"
+// @Success 201 {object} APIKeyResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Too Many API Keys"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/api-keys [post]
+func (h *APIKeysHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to parse API key request", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := validateAPIKeyRequest(&req, now); err != nil {
+		h.log.Debug("invalid API key request", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Admin keys are only useful, and only handed out, to roles with admin permissions
+	role, _ := middleware.GetRole(r.Context())
+	if slices.Contains(req.Scopes, middleware.ScopeAdminRead) && !authz.Privileged(role) {
+		h.log.Warn("admin API key requested without privileges", slog.String("userId", userId), slog.String("role", role))
+		http.Error(w, "The admin:read scope requires an admin role", http.StatusForbidden)
+		return
+	}
+
+	keys, err := h.cosmosService.GetUserAPIKeys(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to get API keys", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+	if len(keys) >= maxAPIKeysPerUser {
+		http.Error(w, fmt.Sprintf("You can have at most %d API keys, revoke one first", maxAPIKeysPerUser), http.StatusConflict)
+		return
+	}
+
+	key, hash, prefix := middleware.GenerateAPIKey()
+	apiKey := &models.APIKey{
+		ID:        hash,
+		UserID:    userId,
+		Name:      req.Name,
+		Prefix:    prefix,
+		Scopes:    req.Scopes,
+		ExpiresAt: *req.ExpiresAt,
+		CreatedAt: now,
+	}
+	if err := h.cosmosService.CreateAPIKey(r.Context(), apiKey); err != nil {
+		h.log.Error("failed to create API key", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("API key created", slog.String("userId", userId), slog.String("prefix", prefix), slog.Any("scopes", req.Scopes))
+
+	response := toAPIKeyResponse(apiKey)
+	response.Key = key
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// GetAPIKeys handles GET requests to list the current user's API keys
+// @Summary List API keys
+// @Description List the personal API keys of the authenticated user, newest first, without the keys themselves
+// @Tags users
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} APIKeyResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/api-keys [get]
+func (h *APIKeysHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.cosmosService.GetUserAPIKeys(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to get API keys", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve API keys", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		response = append(response, toAPIKeyResponse(&keys[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// RevokeAPIKey handles DELETE requests to revoke one of the current user's API keys
+// @Summary Revoke API key
+// @Description Revoke a personal API key of the authenticated user. Requests with the key fail right away
+// @Tags users
+// @Security Bearer
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "API Key Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/api-keys/{id} [delete]
+func (h *APIKeysHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	keyId := r.PathValue("id")
+	key, err := h.cosmosService.GetAPIKey(r.Context(), keyId)
+	if err != nil {
+		h.log.Error("failed to get API key", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+	// Keys of other users are reported as missing, so their IDs cannot be probed
+	if key == nil || key.UserID != userId {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.cosmosService.DeleteAPIKey(r.Context(), keyId); err != nil {
+		if errors.Is(err, cosmos.ErrAPIKeyNotFound) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error("failed to delete API key", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("API key revoked", slog.String("userId", userId), slog.String("prefix", key.Prefix))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]string{
+		"message": "API key revoked successfully",
+		"id":      keyId,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// validateAPIKeyRequest checks the name, scopes and expiry of a new API key and fills in the default expiry
+func validateAPIKeyRequest(req *CreateAPIKeyRequest, now time.Time) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(req.Name) > maxAPIKeyNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxAPIKeyNameLength)
+	}
+	if len(req.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !middleware.ValidScope(scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	req.Scopes = slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+
+	if req.ExpiresAt == nil {
+		expiresAt := now.Add(defaultAPIKeyTTL)
+		req.ExpiresAt = &expiresAt
+	}
+	if !req.ExpiresAt.After(now) {
+		return errors.New("expiresAt must be in the future")
+	}
+	if req.ExpiresAt.After(now.Add(maxAPIKeyTTL)) {
+		return errors.New("expiresAt must be at most a year from now")
+	}
+	return nil
+}
+
+// toAPIKeyResponse converts an API key to its response without the key itself
+func toAPIKeyResponse(key *models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+	}
+}
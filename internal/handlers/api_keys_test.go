@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAPIKeyRequest(t *testing.T) {
+	now := time.Now().UTC()
+	nextMonth, yesterday, nextYear := now.Add(30*24*time.Hour), now.Add(-24*time.Hour), now.Add(2*maxAPIKeyTTL)
+
+	tests := []struct {
+		name    string
+		req     CreateAPIKeyRequest
+		wantErr bool
+	}{
+		{name: "default expiry", req: CreateAPIKeyRequest{Name: "Helpdesk sync", Scopes: []string{middleware.ScopeComplaintsRead}}},
+		{name: "explicit expiry", req: CreateAPIKeyRequest{Name: "Dashboard", Scopes: []string{middleware.ScopeAdminRead}, ExpiresAt: &nextMonth}},
+		{name: "missing name", req: CreateAPIKeyRequest{Name: "  ", Scopes: []string{middleware.ScopeComplaintsRead}}, wantErr: true},
+		{name: "long name", req: CreateAPIKeyRequest{Name: strings.Repeat("a", maxAPIKeyNameLength+1), Scopes: []string{middleware.ScopeComplaintsRead}}, wantErr: true},
+		{name: "no scopes", req: CreateAPIKeyRequest{Name: "Helpdesk sync"}, wantErr: true},
+		{name: "unknown scope", req: CreateAPIKeyRequest{Name: "Helpdesk sync", Scopes: []string{"users:write"}}, wantErr: true},
+		{name: "expired", req: CreateAPIKeyRequest{Name: "Helpdesk sync", Scopes: []string{middleware.ScopeComplaintsRead}, ExpiresAt: &yesterday}, wantErr: true},
+		{name: "too long", req: CreateAPIKeyRequest{Name: "Helpdesk sync", Scopes: []string{middleware.ScopeComplaintsRead}, ExpiresAt: &nextYear}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAPIKeyRequest(&tt.req, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	req := CreateAPIKeyRequest{Name: " Helpdesk sync ", Scopes: []string{middleware.ScopeComplaintsWrite, middleware.ScopeComplaintsRead, middleware.ScopeComplaintsWrite}}
+	require.NoError(t, validateAPIKeyRequest(&req, now))
+	assert.Equal(t, "Helpdesk sync", req.Name)
+	assert.Equal(t, []string{middleware.ScopeComplaintsRead, middleware.ScopeComplaintsWrite}, req.Scopes)
+	require.NotNil(t, req.ExpiresAt)
+	assert.Equal(t, now.Add(defaultAPIKeyTTL), *req.ExpiresAt)
+}
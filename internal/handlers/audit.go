@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// audit records an action of the current user on targetID in the audit log. The action already happened,
+// so failures are only logged.
+func audit(r *http.Request, cosmosService *cosmos.Service, log *slog.Logger, action, targetID string, details map[string]string) {
+	actorID, _ := middleware.GetUserID(r.Context())
+	entry := &models.AuditEntry{
+		ID:        uuid.New().String(),
+		Action:    action,
+		ActorID:   actorID,
+		TargetID:  targetID,
+		Details:   details,
+		IP:        middleware.ClientIP(r),
+		RequestID: chimiddleware.GetReqID(r.Context()),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := cosmosService.CreateAuditEntry(r.Context(), entry); err != nil {
+		log.Error("failed to write audit entry", slog.String("action", action), slog.String("targetId", targetID), slog.String("actorId", actorID), slog.String("error", err.Error()))
+	}
+}
@@ -2,52 +2,96 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/authz"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/lockout"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/notification"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/oidc"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/password"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/registration"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// AuthConfig configures AuthHandler
+type AuthConfig struct {
+	Registration     *registration.Policy // Allowed email domains and the role of new accounts
+	PasswordPolicy   *password.Policy     // Rules for new passwords
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+	PasswordResetTTL time.Duration
+	VerificationTTL  time.Duration  // How long email verification links stay valid
+	MFAIssuer        string         // Account issuer shown by authenticator apps
+	RequireAdminMFA  bool           // Admins and other privileged roles must use two-factor authentication for their routes
+	OIDC             *oidc.Provider // University single sign-on, nil when not configured
+	OIDCLinkByEmail  bool           // Existing accounts are linked to provider users with the same verified email
+	FrontendURL      string         // Single sign-on redirects back to the frontend
+}
+
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
 	cosmosService *cosmos.Service
-	jwtSecret     string
+	keys          *middleware.KeyRing
+	revocations   middleware.RevocationStore
+	notifier      *notification.Notifier
+	lockout       *lockout.Guard
+	config        AuthConfig
 	log           *slog.Logger
 }
 
 // NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(cosmosService *cosmos.Service, jwtSecret string, log *slog.Logger) *AuthHandler {
+func NewAuthHandler(cosmosService *cosmos.Service, keys *middleware.KeyRing, revocations middleware.RevocationStore, notifier *notification.Notifier, lockout *lockout.Guard, config AuthConfig, log *slog.Logger) *AuthHandler {
 	const module = "authHandler"
 	log = log.With(
 		slog.String("module", module),
 	)
 	return &AuthHandler{
 		cosmosService: cosmosService,
-		jwtSecret:     jwtSecret,
+		keys:          keys,
+		revocations:   revocations,
+		notifier:      notifier,
+		lockout:       lockout,
+		config:        config,
 		log:           log,
 	}
 }
 
+// dummyPasswordHash is compared against for unknown emails, so they are not answered faster than wrong passwords
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password for unknown accounts"), bcrypt.DefaultCost)
+
+// tokenPair is an access token with the refresh token that renews it, and the CSRF token for the auth cookie
+type tokenPair struct {
+	accessToken  string
+	refreshToken string
+	csrfToken    string
+}
+
 // RegisterRequest represents the registration request body
 type RegisterRequest struct {
 	Email    string `json:"email"`
 	UserName string `json:"username"`
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	Locale   string `json:"locale,omitempty"` // Optional preferred language for emails
 }
 
 // RegisterResponse represents the registration response
 type RegisterResponse struct {
-	ID       string `json:"id"`
-	Email    string `json:"email"`
-	UserName string `json:"username"`
-	Name     string `json:"name"`
-	Token    string `json:"token"`
+	ID           string `json:"id"`
+	Email        string `json:"email"`
+	UserName     string `json:"username"`
+	Name         string `json:"name"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
 }
 
 // Register handles user registration
@@ -65,15 +109,41 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only allowed email domains may register, and the domain picks the role the account gets once verified
+	email, role, err := h.config.Registration.Check(req.Email)
+	if err != nil {
+		if errors.Is(err, registration.ErrDomainNotAllowed) {
+			h.log.Debug("registration attempt with disallowed email domain")
+			writeError(w, http.StatusForbidden, ErrCodeEmailDomainNotAllowed, "Registration is only open to institutional email addresses")
+			return
+		}
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidEmail, "Invalid email address")
+		return
+	}
+	if !h.checkPassword(w, req.Password) {
+		return
+	}
+	denied, err := h.cosmosService.IsEmailDenied(r.Context(), email)
+	if err != nil {
+		h.log.Error("failed to check email denylist", slog.String("error", err.Error()))
+		http.Error(w, "Failed to check existing user", http.StatusInternalServerError)
+		return
+	}
+	if denied {
+		h.log.Info("registration attempt with denied email")
+		writeError(w, http.StatusForbidden, ErrCodeEmailDenied, "This email address may not register")
+		return
+	}
+
 	// Check if user with this email already exists
-	existingUser, err := h.cosmosService.GetUserByEmail(r.Context(), req.Email)
+	existingUser, err := h.cosmosService.GetUserByEmail(r.Context(), email)
 	if err != nil {
-		h.log.Error("failed to check existing user by email", slog.String("email", req.Email), slog.String("error", err.Error()))
+		h.log.Error("failed to check existing user by email", slog.String("email", email), slog.String("error", err.Error()))
 		http.Error(w, "Failed to check existing user", http.StatusInternalServerError)
 		return
 	}
 	if existingUser != nil {
-		h.log.Debug("registration attempt with existing email", slog.String("email", req.Email))
+		h.log.Debug("registration attempt with existing email", slog.String("email", email))
 		http.Error(w, cosmos.ErrEmailAlreadyExists.Error(), http.StatusConflict)
 		return
 	}
@@ -99,17 +169,8 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create user
-	user := &models.User{
-		ID:           uuid.New().String(),
-		Email:        req.Email,
-		UserName:     req.UserName,
-		Name:         req.Name,
-		PasswordHash: string(hashedPassword),
-		Role:         "student",
-		CreatedAt:    time.Now(),
-	}
-
+	// Create user, unverified until the link in the verification email is opened
+	user := newRegisteredUser(req, email, role, string(hashedPassword), time.Now())
 	// Save user to database
 	if err := h.cosmosService.CreateUser(r.Context(), user); err != nil {
 		h.log.Error("failed to create user", slog.String("userId", user.ID), slog.String("error", err.Error()))
@@ -117,21 +178,27 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := middleware.GenerateJWT(user.ID, user.Email, user.Role, h.jwtSecret)
+	// The account works without verification, except for actions behind RequireVerifiedEmail
+	if err := h.sendVerificationEmail(r, user); err != nil {
+		h.log.Error("failed to send verification email", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+
+	// Generate access and refresh tokens
+	tokens, err := h.issueTokens(r, user, uuid.New().String(), false)
 	if err != nil {
-		h.log.Error("failed to generate JWT token", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		h.log.Error("failed to issue tokens", slog.String("userId", user.ID), slog.String("error", err.Error()))
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
 	// Return response
 	response := RegisterResponse{
-		ID:       user.ID,
-		Email:    user.Email,
-		UserName: user.UserName,
-		Name:     user.Name,
-		Token:    token,
+		ID:           user.ID,
+		Email:        user.Email,
+		UserName:     user.UserName,
+		Name:         user.Name,
+		Token:        tokens.accessToken,
+		RefreshToken: tokens.refreshToken,
 	}
 
 	h.log.Info("user registered successfully", slog.String("userId", user.ID), slog.String("email", user.Email), slog.String("username", user.UserName))
@@ -145,16 +212,42 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// newRegisteredUser creates an unverified student account. The role of the email domain is only kept as the
+// pending role, because anyone can register an address without owning the mailbox.
+func newRegisteredUser(req RegisterRequest, email, role, passwordHash string, now time.Time) *models.User {
+	user := &models.User{
+		ID:                 uuid.New().String(),
+		Email:              email,
+		UserName:           req.UserName,
+		Name:               req.Name,
+		PasswordHash:       passwordHash,
+		Role:               models.RoleStudent,
+		Locale:             req.Locale,
+		CreatedAt:          now,
+		Unverified:         true,
+		VerificationSentAt: &now,
+	}
+	if role != models.RoleStudent {
+		user.PendingRole = role
+	}
+	return user
+}
+
 // LoginRequest represents the login request body
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
-// LoginResponse represents the login response
+// LoginResponse represents the login and refresh response
 type LoginResponse struct {
-	Token string `json:"token"`
-	Role  string `json:"role"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	CSRFToken    string `json:"csrfToken"` // Send in the X-CSRF-Token header with state-changing requests that use the cookies
+	ExpiresIn    int    `json:"expiresIn"` // Access token lifetime in seconds
+	Role         string `json:"role"`
+	// MFAEnrollmentRequired is set for privileged users who must enroll in two-factor authentication before using their routes
+	MFAEnrollmentRequired bool `json:"mfaEnrollmentRequired,omitempty"`
 }
 
 // Login handles user login
@@ -173,53 +266,74 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Email and password are required", http.StatusBadRequest)
 		return
 	}
+	// Accounts are stored under the normalized address, so "Jane@Uni.edu " finds jane@uni.edu
+	email := accountEmail(req.Email)
+
+	// Refuse locked emails before checking the password, so guessing stops working during the lockout
+	lockedFor, err := h.lockout.Locked(r.Context(), email)
+	if err != nil {
+		h.log.Error("failed to check account lockout", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return
+	}
+	if lockedFor > 0 {
+		h.log.Warn("login attempt on locked account", slog.String("event", "login_locked"), slog.String("email", email), slog.String("ip", middleware.ClientIP(r)))
+		w.Header().Set("Retry-After", strconv.Itoa(int(lockedFor.Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, ErrCodeAccountLocked, "Too many failed login attempts, try again later")
+		return
+	}
 
 	// Get user by email
-	user, err := h.cosmosService.GetUserByEmail(r.Context(), req.Email)
+	user, err := h.cosmosService.GetUserByEmail(r.Context(), email)
 	if err != nil {
-		h.log.Error("failed to retrieve user", slog.String("email", req.Email), slog.String("error", err.Error()))
+		h.log.Error("failed to retrieve user", slog.String("email", email), slog.String("error", err.Error()))
 		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
 		return
 	}
-	if user == nil {
-		h.log.Debug("login attempt with non-existent email", slog.String("email", req.Email))
+
+	// Verify password. Unknown emails are compared against a dummy hash, so they take as long and count as failures too.
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = []byte(user.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(req.Password)); err != nil || user == nil {
+		if _, err := h.lockout.Failed(r.Context(), email, middleware.ClientIP(r)); err != nil {
+			h.log.Error("failed to record failed login", slog.String("error", err.Error()))
+		}
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		h.log.Debug("login attempt with invalid password", slog.String("email", req.Email))
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	if h.refuseSuspended(w, user) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := middleware.GenerateJWT(user.ID, user.Email, user.Role, h.jwtSecret)
+	// With two-factor authentication the password only earns a challenge. Failed logins are kept until the
+	// second factor is passed too, so codes cannot be guessed by alternating them with correct passwords.
+	if user.MFAEnabled() {
+		h.writeMFAChallenge(w, user)
+		return
+	}
+
+	if err := h.lockout.Succeeded(r.Context(), email); err != nil {
+		h.log.Error("failed to reset failed logins", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+
+	// Generate access and refresh tokens; every login starts a new rotation family
+	tokens, err := h.issueTokens(r, user, uuid.New().String(), false)
 	if err != nil {
-		h.log.Error("failed to generate JWT token", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		h.log.Error("failed to issue tokens", slog.String("userId", user.ID), slog.String("error", err.Error()))
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	// Set HTTP-only cookie with token
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteNoneMode,
-		MaxAge:   86400, // 24 hours
-	})
+	// Set HTTP-only cookies with the tokens
+	h.setAuthCookies(w, tokens)
 
 	h.log.Info("user logged in successfully", slog.String("userId", user.ID), slog.String("email", user.Email), slog.String("role", user.Role))
 
-	// Return response (also include token for backward compatibility)
-	response := LoginResponse{
-		Token: token,
-		Role:  user.Role,
-	}
+	// Return response (also include tokens for clients that do not use cookies)
+	response := h.loginResponse(user, tokens)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -230,9 +344,333 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Logout handles user logout by clearing the auth cookie
+// RefreshRequest represents the refresh request body, used when the refresh cookie is not sent
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// Each refresh token can be used once; presenting a used one revokes every token of its family.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	presented := refreshTokenFromRequest(r)
+	if presented == "" {
+		http.Error(w, "Refresh token required", http.StatusUnauthorized)
+		return
+	}
+
+	stored, err := h.cosmosService.GetRefreshToken(r.Context(), middleware.HashToken(presented))
+	if err != nil {
+		h.log.Error("failed to get refresh token", slog.String("error", err.Error()))
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+	if stored == nil || stored.Revoked() || time.Now().After(stored.ExpiresAt) {
+		h.log.Debug("invalid refresh token presented")
+		h.clearAuthCookies(w)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if stored.Used() {
+		h.revokeReusedFamily(r, stored)
+		h.clearAuthCookies(w)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.cosmosService.GetUserByID(r.Context(), stored.UserID)
+	if err != nil {
+		h.log.Error("failed to retrieve user for refresh", slog.String("userId", stored.UserID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		h.log.Debug("refresh token of deleted user presented", slog.String("userId", stored.UserID))
+		h.clearAuthCookies(w)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if h.refuseSuspended(w, user) {
+		h.clearAuthCookies(w)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user, stored.FamilyID, stored.MFA)
+	if err != nil {
+		h.log.Error("failed to issue tokens", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	// Mark the presented token as used. Losing this race means it was used twice, which is treated as reuse.
+	now := time.Now().UTC()
+	stored.UsedAt = &now
+	stored.ReplacedBy = middleware.HashToken(tokens.refreshToken)
+	if err := h.cosmosService.UpdateRefreshToken(r.Context(), stored); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			h.revokeReusedFamily(r, stored)
+			h.clearAuthCookies(w)
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		h.log.Error("failed to rotate refresh token", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	h.setAuthCookies(w, tokens)
+
+	h.log.Info("tokens refreshed", slog.String("userId", user.ID))
+
+	response := h.loginResponse(user, tokens)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// Logout handles user logout by revoking the access token and the refresh token family and clearing the auth cookies
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// Clear the auth cookie by setting MaxAge to -1
+	// Revoke the access token, whether it came from the cookie or the Authorization header, and end its session
+	if tokenString, err := middleware.TokenFromRequest(r); err == nil {
+		if claims, err := middleware.ParseJWT(tokenString, h.keys); err == nil {
+			if err := middleware.RevokeClaims(r.Context(), h.revocations, claims); err != nil {
+				h.log.Error("failed to revoke access token on logout", slog.String("userId", claims.UserID), slog.String("error", err.Error()))
+			}
+			if claims.SessionID != "" {
+				h.endSession(r, claims.UserID, claims.SessionID)
+			}
+		}
+	}
+
+	if presented := refreshTokenFromRequest(r); presented != "" {
+		stored, err := h.cosmosService.GetRefreshToken(r.Context(), middleware.HashToken(presented))
+		if err != nil {
+			h.log.Error("failed to get refresh token for logout", slog.String("error", err.Error()))
+		} else if stored != nil {
+			if _, err := h.cosmosService.RevokeRefreshTokenFamily(r.Context(), stored.FamilyID); err != nil {
+				h.log.Error("failed to revoke refresh tokens on logout", slog.String("userId", stored.UserID), slog.String("error", err.Error()))
+			}
+			h.endSession(r, stored.UserID, stored.FamilyID)
+		}
+	}
+
+	h.clearAuthCookies(w)
+
+	h.log.Info("user logged out successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte(`{"message":"Logged out successfully"}`))
+	if err != nil {
+		h.log.Warn("Failed to write")
+		return
+	}
+}
+
+// LogoutAll logs the current user out of all devices. Every access and refresh token issued so far stops working.
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := revokeAllSessions(r.Context(), h.cosmosService, h.revocations, userId); err != nil {
+		h.log.Error("failed to log out of all devices", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to log out of all devices", http.StatusInternalServerError)
+		return
+	}
+
+	h.clearAuthCookies(w)
+
+	h.log.Info("user logged out of all devices", slog.String("userId", userId))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte(`{"message":"Logged out of all devices"}`))
+	if err != nil {
+		h.log.Warn("Failed to write")
+		return
+	}
+}
+
+// CSRFToken returns the CSRF token of the browser, creating it if there is none. Frontends on another site than
+// the API cannot read the CSRF cookie, for example after a single sign-on redirect, and get the token here.
+func (h *AuthHandler) CSRFToken(w http.ResponseWriter, r *http.Request) {
+	token := middleware.CSRFToken(r)
+	if token == "" {
+		token, _ = middleware.GenerateToken()
+		h.setCSRFCookie(w, token)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]string{"csrfToken": token}); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+// refuseSuspended answers with the suspension and returns true if an admin has suspended the user
+func (h *AuthHandler) refuseSuspended(w http.ResponseWriter, user *models.User) bool {
+	if !user.Suspended() {
+		return false
+	}
+	h.log.Info("login attempt on suspended account", slog.String("userId", user.ID))
+	middleware.WriteRestriction(w, middleware.ErrCodeAccountSuspended, "Your account has been suspended", user.Suspension)
+	return true
+}
+
+// issueTokens creates an access token and a refresh token in the given rotation family.
+// mfa records that the family was started with two-factor authentication.
+func (h *AuthHandler) issueTokens(r *http.Request, user *models.User, familyID string, mfa bool) (*tokenPair, error) {
+	// Tokens carry the current token version, so "log out of all devices" invalidates them
+	tokenVersion, err := h.revocations.TokenVersion(r.Context(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The family is the session shown to the user, every token of it records the latest activity
+	now := time.Now().UTC()
+	created, err := h.saveSession(r, user.ID, familyID, mfa, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new session gets a new CSRF token, refreshed tokens keep the one the client already has
+	csrfToken := middleware.CSRFToken(r)
+	if created || csrfToken == "" {
+		csrfToken, _ = middleware.GenerateToken()
+	}
+
+	accessToken, err := middleware.GenerateJWT(h.keys, middleware.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		TokenVersion: tokenVersion,
+		MFA:          mfa,
+		Categories:   user.Categories,
+		SessionID:    familyID,
+	}, h.config.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, hash := middleware.GenerateToken()
+	if err := h.cosmosService.CreateRefreshToken(r.Context(), &models.RefreshToken{
+		ID:        hash,
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		MFA:       mfa,
+		ExpiresAt: now.Add(h.config.RefreshTokenTTL),
+		CreatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{accessToken: accessToken, refreshToken: refreshToken, csrfToken: csrfToken}, nil
+}
+
+// saveSession creates the session of a new token family, or records the activity of an existing one.
+// It reports whether the session was created.
+func (h *AuthHandler) saveSession(r *http.Request, userID, familyID string, mfa bool, now time.Time) (bool, error) {
+	session, err := h.cosmosService.GetSession(r.Context(), userID, familyID)
+	if err != nil {
+		return false, err
+	}
+	created := session == nil
+	if created {
+		userAgent := r.UserAgent()
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = userAgent[:maxUserAgentLength]
+		}
+		session = &models.Session{
+			ID:        familyID,
+			UserID:    userID,
+			Device:    deviceName(userAgent),
+			UserAgent: userAgent,
+			MFA:       mfa,
+			CreatedAt: now,
+		}
+	}
+	session.IP = middleware.ClientIP(r)
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(h.config.RefreshTokenTTL)
+	return created, h.cosmosService.SaveSession(r.Context(), session)
+}
+
+// loginResponse returns the body of a successful login or refresh
+func (h *AuthHandler) loginResponse(user *models.User, tokens *tokenPair) LoginResponse {
+	return LoginResponse{
+		Token:                 tokens.accessToken,
+		RefreshToken:          tokens.refreshToken,
+		CSRFToken:             tokens.csrfToken,
+		ExpiresIn:             int(h.config.AccessTokenTTL.Seconds()),
+		Role:                  user.Role,
+		MFAEnrollmentRequired: h.config.RequireAdminMFA && authz.Privileged(user.Role) && !user.MFAEnabled(),
+	}
+}
+
+// revokeReusedFamily revokes the family of a refresh token that was presented after it had been rotated.
+// Either the client or an attacker holds a stolen copy, so every token of the family stops working.
+func (h *AuthHandler) revokeReusedFamily(r *http.Request, stored *models.RefreshToken) {
+	h.log.Warn("refresh token reuse detected, revoking token family", slog.String("userId", stored.UserID), slog.String("familyId", stored.FamilyID))
+	if _, err := h.cosmosService.RevokeRefreshTokenFamily(r.Context(), stored.FamilyID); err != nil {
+		h.log.Error("failed to revoke refresh token family", slog.String("userId", stored.UserID), slog.String("familyId", stored.FamilyID), slog.String("error", err.Error()))
+	}
+	h.endSession(r, stored.UserID, stored.FamilyID)
+}
+
+// endSession removes a session, which stops its access tokens from working. Failures are only logged; the
+// session's refresh tokens are revoked separately.
+func (h *AuthHandler) endSession(r *http.Request, userID, sessionID string) {
+	if err := h.cosmosService.DeleteSession(r.Context(), userID, sessionID); err != nil && !errors.Is(err, cosmos.ErrSessionNotFound) {
+		h.log.Error("failed to remove session", slog.String("userId", userID), slog.String("error", err.Error()))
+	}
+}
+
+// setAuthCookies sets the HTTP-only access and refresh token cookies and the CSRF token cookie
+func (h *AuthHandler) setAuthCookies(w http.ResponseWriter, tokens *tokenPair) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "auth_token",
+		Value:    tokens.accessToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   int(h.config.AccessTokenTTL.Seconds()),
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.RefreshCookieName,
+		Value:    tokens.refreshToken,
+		Path:     middleware.RefreshCookiePath,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   int(h.config.RefreshTokenTTL.Seconds()),
+	})
+	h.setCSRFCookie(w, tokens.csrfToken)
+}
+
+// setCSRFCookie sets the CSRF token cookie. It is not HTTP-only, so a frontend on the API's site can read it.
+func (h *AuthHandler) setCSRFCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   int(h.config.RefreshTokenTTL.Seconds()),
+	})
+}
+
+// clearAuthCookies deletes the access, refresh and CSRF token cookies
+func (h *AuthHandler) clearAuthCookies(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "auth_token",
 		Value:    "",
@@ -242,14 +680,43 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteNoneMode,
 		MaxAge:   -1, // Delete cookie
 	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.RefreshCookieName,
+		Value:    "",
+		Path:     middleware.RefreshCookiePath,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   -1, // Delete cookie
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.CSRFCookieName,
+		Value:    "",
+		Path:     "/",
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   -1, // Delete cookie
+	})
+}
 
-	h.log.Info("user logged out successfully")
+// refreshTokenFromRequest returns the refresh token from the cookie, falling back to the JSON body
+func refreshTokenFromRequest(r *http.Request) string {
+	if cookie, err := r.Cookie(middleware.RefreshCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, err := w.Write([]byte(`{"message":"Logged out successfully"}`))
-	if err != nil {
-		h.log.Warn("Failed to write")
-		return
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return ""
+	}
+	return req.RefreshToken
+}
+
+// accountEmail returns an email address in the normalized form accounts are stored under.
+// Addresses that cannot be normalized belong to no account and are only trimmed and lower-cased.
+func accountEmail(email string) string {
+	if normalized, _, err := registration.NormalizeEmail(email); err == nil {
+		return normalized
 	}
+	return strings.ToLower(strings.TrimSpace(email))
 }
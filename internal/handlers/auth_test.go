@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/password"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		body   string
+		want   string
+	}{
+		{name: "cookie", cookie: "from-cookie", want: "from-cookie"},
+		{name: "body", body: `{"refreshToken":"from-body"}`, want: "from-body"},
+		{name: "cookie wins over body", cookie: "from-cookie", body: `{"refreshToken":"from-body"}`, want: "from-cookie"},
+		{name: "empty body", want: ""},
+		{name: "invalid body", body: `not json`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", strings.NewReader(tt.body))
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: middleware.RefreshCookieName, Value: tt.cookie})
+			}
+
+			assert.Equal(t, tt.want, refreshTokenFromRequest(req))
+		})
+	}
+}
+
+func TestRegisterRejectsEmail(t *testing.T) {
+	policy, err := registration.NewPolicy([]string{"*.university.edu"}, nil)
+	require.NoError(t, err)
+	passwords, err := password.NewPolicy(8, 1, true)
+	require.NoError(t, err)
+	h := NewAuthHandler(nil, nil, nil, nil, nil, AuthConfig{Registration: policy, PasswordPolicy: passwords}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name       string
+		email      string
+		password   string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "other domain", email: "jane@gmail.com", password: "correct horse", wantStatus: http.StatusForbidden, wantCode: ErrCodeEmailDomainNotAllowed},
+		{name: "invalid email", email: "jane", password: "correct horse", wantStatus: http.StatusBadRequest, wantCode: ErrCodeInvalidEmail},
+		{name: "short password", email: "jane@cs.university.edu", password: "horse", wantStatus: http.StatusBadRequest, wantCode: ErrCodeWeakPassword},
+		{name: "breached password", email: "jane@cs.university.edu", password: "password123", wantStatus: http.StatusBadRequest, wantCode: ErrCodeWeakPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"email":"` + tt.email + `","username":"jane","name":"Jane","password":"` + tt.password + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			h.Register(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			var response ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantCode, response.Code)
+		})
+	}
+}
+
+func TestRegisterPrivilegedDomainWaitsForVerification(t *testing.T) {
+	policy, err := registration.NewPolicy([]string{"*.university.edu"}, map[string]string{"staff.university.edu": models.RoleAdmin})
+	require.NoError(t, err)
+	email, role, err := policy.Check("x@staff.university.edu")
+	require.NoError(t, err)
+	require.Equal(t, models.RoleAdmin, role)
+
+	user := newRegisteredUser(RegisterRequest{UserName: "x", Name: "X"}, email, role, "hash", time.Now())
+	assert.Equal(t, models.RoleStudent, user.Role, "the domain role needs a verified address")
+	assert.Equal(t, models.RoleAdmin, user.PendingRole)
+	assert.False(t, user.EmailVerified())
+
+	markEmailVerified(user, time.Now())
+	assert.True(t, user.EmailVerified())
+	assert.Equal(t, models.RoleAdmin, user.Role)
+	assert.Empty(t, user.PendingRole)
+
+	student := newRegisteredUser(RegisterRequest{UserName: "jane", Name: "Jane"}, "jane@cs.university.edu", models.RoleStudent, "hash", time.Now())
+	markEmailVerified(student, time.Now())
+	assert.Equal(t, models.RoleStudent, student.Role)
+}
+
+func TestAccountEmail(t *testing.T) {
+	assert.Equal(t, "jane@university.edu", accountEmail(" Jane@University.EDU "))
+	assert.Equal(t, "not-an-email", accountEmail(" Not-An-Email "))
+}
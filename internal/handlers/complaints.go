@@ -4,24 +4,49 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/services"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/authz"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/notification"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/webhook"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 )
 
+// messageSchemaVersion is the schema version of the complaint messages published to Service Bus
+const messageSchemaVersion = "1"
+
+// escalationTTL is how long an escalation message stays deliverable after its scheduled time.
+// A notice that could not be handled within a day is dropped instead of arriving late.
+const escalationTTL = 24 * time.Hour
+
+// ComplaintsConfig configures the ComplaintsHandler
+type ComplaintsConfig struct {
+	Categories      []string      // Allowed complaint categories; the first is the default
+	EscalationQueue string        // Queue for scheduled escalation messages; empty disables escalations
+	EscalateAfter   time.Duration // How long a complaint may await review before it is escalated
+}
+
 // ComplaintsHandler handles complaint-related requests
 type ComplaintsHandler struct {
 	cosmosService     *cosmos.Service
 	serviceBusService *services.ServiceBusService
+	notifier          *notification.Notifier
+	webhooks          *webhook.Service
+	categories        []string // Allowed complaint categories; the first is the default
+	escalationQueue   string
+	escalateAfter     time.Duration
 	log               *slog.Logger
 }
 
 // NewComplaintsHandler creates a new ComplaintsHandler
-func NewComplaintsHandler(cosmosService *cosmos.Service, serviceBusService *services.ServiceBusService, log *slog.Logger) *ComplaintsHandler {
+func NewComplaintsHandler(cosmosService *cosmos.Service, serviceBusService *services.ServiceBusService, notifier *notification.Notifier, webhooks *webhook.Service, config ComplaintsConfig, log *slog.Logger) *ComplaintsHandler {
 	const module = "complaintsHandler"
 	log = log.With(
 		slog.String("module", module),
@@ -29,6 +54,11 @@ func NewComplaintsHandler(cosmosService *cosmos.Service, serviceBusService *serv
 	return &ComplaintsHandler{
 		cosmosService:     cosmosService,
 		serviceBusService: serviceBusService,
+		notifier:          notifier,
+		webhooks:          webhooks,
+		categories:        config.Categories,
+		escalationQueue:   config.EscalationQueue,
+		escalateAfter:     config.EscalateAfter,
 		log:               log,
 	}
 }
@@ -36,6 +66,7 @@ func NewComplaintsHandler(cosmosService *cosmos.Service, serviceBusService *serv
 // CreateComplaintRequest represents the request body for creating a complaint
 type CreateComplaintRequest struct {
 	Description string `json:"description"`
+	Category    string `json:"category,omitempty"` // Defaults to the first configured category
 }
 
 // CreateComplaint handles POST requests to create a new complaint
@@ -48,6 +79,10 @@ func (h *ComplaintsHandler) CreateComplaint(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if h.refuseBanned(w, r, models.RestrictionPosting, middleware.ErrCodePostingBanned, "You are not allowed to submit complaints") {
+		return
+	}
+
 	// Parse JSON body
 	var req CreateComplaintRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -63,11 +98,23 @@ func (h *ComplaintsHandler) CreateComplaint(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// Validate category
+	category := req.Category
+	if category == "" && len(h.categories) > 0 {
+		category = h.categories[0]
+	}
+	if !slices.Contains(h.categories, category) {
+		h.log.Debug("invalid complaint category", slog.String("userId", userId), slog.String("category", req.Category))
+		http.Error(w, "Invalid category", http.StatusBadRequest)
+		return
+	}
+
 	// Create complaint
 	complaint := &models.Complaint{
 		ID:          uuid.New().String(),
 		UserID:      userId,
 		Description: req.Description,
+		Category:    category,
 		Status:      models.StatusPending,
 		CreatedAt:   time.Now(),
 	}
@@ -80,7 +127,14 @@ func (h *ComplaintsHandler) CreateComplaint(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Send complaint ID to Service Bus queue
-	if err := h.serviceBusService.SendMessage(r.Context(), "new-complaints", complaint.ID); err != nil {
+	if err := h.serviceBusService.Publish(r.Context(), "new-complaints", &services.OutgoingMessage{
+		Body:          []byte(complaint.ID),
+		MessageID:     complaint.ID + "-created",
+		CorrelationID: chimiddleware.GetReqID(r.Context()),
+		SessionID:     complaint.ID,
+		EventType:     services.EventComplaintCreated,
+		SchemaVersion: messageSchemaVersion,
+	}); err != nil {
 		h.log.Error("failed to send complaint to service bus", slog.String("userId", userId), slog.String("complaintId", complaint.ID), slog.String("error", err.Error()))
 		http.Error(w, "Failed to queue complaint", http.StatusInternalServerError)
 		return
@@ -89,6 +143,9 @@ func (h *ComplaintsHandler) CreateComplaint(w http.ResponseWriter, r *http.Reque
 	// Log successful complaint creation
 	h.log.Info("complaint created successfully", slog.String("userId", userId), slog.String("complaintId", complaint.ID))
 
+	h.publishWebhook(r, models.WebhookEventComplaintCreated, complaint)
+	h.scheduleEscalation(r, complaint)
+
 	// Return created complaint as JSON
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "no-store")
@@ -193,15 +250,51 @@ func (h *ComplaintsHandler) UpdateComplaint(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// Department staff may only moderate complaints in their categories
+	role, _ := middleware.GetRole(r.Context())
+	categories := middleware.GetCategories(r.Context())
+	if all, _ := authz.Scope(role, categories, authz.ComplaintsModerate); !all {
+		existing, err := h.cosmosService.GetComplaintByID(r.Context(), complaintId)
+		if err != nil {
+			h.log.Error("failed to get complaint for status update", slog.String("adminId", adminId), slog.String("complaintId", complaintId), slog.String("error", err.Error()))
+			http.Error(w, "Failed to retrieve complaint", http.StatusInternalServerError)
+			return
+		}
+		if existing == nil {
+			h.log.Debug("complaint not found for status update", slog.String("adminId", adminId), slog.String("complaintId", complaintId))
+			http.Error(w, "Complaint not found", http.StatusNotFound)
+			return
+		}
+		if !authz.Allows(role, categories, authz.ComplaintsModerate, existing.Category) {
+			h.log.Warn("moderation outside assigned categories", slog.String("userId", adminId), slog.String("complaintId", complaintId), slog.String("category", existing.Category))
+			http.Error(w, "Forbidden: complaint is outside your categories", http.StatusForbidden)
+			return
+		}
+	}
+
 	// Update complaint status and optionally add comment in Cosmos DB
-	if err := h.cosmosService.UpdateComplaintStatusWithComment(r.Context(), complaintId, req.Status, req.Comment, adminId); err != nil {
+	complaint, err := h.cosmosService.UpdateComplaintStatusWithComment(r.Context(), complaintId, req.Status, req.Comment, adminId)
+	if err != nil {
 		h.log.Error("failed to update complaint status", slog.String("adminId", adminId), slog.String("complaintId", complaintId), slog.String("error", err.Error()))
 		http.Error(w, "Failed to update complaint", http.StatusInternalServerError)
 		return
 	}
+	if complaint == nil {
+		h.log.Debug("complaint not found for status update", slog.String("adminId", adminId), slog.String("complaintId", complaintId))
+		http.Error(w, "Complaint not found", http.StatusNotFound)
+		return
+	}
 
-	// Send complaint ID to Service Bus queue
-	if err := h.serviceBusService.SendMessage(r.Context(), "complaint-status-changed", complaintId); err != nil {
+	// Send complaint ID to Service Bus queue. The queues do not detect duplicates, so a message that is
+	// delivered twice is handled twice; the notifier derives its notification IDs from the status change.
+	if err := h.serviceBusService.Publish(r.Context(), "complaint-status-changed", &services.OutgoingMessage{
+		Body:          []byte(complaintId),
+		MessageID:     complaintId + "-status-" + strconv.FormatInt(complaint.StatusChangedAt.UnixNano(), 10),
+		CorrelationID: chimiddleware.GetReqID(r.Context()),
+		SessionID:     complaintId,
+		EventType:     services.EventComplaintStatusChanged,
+		SchemaVersion: messageSchemaVersion,
+	}); err != nil {
 		h.log.Error("failed to send complaint to service bus", slog.String("adminId", adminId), slog.String("complaintId", complaintId), slog.String("error", err.Error()))
 		http.Error(w, "Failed to queue status change notification", http.StatusInternalServerError)
 		return
@@ -210,6 +303,8 @@ func (h *ComplaintsHandler) UpdateComplaint(w http.ResponseWriter, r *http.Reque
 	// Log successful status change
 	h.log.Info("complaint status updated", slog.String("adminId", adminId), slog.String("complaintId", complaintId), slog.String("newStatus", req.Status))
 
+	h.publishWebhook(r, models.WebhookEventComplaintStatusChanged, complaint)
+
 	// Return success response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -223,9 +318,9 @@ func (h *ComplaintsHandler) UpdateComplaint(w http.ResponseWriter, r *http.Reque
 	}
 }
 
-// GetAllComplaintsAdmin handles GET requests to retrieve all complaints (admin-only)
+// GetAllComplaintsAdmin handles GET requests to retrieve all complaints (requires complaints:read)
 // @Summary Get all complaints (admin)
-// @Description Get all complaints, optionally filtered by status
+// @Description Get all complaints, optionally filtered by status. Department staff only see their categories
 // @Tags admin
 // @Security Bearer
 // @Produce json
@@ -248,16 +343,21 @@ func (h *ComplaintsHandler) GetAllComplaintsAdmin(w http.ResponseWriter, r *http
 		http.Error(w, "Role not found in context", http.StatusInternalServerError)
 		return
 	}
-	if role != models.RoleAdmin {
-		h.log.Warn("non-admin attempted admin complaints", slog.String("userId", adminId), slog.String("role", role))
-		http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
+	if !authz.Has(role, authz.ComplaintsRead) {
+		h.log.Warn("user without permission attempted admin complaints", slog.String("userId", adminId), slog.String("role", role))
+		http.Error(w, "Forbidden: missing permission "+string(authz.ComplaintsRead), http.StatusForbidden)
 		return
 	}
+	// A nil filter returns every category, so staff without categories get an empty one
+	all, categories := authz.Scope(role, middleware.GetCategories(r.Context()), authz.ComplaintsRead)
+	if !all && categories == nil {
+		categories = []string{}
+	}
 
 	status := r.URL.Query().Get("status")
-	h.log.Info("admin getting all complaints", slog.String("adminId", adminId), slog.String("status", status))
+	h.log.Info("admin getting all complaints", slog.String("adminId", adminId), slog.String("status", status), slog.Any("categories", categories))
 
-	complaints, err := h.cosmosService.GetAllComplaints(r.Context(), status)
+	complaints, err := h.cosmosService.GetAllComplaints(r.Context(), status, categories)
 	if err != nil {
 		h.log.Error("failed to get all complaints", slog.String("adminId", adminId), slog.String("status", status), slog.String("error", err.Error()))
 		http.Error(w, "Failed to retrieve complaints", http.StatusInternalServerError)
@@ -281,7 +381,7 @@ func (h *ComplaintsHandler) GetAllComplaintsAdmin(w http.ResponseWriter, r *http
 }
 
 // DeleteComplaint handles DELETE requests to delete a complaint
-// Students can only delete their own complaints, admins can delete any complaint
+// Students can only delete their own complaints; roles with complaints:delete can delete any complaint in their scope
 // @Summary Delete a complaint
 // @Description Delete a complaint by ID. Students can only delete their own complaints, admins can delete any
 // @Tags complaints
@@ -334,8 +434,8 @@ func (h *ComplaintsHandler) DeleteComplaint(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Check authorization: student can only delete their own, admin can delete any
-	if role != models.RoleAdmin && complaint.UserID != userId {
+	// Check authorization: owners can delete their own, otherwise the complaints:delete permission is needed
+	if complaint.UserID != userId && !authz.Allows(role, middleware.GetCategories(r.Context()), authz.ComplaintsDelete, complaint.Category) {
 		h.log.Warn("unauthorized deletion attempt", slog.String("userId", userId), slog.String("complaintId", complaintId), slog.String("complaintOwnerId", complaint.UserID))
 		http.Error(w, "Forbidden: you can only delete your own complaints", http.StatusForbidden)
 		return
@@ -351,6 +451,8 @@ func (h *ComplaintsHandler) DeleteComplaint(w http.ResponseWriter, r *http.Reque
 	// Log successful deletion
 	h.log.Info("complaint deleted successfully", slog.String("userId", userId), slog.String("complaintId", complaintId), slog.String("role", role))
 
+	h.publishWebhook(r, models.WebhookEventComplaintDeleted, complaint)
+
 	// Return success response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -383,7 +485,7 @@ func (h *ComplaintsHandler) GetApprovedComplaints(w http.ResponseWriter, r *http
 
 	h.log.Info("getting approved complaints", slog.String("userId", userId))
 
-	complaints, err := h.cosmosService.GetAllComplaints(r.Context(), models.StatusApproved)
+	complaints, err := h.cosmosService.GetAllComplaints(r.Context(), models.StatusApproved, nil)
 	if err != nil {
 		h.log.Error("failed to get approved complaints", slog.String("userId", userId), slog.String("error", err.Error()))
 		http.Error(w, "Failed to retrieve approved complaints", http.StatusInternalServerError)
@@ -436,6 +538,10 @@ func (h *ComplaintsHandler) LikeComplaint(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if h.refuseBanned(w, r, models.RestrictionLiking, middleware.ErrCodeLikingBanned, "You are not allowed to like complaints") {
+		return
+	}
+
 	// Like the complaint
 	if err := h.cosmosService.LikeComplaint(r.Context(), complaintId, userId); err != nil {
 		h.log.Error("failed to like complaint", slog.String("userId", userId), slog.String("complaintId", complaintId), slog.String("error", err.Error()))
@@ -459,6 +565,12 @@ func (h *ComplaintsHandler) LikeComplaint(w http.ResponseWriter, r *http.Request
 
 	h.log.Info("complaint liked", slog.String("userId", userId), slog.String("complaintId", complaintId), slog.Int("likeCount", complaint.LikeCount))
 
+	// Let the owner know; the like itself already succeeded, so a failure here is only logged
+	if err := h.notifier.NotifyLiked(r.Context(), complaint, userId); err != nil {
+		h.log.Error("failed to notify complaint owner about like", slog.String("userId", userId), slog.String("complaintId", complaintId), slog.String("error", err.Error()))
+	}
+	h.publishWebhook(r, models.WebhookEventComplaintLiked, complaint)
+
 	// Convert to response DTO with user-specific like information
 	complaintResponse := cosmos.ToComplaintResponse(complaint, userId)
 
@@ -533,3 +645,45 @@ func (h *ComplaintsHandler) UnlikeComplaint(w http.ResponseWriter, r *http.Reque
 		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("complaintId", complaintId), slog.String("error", err.Error()))
 	}
 }
+
+// refuseBanned answers with the ban and returns true if an admin has banned the user from the action.
+// It relies on the user document loaded by RequireAuth.
+func (h *ComplaintsHandler) refuseBanned(w http.ResponseWriter, r *http.Request, kind, code, message string) bool {
+	user, ok := middleware.GetUser(r.Context())
+	if !ok {
+		return false
+	}
+	ban := user.ActiveBan(kind, time.Now())
+	if ban == nil {
+		return false
+	}
+	h.log.Info("banned user attempted a restricted action", slog.String("userId", user.ID), slog.String("kind", kind))
+	middleware.WriteRestriction(w, code, message, ban)
+	return true
+}
+
+// scheduleEscalation publishes a message that the broker delivers once the complaint's review deadline passes.
+// The complaint is already saved, so a failure is only logged and the complaint is just not escalated.
+func (h *ComplaintsHandler) scheduleEscalation(r *http.Request, complaint *models.Complaint) {
+	if h.escalationQueue == "" {
+		return
+	}
+	if err := h.serviceBusService.Publish(r.Context(), h.escalationQueue, &services.OutgoingMessage{
+		Body:          []byte(complaint.ID),
+		MessageID:     complaint.ID + "-escalation",
+		CorrelationID: chimiddleware.GetReqID(r.Context()),
+		EventType:     services.EventComplaintEscalationDue,
+		SchemaVersion: messageSchemaVersion,
+		TimeToLive:    escalationTTL,
+		ScheduledAt:   complaint.CreatedAt.Add(h.escalateAfter),
+	}); err != nil {
+		h.log.Error("failed to schedule complaint escalation", slog.String("complaintId", complaint.ID), slog.String("error", err.Error()))
+	}
+}
+
+// publishWebhook queues a complaint event for webhook subscribers. The request already succeeded, so failures are only logged.
+func (h *ComplaintsHandler) publishWebhook(r *http.Request, eventType string, complaint *models.Complaint) {
+	if err := h.webhooks.PublishComplaint(r.Context(), eventType, complaint); err != nil {
+		h.log.Error("failed to publish webhook event", slog.String("event", eventType), slog.String("complaintId", complaint.ID), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/registration"
+)
+
+// DenylistHandler handles admin requests to manage email addresses that may not register
+type DenylistHandler struct {
+	cosmosService *cosmos.Service
+	log           *slog.Logger
+}
+
+// NewDenylistHandler creates a new DenylistHandler
+func NewDenylistHandler(cosmosService *cosmos.Service, log *slog.Logger) *DenylistHandler {
+	const module = "denylistHandler"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &DenylistHandler{
+		cosmosService: cosmosService,
+		log:           log,
+	}
+}
+
+// DeniedEmailRequest represents the add denied email request body
+type DeniedEmailRequest struct {
+	Email  string `json:"email"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// GetDeniedEmails handles GET requests to list the registration denylist
+// @Summary List denied email addresses (admin)
+// @Description List the email addresses that may not register, newest first
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} models.DeniedEmail
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/email-denylist [get]
+func (h *DenylistHandler) GetDeniedEmails(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.cosmosService.GetDeniedEmails(r.Context())
+	if err != nil {
+		h.log.Error("failed to get email denylist", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve denylist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+// AddDeniedEmail handles POST requests to put an email address on the registration denylist
+// @Summary Deny an email address (admin)
+// @Description Prevent an email address from registering. Existing accounts are not affected
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body DeniedEmailRequest true "Denied email"
+// @Success 201 {object} models.DeniedEmail
+// @Failure 400 {object} ErrorResponse "Invalid email"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/email-denylist [post]
+func (h *DenylistHandler) AddDeniedEmail(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req DeniedEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	email, _, err := registration.NormalizeEmail(req.Email)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidEmail, "Invalid email address")
+		return
+	}
+
+	entry := &models.DeniedEmail{
+		ID:        email,
+		Reason:    req.Reason,
+		CreatedBy: userId,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.cosmosService.AddDeniedEmail(r.Context(), entry); err != nil {
+		h.log.Error("failed to add denied email", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to add email to denylist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+// RemoveDeniedEmail handles DELETE requests to remove an email address from the registration denylist
+// @Summary Allow a denied email address again (admin)
+// @Description Remove an email address from the registration denylist
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param email path string true "Email address"
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not On Denylist"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/email-denylist/{email} [delete]
+func (h *DenylistHandler) RemoveDeniedEmail(w http.ResponseWriter, r *http.Request) {
+	email, _, err := registration.NormalizeEmail(r.PathValue("email"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidEmail, "Invalid email address")
+		return
+	}
+
+	if err := h.cosmosService.RemoveDeniedEmail(r.Context(), email); err != nil {
+		if errors.Is(err, cosmos.ErrDeniedEmailNotFound) {
+			http.Error(w, "Email is not on the denylist", http.StatusNotFound)
+			return
+		}
+		h.log.Error("failed to remove denied email", slog.String("error", err.Error()))
+		http.Error(w, "Failed to remove email from denylist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]string{
+		"message": "Email removed from denylist",
+		"email":   email,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
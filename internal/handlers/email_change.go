@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/registration"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ChangeEmailRequest represents the change email request body
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"` // Required unless the account has no password
+}
+
+// ConfirmEmailChangeRequest represents the confirm email change request body
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token"`
+}
+
+// ChangeEmail handles POST requests to change the current user's email address
+// @Summary Change email address
+// @Description Email a confirmation link to the new address and a notice to the current one. The address only changes once the link is opened
+// @Tags users
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body ChangeEmailRequest true "New email address and password"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} ErrorResponse "Invalid Email"
+// @Failure 401 {string} string "Invalid Credentials"
+// @Failure 403 {object} ErrorResponse "Email Not Allowed"
+// @Failure 409 {string} string "Email Already Exists"
+// @Failure 429 {string} string "Too Many Requests"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/email [post]
+func (h *AuthHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var req ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to parse change email request", slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	// Single sign-on accounts have no password, the link to the new address is their only check
+	if user.PasswordHash != "" {
+		if req.Password == "" {
+			http.Error(w, "Password is required", http.StatusBadRequest)
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+			h.log.Info("email change with wrong password", slog.String("userId", user.ID), slog.String("ip", middleware.ClientIP(r)))
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	email, ok := h.checkNewEmail(w, r, user, req.Email)
+	if !ok {
+		return
+	}
+	if user.PendingEmail == email && user.EmailChangeSentAt != nil && time.Since(*user.EmailChangeSentAt) < verificationResendInterval {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Confirmation email was sent recently, please wait a minute", http.StatusTooManyRequests)
+		return
+	}
+
+	now := time.Now().UTC()
+	user.PendingEmail = email
+	user.EmailChangeSentAt = &now
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "The account was changed meanwhile, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to save pending email", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to change email", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := middleware.GeneratePurposeToken(h.keys, middleware.PurposeEmailChange, user.ID, email, h.config.VerificationTTL)
+	if err == nil {
+		err = h.notifier.SendEmailChange(r.Context(), user, token, h.config.VerificationTTL)
+	}
+	if err != nil {
+		h.log.Error("failed to send email change confirmation", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to send confirmation email", http.StatusInternalServerError)
+		return
+	}
+	// The confirmation is on its way, so a failed notice is only logged
+	if err := h.notifier.SendEmailChangeNotice(r.Context(), user); err != nil {
+		h.log.Error("failed to send email change notice", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+
+	h.log.Info("email change requested", slog.String("userId", user.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if _, err := w.Write([]byte(`{"message":"Confirmation link sent to the new address"}`)); err != nil {
+		h.log.Warn("Failed to write")
+	}
+}
+
+// ConfirmEmailChange handles POST requests to commit an email change with the token from the confirmation link
+// @Summary Confirm email change
+// @Description Change the email address with the token sent to the new address. Every other session is logged out, and this one gets new tokens
+// @Tags users
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body ConfirmEmailChangeRequest true "Confirmation token"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {string} string "Invalid Or Expired Token"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Email Already Exists"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/email/confirm [post]
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmEmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	purpose, err := middleware.ParsePurposeToken(req.Token, h.keys, middleware.PurposeEmailChange)
+	if err != nil {
+		h.log.Debug("invalid email change token presented", slog.String("error", err.Error()))
+		http.Error(w, "Invalid or expired confirmation token", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	// Links for another account, or for an address a newer request replaced, are not valid
+	if purpose.Subject != user.ID || user.PendingEmail == "" || purpose.Email != user.PendingEmail {
+		http.Error(w, "Invalid or expired confirmation token", http.StatusBadRequest)
+		return
+	}
+
+	// Another account may have taken the address since the change was requested
+	email, ok := h.checkNewEmail(w, r, user, purpose.Email)
+	if !ok {
+		return
+	}
+
+	// Opening the link verified the new address. A reset link sent to the old address stops working.
+	now := time.Now().UTC()
+	user.Email = email
+	user.PendingEmail = ""
+	user.EmailChangeSentAt = nil
+	user.Unverified = false
+	user.EmailVerifiedAt = &now
+	user.PendingRole = "" // The role of the old address's domain was never proven
+	user.PasswordResetHash = ""
+	user.PasswordResetExpiresAt = nil
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "The account was changed meanwhile, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to save new email", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to change email", http.StatusInternalServerError)
+		return
+	}
+
+	// Tokens carry the email, so every session is logged out and this client gets tokens with the new one
+	if err := revokeAllSessions(r.Context(), h.cosmosService, h.revocations, user.ID); err != nil {
+		h.log.Error("failed to revoke sessions after email change", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Email was changed, but failed to log out other sessions", http.StatusInternalServerError)
+		return
+	}
+	claims, _ := middleware.GetClaims(r.Context())
+	tokens, err := h.issueTokens(r, user, uuid.New().String(), claims != nil && claims.MFA)
+	if err != nil {
+		h.log.Error("failed to issue tokens", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		h.clearAuthCookies(w)
+		http.Error(w, "Email was changed, please log in again", http.StatusInternalServerError)
+		return
+	}
+	h.setAuthCookies(w, tokens)
+
+	h.log.Info("email changed", slog.String("userId", user.ID))
+
+	response := h.loginResponse(user, tokens)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// checkNewEmail applies the registration rules to a new email address for the user and returns it normalized.
+// It writes the error response and returns false if the address may not be used.
+func (h *AuthHandler) checkNewEmail(w http.ResponseWriter, r *http.Request, user *models.User, newEmail string) (string, bool) {
+	// The domain rules apply, but the role stays the one the account has
+	email, _, err := h.config.Registration.Check(newEmail)
+	if err != nil {
+		if errors.Is(err, registration.ErrDomainNotAllowed) {
+			writeError(w, http.StatusForbidden, ErrCodeEmailDomainNotAllowed, "Only institutional email addresses can be used")
+			return "", false
+		}
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidEmail, "Invalid email address")
+		return "", false
+	}
+	if strings.EqualFold(email, user.Email) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidEmail, "The new email must differ from the current one")
+		return "", false
+	}
+
+	denied, err := h.cosmosService.IsEmailDenied(r.Context(), email)
+	if err != nil {
+		h.log.Error("failed to check email denylist", slog.String("error", err.Error()))
+		http.Error(w, "Failed to check existing user", http.StatusInternalServerError)
+		return "", false
+	}
+	if denied {
+		h.log.Info("email change to denied email", slog.String("userId", user.ID))
+		writeError(w, http.StatusForbidden, ErrCodeEmailDenied, "This email address may not be used")
+		return "", false
+	}
+
+	existingUser, err := h.cosmosService.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		h.log.Error("failed to check existing user by email", slog.String("error", err.Error()))
+		http.Error(w, "Failed to check existing user", http.StatusInternalServerError)
+		return "", false
+	}
+	if existingUser != nil && existingUser.ID != user.ID {
+		h.log.Debug("email change to existing email", slog.String("userId", user.ID))
+		http.Error(w, cosmos.ErrEmailAlreadyExists.Error(), http.StatusConflict)
+		return "", false
+	}
+	return email, true
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNewEmailRejects(t *testing.T) {
+	policy, err := registration.NewPolicy([]string{"*.university.edu"}, nil)
+	require.NoError(t, err)
+	h := NewAuthHandler(nil, nil, nil, nil, nil, AuthConfig{Registration: policy}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	user := &models.User{ID: "user-1", Email: "jane@cs.university.edu"}
+
+	tests := []struct {
+		name       string
+		email      string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "other domain", email: "jane@gmail.com", wantStatus: http.StatusForbidden, wantCode: ErrCodeEmailDomainNotAllowed},
+		{name: "invalid email", email: "jane", wantStatus: http.StatusBadRequest, wantCode: ErrCodeInvalidEmail},
+		{name: "current email", email: "Jane@CS.university.edu", wantStatus: http.StatusBadRequest, wantCode: ErrCodeInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/users/me/email", nil)
+			rec := httptest.NewRecorder()
+			_, ok := h.checkNewEmail(rec, req, user, tt.email)
+
+			assert.False(t, ok)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			var response ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantCode, response.Code)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes returned in ErrorResponse
+const (
+	ErrCodeInvalidEmail          = "invalid_email"
+	ErrCodeEmailDomainNotAllowed = "email_domain_not_allowed"
+	ErrCodeEmailDenied           = "email_denied"
+	ErrCodeAccountLocked         = "account_locked"
+	ErrCodeWeakPassword          = "weak_password"
+)
+
+// ErrorResponse is the body of errors that clients need to tell apart
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeError writes a JSON error with a machine-readable code
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Code: code, Message: message})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+)
+
+// JWKSHandler publishes the public keys that verify access tokens
+type JWKSHandler struct {
+	keys *middleware.KeyRing
+	log  *slog.Logger
+}
+
+// NewJWKSHandler creates a new JWKSHandler
+func NewJWKSHandler(keys *middleware.KeyRing, log *slog.Logger) *JWKSHandler {
+	const module = "jwksHandler"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &JWKSHandler{
+		keys: keys,
+		log:  log,
+	}
+}
+
+// GetJWKS handles GET requests for the JSON Web Key Set
+// @Summary Get token verification keys
+// @Description Get the public keys (JWKS) that verify access tokens, including the next key before it starts signing
+// @Tags auth
+// @Produce json
+// @Success 200 {object} middleware.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.keys.JWKS()); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/authz"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/totp"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// mfaChallengeTTL is how long the second login step may take
+	mfaChallengeTTL = 5 * time.Minute
+	// recoveryCodeCount is how many recovery codes are issued at a time
+	recoveryCodeCount = 10
+)
+
+// MFAChallengeResponse is returned by Login instead of tokens when the user has two-factor authentication
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`  // Send with the code to /api/auth/mfa/verify
+	ExpiresIn   int    `json:"expiresIn"` // Challenge lifetime in seconds
+}
+
+// MFAVerifyRequest represents the second login step
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfaToken"`
+	Code     string `json:"code"` // Authenticator code or recovery code
+}
+
+// MFAEnrollResponse contains the secret to add to an authenticator app
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"` // otpauth:// URI to show as a QR code
+}
+
+// MFACodeRequest represents a request confirmed with an authenticator code
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAConfirmResponse contains the recovery codes, shown only once, and tokens for a two-factor session
+type MFAConfirmResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+	LoginResponse
+}
+
+// MFADisableRequest represents the disable two-factor authentication request body
+type MFADisableRequest struct {
+	Password string `json:"password,omitempty"` // Required unless the account has no password
+	Code     string `json:"code"`               // Authenticator code or recovery code
+}
+
+// RecoveryCodesResponse contains newly generated recovery codes, shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// VerifyMFA completes a login with the challenge token from Login and an authenticator or recovery code
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		http.Error(w, "MFA token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := middleware.ParsePurposeToken(req.MFAToken, h.keys, middleware.PurposeMFAChallenge)
+	if err != nil {
+		h.log.Debug("invalid MFA challenge token presented", slog.String("error", err.Error()))
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+
+	// Wrong codes count as failed logins of the account
+	lockedFor, err := h.lockout.Locked(r.Context(), claims.Email)
+	if err != nil {
+		h.log.Error("failed to check account lockout", slog.String("error", err.Error()))
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if lockedFor > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(lockedFor.Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, ErrCodeAccountLocked, "Too many failed login attempts, try again later")
+		return
+	}
+
+	user, err := h.cosmosService.GetUserByID(r.Context(), claims.Subject)
+	if err != nil {
+		h.log.Error("failed to get user for MFA verification", slog.String("userId", claims.Subject), slog.String("error", err.Error()))
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if user == nil || user.Email != claims.Email || !user.MFAEnabled() {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+
+	if h.refuseSuspended(w, user) {
+		return
+	}
+
+	method := useSecondFactor(user, req.Code, time.Now())
+	if method == "" {
+		if _, err := h.lockout.Failed(r.Context(), user.Email, middleware.ClientIP(r)); err != nil {
+			h.log.Error("failed to record failed login", slog.String("error", err.Error()))
+		}
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	// The conditional write makes every code single-use, a concurrent login with the same code loses
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "Invalid code", http.StatusUnauthorized)
+			return
+		}
+		h.log.Error("failed to save used MFA code", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.lockout.Succeeded(r.Context(), user.Email); err != nil {
+		h.log.Error("failed to reset failed logins", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+
+	tokens, err := h.issueTokens(r, user, uuid.New().String(), true)
+	if err != nil {
+		h.log.Error("failed to issue tokens", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	h.setAuthCookies(w, tokens)
+
+	h.log.Info("user logged in with two-factor authentication", slog.String("userId", user.ID), slog.String("method", method), slog.Int("recoveryCodesLeft", len(user.MFARecoveryCodes)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.loginResponse(user, tokens)); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// EnrollMFA starts two-factor enrollment for the current user with a new secret. The secret is only used
+// after ConfirmMFA, so an abandoned enrollment does not lock the user out.
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.MFAEnabled() {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		h.log.Error("failed to generate TOTP secret", slog.String("error", err.Error()))
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+	user.MFAPendingSecret = secret
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		h.log.Error("failed to save pending TOTP secret", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("two-factor enrollment started", slog.String("userId", user.ID))
+
+	response := MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(secret, h.config.MFAIssuer, user.Email),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// ConfirmMFA enables two-factor authentication once the user entered a code for the pending secret.
+// It returns the recovery codes and tokens for a two-factor session, so admins need not log in again.
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.MFAPendingSecret == "" {
+		http.Error(w, "No two-factor enrollment in progress", http.StatusBadRequest)
+		return
+	}
+
+	step, valid := totp.Validate(user.MFAPendingSecret, req.Code, time.Now())
+	if !valid {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	recoveryCodes := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	user.MFASecret = user.MFAPendingSecret
+	user.MFAPendingSecret = ""
+	user.MFAEnabledAt = &now
+	user.MFALastStep = step
+	user.MFARecoveryCodes = hashRecoveryCodes(recoveryCodes)
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "Enrollment changed concurrently, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to enable two-factor authentication", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user, uuid.New().String(), true)
+	if err != nil {
+		h.log.Error("failed to issue tokens", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	h.setAuthCookies(w, tokens)
+
+	h.log.Info("two-factor authentication enabled", slog.String("userId", user.ID))
+
+	response := MFAConfirmResponse{
+		RecoveryCodes: recoveryCodes,
+		LoginResponse: h.loginResponse(user, tokens),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// DisableMFA turns two-factor authentication off after checking the password and a code
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	var req MFADisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !user.MFAEnabled() {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+	if h.config.RequireAdminMFA && authz.Privileged(user.Role) {
+		http.Error(w, "Forbidden: your role requires two-factor authentication", http.StatusForbidden)
+		return
+	}
+
+	// Single sign-on accounts have no password, the code is their only check
+	if user.PasswordHash != "" {
+		if req.Password == "" {
+			http.Error(w, "Password is required", http.StatusBadRequest)
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+	}
+	if useSecondFactor(user, req.Code, time.Now()) == "" {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	user.MFASecret = ""
+	user.MFAPendingSecret = ""
+	user.MFAEnabledAt = nil
+	user.MFARecoveryCodes = nil
+	user.MFALastStep = 0
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "Invalid code", http.StatusUnauthorized)
+			return
+		}
+		h.log.Error("failed to disable two-factor authentication", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("two-factor authentication disabled", slog.String("userId", user.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(`{"message":"Two-factor authentication disabled"}`)); err != nil {
+		h.log.Warn("Failed to write")
+	}
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user after checking an authenticator code
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !user.MFAEnabled() {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+
+	// Only an authenticator code is accepted, a leaked recovery code must not yield new ones
+	step, valid := totp.Validate(user.MFASecret, req.Code, time.Now())
+	if !valid || step <= user.MFALastStep {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	recoveryCodes := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	user.MFALastStep = step
+	user.MFARecoveryCodes = hashRecoveryCodes(recoveryCodes)
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "Invalid code", http.StatusUnauthorized)
+			return
+		}
+		h.log.Error("failed to save recovery codes", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("recovery codes regenerated", slog.String("userId", user.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: recoveryCodes}); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// writeMFAChallenge answers a correct password of a two-factor user with a challenge instead of tokens
+func (h *AuthHandler) writeMFAChallenge(w http.ResponseWriter, user *models.User) {
+	token, err := middleware.GeneratePurposeToken(h.keys, middleware.PurposeMFAChallenge, user.ID, user.Email, mfaChallengeTTL)
+	if err != nil {
+		h.log.Error("failed to generate MFA challenge", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("password accepted, second factor required", slog.String("userId", user.ID))
+
+	response := MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(mfaChallengeTTL.Seconds()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// currentUser loads the authenticated user, writing an error response if that fails
+func (h *AuthHandler) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	user, err := h.cosmosService.GetUserByID(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to get user", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// useSecondFactor checks an authenticator or recovery code and marks it used on user, which the caller
+// must save with a conditional write. It returns "totp" or "recovery", or "" if the code is not valid.
+func useSecondFactor(user *models.User, code string, now time.Time) string {
+	if step, valid := totp.Validate(user.MFASecret, code, now); valid {
+		if step <= user.MFALastStep {
+			return "" // Replayed code
+		}
+		user.MFALastStep = step
+		return "totp"
+	}
+
+	hash := middleware.HashToken(totp.NormalizeRecoveryCode(code))
+	if i := slices.Index(user.MFARecoveryCodes, hash); i >= 0 {
+		user.MFARecoveryCodes = slices.Delete(user.MFARecoveryCodes, i, i+1)
+		return "recovery"
+	}
+	return ""
+}
+
+// hashRecoveryCodes returns the hashes of recovery codes to store
+func hashRecoveryCodes(codes []string) []string {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = middleware.HashToken(code)
+	}
+	return hashes
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseSecondFactor(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	recoveryCodes := totp.GenerateRecoveryCodes(2)
+	now := time.Now()
+	enabledAt := now.Add(-time.Hour)
+
+	user := &models.User{
+		MFASecret:        secret,
+		MFAEnabledAt:     &enabledAt,
+		MFARecoveryCodes: hashRecoveryCodes(recoveryCodes),
+	}
+
+	code, err := totp.Code(secret, totp.Step(now))
+	require.NoError(t, err)
+
+	// An authenticator code works once
+	assert.Equal(t, "totp", useSecondFactor(user, code, now))
+	assert.Equal(t, totp.Step(now), user.MFALastStep)
+	assert.Empty(t, useSecondFactor(user, code, now), "replayed code must be rejected")
+
+	// Codes of earlier steps are rejected after a later one was used
+	previous, err := totp.Code(secret, totp.Step(now)-1)
+	require.NoError(t, err)
+	assert.Empty(t, useSecondFactor(user, previous, now))
+
+	// Recovery codes are single-use and case-insensitive
+	assert.Equal(t, "recovery", useSecondFactor(user, " "+recoveryCodes[1]+" ", now))
+	assert.Len(t, user.MFARecoveryCodes, 1)
+	assert.Empty(t, useSecondFactor(user, recoveryCodes[1], now))
+
+	assert.Empty(t, useSecondFactor(user, "000000", now.Add(time.Hour)))
+	assert.Empty(t, useSecondFactor(user, "wrong-codes", now))
+}
+
+func TestLoginResponseMFAEnrollmentRequired(t *testing.T) {
+	enabledAt := time.Now()
+	tokens := &tokenPair{accessToken: "access", refreshToken: "refresh"}
+
+	tests := []struct {
+		name     string
+		require  bool
+		user     models.User
+		expected bool
+	}{
+		{name: "admin without MFA", require: true, user: models.User{Role: models.RoleAdmin}, expected: true},
+		{name: "admin with MFA", require: true, user: models.User{Role: models.RoleAdmin, MFASecret: "secret", MFAEnabledAt: &enabledAt}},
+		{name: "student without MFA", require: true, user: models.User{Role: models.RoleStudent}},
+		{name: "not required", require: false, user: models.User{Role: models.RoleAdmin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &AuthHandler{config: AuthConfig{RequireAdminMFA: tt.require, AccessTokenTTL: time.Minute}}
+			response := h.loginResponse(&tt.user, tokens)
+			assert.Equal(t, tt.expected, response.MFAEnrollmentRequired)
+			assert.Equal(t, 60, response.ExpiresIn)
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/webhook"
+)
+
+// NotificationsHandler handles in-app notification inbox requests
+type NotificationsHandler struct {
+	cosmosService *cosmos.Service
+	log           *slog.Logger
+}
+
+// NewNotificationsHandler creates a new NotificationsHandler
+func NewNotificationsHandler(cosmosService *cosmos.Service, log *slog.Logger) *NotificationsHandler {
+	const module = "notificationsHandler"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &NotificationsHandler{
+		cosmosService: cosmosService,
+		log:           log,
+	}
+}
+
+// GetNotifications handles GET requests to retrieve the current user's notifications
+// @Summary Get notifications
+// @Description Get the authenticated user's notifications, newest first. Use unread=true to only get unread ones
+// @Tags notifications
+// @Security Bearer
+// @Produce json
+// @Param unread query bool false "Only return unread notifications"
+// @Success 200 {array} models.Notification
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/notifications [get]
+func (h *NotificationsHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := h.cosmosService.GetNotifications(r.Context(), userId, unreadOnly)
+	if err != nil {
+		h.log.Error("failed to get notifications", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(notifications); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// MarkNotificationRead handles POST requests to mark a single notification as read
+// @Summary Mark a notification as read
+// @Description Mark one of the authenticated user's notifications as read
+// @Tags notifications
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} models.Notification
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Notification Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/notifications/{id}/read [post]
+func (h *NotificationsHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	// Get notification ID from URL parameter
+	notificationId := r.PathValue("id")
+	if notificationId == "" {
+		h.log.Debug("notification id not provided in URL", slog.String("userId", userId))
+		http.Error(w, "Notification ID required", http.StatusBadRequest)
+		return
+	}
+
+	notification, err := h.cosmosService.MarkNotificationRead(r.Context(), userId, notificationId)
+	if err != nil {
+		if errors.Is(err, cosmos.ErrNotificationNotFound) {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error("failed to mark notification as read", slog.String("userId", userId), slog.String("notificationId", notificationId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to update notification", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(notification); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("notificationId", notificationId), slog.String("error", err.Error()))
+	}
+}
+
+// MarkAllNotificationsRead handles POST requests to mark all of the current user's notifications as read
+// @Summary Mark all notifications as read
+// @Description Mark every unread notification of the authenticated user as read
+// @Tags notifications
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/notifications/read-all [post]
+func (h *NotificationsHandler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := h.cosmosService.MarkAllNotificationsRead(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to mark all notifications as read", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("all notifications marked as read", slog.String("userId", userId), slog.Int("count", count))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]interface{}{
+		"message": "All notifications marked as read",
+		"updated": count,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// NotificationPreferencesRequest represents the update notification preferences request body.
+// Event types left out keep their default preference.
+type NotificationPreferencesRequest struct {
+	Events              map[string]models.EventPreference `json:"events"`
+	WebhookURL          string                            `json:"webhookUrl,omitempty"`
+	RotateWebhookSecret bool                              `json:"rotateWebhookSecret,omitempty"` // Generate a new secret for an unchanged webhookUrl
+	AdminDigest         string                            `json:"adminDigest,omitempty"`         // daily, weekly or off; only used for admins
+}
+
+// GetNotificationPreferences handles GET requests to retrieve the current user's notification preferences
+// @Summary Get notification preferences
+// @Description Get the channels and delivery mode used for each notification event type. Defaults are returned for unset event types
+// @Tags notifications
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/notification-preferences [get]
+func (h *NotificationsHandler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	prefs, err := h.cosmosService.GetNotificationPreferences(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to get notification preferences", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(withDefaultPreferences(userId, prefs)); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// UpdateNotificationPreferences handles PUT requests to replace the current user's notification preferences
+// @Summary Update notification preferences
+// @Description Set the channels (in_app, email, webhook) and delivery (immediate, digest) for each event type. The webhook channel requires an https webhookUrl on a public address. A new webhookUrl, or rotateWebhookSecret, generates the webhookSecret that signs deliveries; it is only returned then. Admins can also set adminDigest (daily, weekly, off)
+// @Tags notifications
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body NotificationPreferencesRequest true "Notification preferences"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/notification-preferences [put]
+func (h *NotificationsHandler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req NotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to parse notification preferences request", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateNotificationPreferences(&req); err != nil {
+		h.log.Debug("invalid notification preferences", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Personal webhooks are sent from inside the deployment, so they may not reach internal hosts.
+	// The delivery worker checks the dialed address again, in case the host resolves differently by then.
+	if req.WebhookURL != "" {
+		if err := webhook.CheckPublicURL(r.Context(), req.WebhookURL); err != nil {
+			h.log.Info("webhook URL rejected", slog.String("userId", userId), slog.String("error", err.Error()))
+			http.Error(w, "webhookUrl must resolve to a public address", http.StatusBadRequest)
+			return
+		}
+	}
+
+	current, err := h.cosmosService.GetNotificationPreferences(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to get notification preferences", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to update notification preferences", http.StatusInternalServerError)
+		return
+	}
+	secret, generated := webhookSecretFor(current, &req)
+
+	prefs := &models.NotificationPreferences{
+		UserID:        userId,
+		Events:        req.Events,
+		WebhookURL:    req.WebhookURL,
+		WebhookSecret: secret,
+		AdminDigest:   req.AdminDigest,
+	}
+	if err := h.cosmosService.SaveNotificationPreferences(r.Context(), prefs); err != nil {
+		h.log.Error("failed to save notification preferences", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to update notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	// Like admin webhook secrets, the secret is only shown when it is generated
+	response := withDefaultPreferences(userId, prefs)
+	if generated {
+		response.WebhookSecret = secret
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// validateNotificationPreferences checks event types, channels and delivery modes, and fills in immediate delivery when it is omitted
+func validateNotificationPreferences(req *NotificationPreferencesRequest) error {
+	usesWebhook := false
+	for eventType, pref := range req.Events {
+		if !slices.Contains(models.NotificationEventTypes, eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+		for _, channel := range pref.Channels {
+			switch channel {
+			case models.ChannelInApp, models.ChannelEmail:
+			case models.ChannelWebhook:
+				usesWebhook = true
+			default:
+				return fmt.Errorf("unknown channel %q for event type %q", channel, eventType)
+			}
+		}
+		switch pref.Delivery {
+		case "":
+			pref.Delivery = models.DeliveryImmediate
+			req.Events[eventType] = pref
+		case models.DeliveryImmediate, models.DeliveryDigest:
+		default:
+			return fmt.Errorf("delivery must be %q or %q", models.DeliveryImmediate, models.DeliveryDigest)
+		}
+	}
+
+	switch req.AdminDigest {
+	case "", models.AdminDigestDaily, models.AdminDigestWeekly, models.AdminDigestOff:
+	default:
+		return fmt.Errorf("adminDigest must be %q, %q or %q", models.AdminDigestDaily, models.AdminDigestWeekly, models.AdminDigestOff)
+	}
+
+	if req.WebhookURL != "" {
+		u, err := url.Parse(req.WebhookURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("webhookUrl must be an absolute https URL")
+		}
+	} else if usesWebhook {
+		return errors.New("webhookUrl is required for the webhook channel")
+	}
+	return nil
+}
+
+// webhookSecretFor returns the secret that signs deliveries to the requested webhook URL and whether it was just
+// generated. The current secret is kept while the URL stays the same, unless the request rotates it.
+func webhookSecretFor(current *models.NotificationPreferences, req *NotificationPreferencesRequest) (string, bool) {
+	if req.WebhookURL == "" {
+		return "", false
+	}
+	if current != nil && current.WebhookURL == req.WebhookURL && current.WebhookSecret != "" && !req.RotateWebhookSecret {
+		return current.WebhookSecret, false
+	}
+	return webhook.NewSecret(), true
+}
+
+// withDefaultPreferences returns the preferences with defaults filled in for unset event types, without the webhook
+// secret
+func withDefaultPreferences(userId string, prefs *models.NotificationPreferences) *models.NotificationPreferences {
+	result := models.DefaultNotificationPreferences(userId)
+	if prefs == nil {
+		return result
+	}
+	for eventType, pref := range prefs.Events {
+		result.Events[eventType] = pref
+	}
+	result.WebhookURL = prefs.WebhookURL
+	result.AdminDigest = prefs.AdminDigest
+	result.UpdatedAt = prefs.UpdatedAt
+	return result
+}
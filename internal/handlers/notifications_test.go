@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNotificationPreferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     NotificationPreferencesRequest
+		wantErr bool
+	}{
+		{
+			name: "valid preferences",
+			req: NotificationPreferencesRequest{Events: map[string]models.EventPreference{
+				models.EventStatusChanged:  {Channels: []string{models.ChannelInApp, models.ChannelEmail}, Delivery: models.DeliveryImmediate},
+				models.EventComplaintLiked: {Channels: []string{models.ChannelEmail}, Delivery: models.DeliveryDigest},
+			}},
+		},
+		{
+			name: "no channels disables an event",
+			req: NotificationPreferencesRequest{Events: map[string]models.EventPreference{
+				models.EventNewComment: {Channels: []string{}},
+			}},
+		},
+		{
+			name: "unknown event type",
+			req: NotificationPreferencesRequest{Events: map[string]models.EventPreference{
+				"deleted": {Channels: []string{models.ChannelEmail}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "unknown channel",
+			req: NotificationPreferencesRequest{Events: map[string]models.EventPreference{
+				models.EventStatusChanged: {Channels: []string{"sms"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "unknown delivery",
+			req: NotificationPreferencesRequest{Events: map[string]models.EventPreference{
+				models.EventStatusChanged: {Channels: []string{models.ChannelEmail}, Delivery: "weekly"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "webhook channel without URL",
+			req: NotificationPreferencesRequest{Events: map[string]models.EventPreference{
+				models.EventEscalation: {Channels: []string{models.ChannelWebhook}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "webhook URL must use https",
+			req: NotificationPreferencesRequest{
+				Events: map[string]models.EventPreference{
+					models.EventEscalation: {Channels: []string{models.ChannelWebhook}},
+				},
+				WebhookURL: "http://hooks.example.edu/portal",
+			},
+			wantErr: true,
+		},
+		{
+			name: "weekly admin digest",
+			req:  NotificationPreferencesRequest{AdminDigest: models.AdminDigestWeekly},
+		},
+		{
+			name:    "unknown admin digest frequency",
+			req:     NotificationPreferencesRequest{AdminDigest: "hourly"},
+			wantErr: true,
+		},
+		{
+			name: "webhook channel with https URL",
+			req: NotificationPreferencesRequest{
+				Events: map[string]models.EventPreference{
+					models.EventEscalation: {Channels: []string{models.ChannelWebhook}},
+				},
+				WebhookURL: "https://hooks.example.edu/portal",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNotificationPreferences(&tt.req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			for _, pref := range tt.req.Events {
+				assert.NotEmpty(t, pref.Delivery, "delivery should default to immediate")
+			}
+		})
+	}
+}
+
+func TestWithDefaultPreferences(t *testing.T) {
+	prefs := withDefaultPreferences("user-1", &models.NotificationPreferences{
+		WebhookURL:    "https://example.com/hook",
+		WebhookSecret: "s3cret",
+		Events: map[string]models.EventPreference{
+			models.EventNewComment: {Channels: []string{models.ChannelEmail}, Delivery: models.DeliveryDigest},
+		},
+	})
+
+	assert.Equal(t, "user-1", prefs.UserID)
+	assert.Len(t, prefs.Events, len(models.NotificationEventTypes))
+	assert.True(t, prefs.Events[models.EventNewComment].IsDigest())
+	assert.True(t, prefs.Events[models.EventStatusChanged].Has(models.ChannelEmail))
+	assert.Equal(t, "https://example.com/hook", prefs.WebhookURL)
+	assert.Empty(t, prefs.WebhookSecret)
+}
+
+func TestWebhookSecretFor(t *testing.T) {
+	current := &models.NotificationPreferences{WebhookURL: "https://example.com/hook", WebhookSecret: "s3cret"}
+
+	secret, generated := webhookSecretFor(current, &NotificationPreferencesRequest{WebhookURL: "https://example.com/hook"})
+	assert.Equal(t, "s3cret", secret)
+	assert.False(t, generated)
+
+	secret, generated = webhookSecretFor(current, &NotificationPreferencesRequest{WebhookURL: "https://example.com/hook", RotateWebhookSecret: true})
+	assert.True(t, generated)
+	assert.NotEmpty(t, secret)
+	assert.NotEqual(t, "s3cret", secret)
+
+	secret, generated = webhookSecretFor(current, &NotificationPreferencesRequest{WebhookURL: "https://example.com/other"})
+	assert.True(t, generated)
+	assert.NotEqual(t, "s3cret", secret)
+
+	secret, generated = webhookSecretFor(nil, &NotificationPreferencesRequest{WebhookURL: "https://example.com/hook"})
+	assert.True(t, generated)
+	assert.NotEmpty(t, secret)
+
+	secret, generated = webhookSecretFor(current, &NotificationPreferencesRequest{})
+	assert.Empty(t, secret)
+	assert.False(t, generated)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/oidc"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/registration"
+	"github.com/google/uuid"
+)
+
+const (
+	// oidcStateCookieName holds the login state between the redirect to the identity provider and the callback
+	oidcStateCookieName = "oidc_state"
+	oidcStateCookiePath = "/api/auth/oidc"
+	// oidcLoginTTL is how long the user may take to log in at the identity provider
+	oidcLoginTTL = 10 * time.Minute
+)
+
+// Error codes passed to the frontend login page after a failed single sign-on
+const (
+	oidcErrFailed     = "sso_failed"
+	oidcErrNotAllowed = "sso_not_allowed"
+	oidcErrExists     = "sso_account_exists"
+	oidcErrDenied     = ErrCodeEmailDenied
+	oidcErrSuspended  = middleware.ErrCodeAccountSuspended
+)
+
+var (
+	errOIDCEmailUnverified = errors.New("identity provider has not verified the email address")
+	errOIDCEmailDenied     = errors.New("email address is on the registration denylist")
+	errOIDCAccountExists   = errors.New("an account with the email address exists and is not linked")
+)
+
+// OIDCLogin redirects the browser to the university identity provider
+func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	state := oidc.NewLoginState()
+	authURL, err := h.config.OIDC.AuthCodeURL(r.Context(), state)
+	if err != nil {
+		h.log.Error("failed to start single sign-on", slog.String("error", err.Error()))
+		h.redirectOIDCError(w, r, oidcErrFailed)
+		return
+	}
+
+	// Lax, so the cookie comes back with the top-level redirect from the identity provider
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state.Encode(),
+		Path:     oidcStateCookiePath,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback completes single sign-on: it redeems the authorization code, provisions or links the user and
+// redirects to the frontend with the auth cookies set
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	// The state cookie is single-use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    "",
+		Path:     oidcStateCookiePath,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1, // Delete cookie
+	})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.log.Info("identity provider returned an error", slog.String("error", providerErr), slog.String("description", query.Get("error_description")))
+		h.redirectOIDCError(w, r, oidcErrFailed)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil {
+		h.log.Debug("single sign-on callback without state cookie")
+		h.redirectOIDCError(w, r, oidcErrFailed)
+		return
+	}
+	state, err := oidc.DecodeLoginState(cookie.Value)
+	if err != nil || subtle.ConstantTimeCompare([]byte(state.State), []byte(query.Get("state"))) != 1 {
+		h.log.Warn("single sign-on callback with mismatched state", slog.String("ip", middleware.ClientIP(r)))
+		h.redirectOIDCError(w, r, oidcErrFailed)
+		return
+	}
+
+	identity, err := h.config.OIDC.Exchange(r.Context(), query.Get("code"), state)
+	if err != nil {
+		h.log.Error("failed to redeem authorization code", slog.String("error", err.Error()))
+		h.redirectOIDCError(w, r, oidcErrFailed)
+		return
+	}
+
+	role, err := h.config.OIDC.Role(identity.Groups)
+	if err != nil {
+		h.log.Info("single sign-on refused for user outside allowed groups", slog.String("subject", identity.Subject))
+		h.redirectOIDCError(w, r, oidcErrNotAllowed)
+		return
+	}
+
+	user, err := h.oidcUser(r.Context(), identity, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, errOIDCEmailDenied):
+			h.redirectOIDCError(w, r, oidcErrDenied)
+		case errors.Is(err, errOIDCAccountExists):
+			h.log.Info("single sign-on refused for unlinked account", slog.String("subject", identity.Subject))
+			h.redirectOIDCError(w, r, oidcErrExists)
+		case errors.Is(err, errOIDCEmailUnverified), errors.Is(err, registration.ErrInvalidEmail):
+			h.log.Info("single sign-on refused", slog.String("subject", identity.Subject), slog.String("error", err.Error()))
+			h.redirectOIDCError(w, r, oidcErrNotAllowed)
+		default:
+			h.log.Error("failed to provision single sign-on user", slog.String("subject", identity.Subject), slog.String("error", err.Error()))
+			h.redirectOIDCError(w, r, oidcErrFailed)
+		}
+		return
+	}
+
+	if user.Suspended() {
+		h.log.Info("single sign-on refused for suspended account", slog.String("userId", user.ID))
+		h.redirectOIDCError(w, r, oidcErrSuspended)
+		return
+	}
+
+	// Portal two-factor authentication still applies, unless the identity provider did its own
+	if user.MFAEnabled() && !identity.MFA {
+		token, err := middleware.GeneratePurposeToken(h.keys, middleware.PurposeMFAChallenge, user.ID, user.Email, mfaChallengeTTL)
+		if err != nil {
+			h.log.Error("failed to generate MFA challenge", slog.String("userId", user.ID), slog.String("error", err.Error()))
+			h.redirectOIDCError(w, r, oidcErrFailed)
+			return
+		}
+		// In the fragment, so the token never reaches server logs
+		http.Redirect(w, r, h.config.FrontendURL+"/login/mfa#mfaToken="+url.QueryEscape(token), http.StatusFound)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user, uuid.New().String(), identity.MFA)
+	if err != nil {
+		h.log.Error("failed to issue tokens", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		h.redirectOIDCError(w, r, oidcErrFailed)
+		return
+	}
+	h.setAuthCookies(w, tokens)
+
+	h.log.Info("user logged in with single sign-on", slog.String("userId", user.ID), slog.String("role", user.Role))
+
+	http.Redirect(w, r, h.config.FrontendURL+"/auth/callback", http.StatusFound)
+}
+
+// oidcUser returns the user linked to the identity, linking an account with the same email if OIDCLinkByEmail
+// allows it, or creating one.
+// The role follows the identity provider's groups on every login.
+func (h *AuthHandler) oidcUser(ctx context.Context, identity *oidc.Identity, role string) (*models.User, error) {
+	user, err := h.cosmosService.GetUserByOIDCSubject(ctx, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		if user.Role != role {
+			h.log.Info("role changed by identity provider groups", slog.String("userId", user.ID), slog.String("from", user.Role), slog.String("to", role))
+			user.Role = role
+			if err := h.cosmosService.ReplaceUser(ctx, user); err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	}
+
+	email, _, err := registration.NormalizeEmail(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = h.cosmosService.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if user != nil {
+		// Linking hands the account to whoever the provider vouches for. It needs the opt-in and a verified
+		// email, otherwise anyone able to set that email at the provider could take over the account.
+		if !h.config.OIDCLinkByEmail {
+			return nil, errOIDCAccountExists
+		}
+		if !identity.EmailVerified {
+			return nil, errOIDCEmailUnverified
+		}
+		user.OIDCSubject = identity.Subject
+		user.Role = role
+		if !user.EmailVerified() {
+			user.Unverified = false
+			user.EmailVerifiedAt = &now
+		}
+		if err := h.cosmosService.ReplaceUser(ctx, user); err != nil {
+			return nil, err
+		}
+		h.log.Info("account linked to identity provider", slog.String("userId", user.ID))
+		return user, nil
+	}
+
+	denied, err := h.cosmosService.IsEmailDenied(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if denied {
+		return nil, errOIDCEmailDenied
+	}
+
+	username, err := h.oidcUsername(ctx, identity, email)
+	if err != nil {
+		return nil, err
+	}
+	name := identity.Name
+	if name == "" {
+		name = username
+	}
+
+	// Accounts created through single sign-on have no password. The email counts as verified only if the provider says so.
+	user = &models.User{
+		ID:          uuid.New().String(),
+		Email:       email,
+		UserName:    username,
+		Name:        name,
+		Role:        role,
+		CreatedAt:   now,
+		OIDCSubject: identity.Subject,
+	}
+	if identity.EmailVerified {
+		user.EmailVerifiedAt = &now
+	} else {
+		user.Unverified = true
+	}
+	if err := h.cosmosService.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	h.log.Info("user provisioned by single sign-on", slog.String("userId", user.ID), slog.String("role", role))
+	return user, nil
+}
+
+// oidcUsername returns a free username for a new single sign-on user, preferring the provider's username
+func (h *AuthHandler) oidcUsername(ctx context.Context, identity *oidc.Identity, email string) (string, error) {
+	base := identity.Username
+	if base == "" || strings.Contains(base, "@") {
+		base = email[:strings.IndexByte(email, '@')]
+	}
+
+	username := base
+	for range 3 {
+		existing, err := h.cosmosService.GetUserByUsername(ctx, username)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return username, nil
+		}
+		username = base + "-" + uuid.New().String()[:6]
+	}
+	return username, nil
+}
+
+// redirectOIDCError sends the browser back to the frontend login page with an error code
+func (h *AuthHandler) redirectOIDCError(w http.ResponseWriter, r *http.Request, code string) {
+	http.Redirect(w, r, h.config.FrontendURL+"/login?error="+url.QueryEscape(code), http.StatusFound)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestOIDCHandler returns an AuthHandler for an identity provider that only serves its discovery document
+func newTestOIDCHandler(t *testing.T) (*AuthHandler, string) {
+	t.Helper()
+	var issuer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": issuer + "/authorize",
+			"token_endpoint":         issuer + "/token",
+			"jwks_uri":               issuer + "/jwks",
+		})
+	}))
+	t.Cleanup(server.Close)
+	issuer = server.URL
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	provider, err := oidc.NewProvider(oidc.Config{
+		IssuerURL:   issuer,
+		ClientID:    "portal",
+		RedirectURL: "https://portal.example/api/auth/oidc/callback",
+	}, nil, log)
+	require.NoError(t, err)
+
+	return NewAuthHandler(nil, nil, nil, nil, nil, AuthConfig{OIDC: provider, FrontendURL: "https://app.example"}, log), issuer
+}
+
+func TestOIDCLogin(t *testing.T) {
+	h, issuer := newTestOIDCHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.OIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+
+	require.Equal(t, http.StatusFound, rec.Code)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, issuer+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, oidcStateCookieName, cookies[0].Name)
+	assert.Equal(t, oidcStateCookiePath, cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+
+	state, err := oidc.DecodeLoginState(cookies[0].Value)
+	require.NoError(t, err)
+	assert.Equal(t, state.State, location.Query().Get("state"))
+	assert.Equal(t, state.Nonce, location.Query().Get("nonce"))
+	assert.NotContains(t, location.RawQuery, state.Verifier, "only the PKCE challenge may leave the browser")
+}
+
+func TestOIDCCallbackRejectsInvalidState(t *testing.T) {
+	h, _ := newTestOIDCHandler(t)
+	state := oidc.NewLoginState()
+
+	tests := []struct {
+		name   string
+		cookie string
+		query  string
+	}{
+		{name: "no cookie", query: "?code=abc&state=" + state.State},
+		{name: "state mismatch", cookie: state.Encode(), query: "?code=abc&state=other"},
+		{name: "malformed cookie", cookie: "garbage", query: "?code=abc&state=garbage"},
+		{name: "provider error", cookie: state.Encode(), query: "?error=access_denied&state=" + state.State},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookieName, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.OIDCCallback(rec, req)
+
+			assert.Equal(t, http.StatusFound, rec.Code)
+			assert.Equal(t, "https://app.example/login?error=sso_failed", rec.Header().Get("Location"))
+		})
+	}
+}
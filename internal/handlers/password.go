@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/password"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// forgotPasswordMessage is returned whether or not the email belongs to an account
+const forgotPasswordMessage = `{"message":"If an account with this email exists, a password reset link has been sent"}`
+
+// passwordResetTimeout bounds the background work of a forgot password request
+const passwordResetTimeout = 30 * time.Second
+
+// ForgotPasswordRequest represents the forgot password request body
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents the reset password request body
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ChangePasswordRequest represents the change password request body
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// ForgotPassword emails a single-use password reset link. The response does not reveal whether the email exists.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to parse forgot password request", slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	// The lookup, token write and email run after the response, so known and unknown addresses are answered
+	// equally fast. Failures are only logged, a different response would reveal that the account exists.
+	email := accountEmail(req.Email)
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, passwordResetTimeout)
+		defer cancel()
+		if err := h.sendPasswordReset(ctx, email); err != nil {
+			h.log.Error("failed to send password reset", slog.String("error", err.Error()))
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if _, err := w.Write([]byte(forgotPasswordMessage)); err != nil {
+		h.log.Warn("Failed to write")
+	}
+}
+
+// sendPasswordReset stores the hash of a new reset token on the user with the given email and emails the token.
+// A new request replaces the previous token.
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := h.cosmosService.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		h.log.Debug("password reset requested for unknown email")
+		return nil
+	}
+
+	token, hash := middleware.GenerateToken()
+	expiresAt := time.Now().UTC().Add(h.config.PasswordResetTTL)
+	user.PasswordResetHash = hash
+	user.PasswordResetExpiresAt = &expiresAt
+	if err := h.cosmosService.ReplaceUser(ctx, user); err != nil {
+		return err
+	}
+
+	if err := h.notifier.SendPasswordReset(ctx, user, token, h.config.PasswordResetTTL); err != nil {
+		return err
+	}
+
+	h.log.Info("password reset requested", slog.String("userId", user.ID))
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and revokes all of the user's sessions
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to parse reset password request", slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, "Token and password are required", http.StatusBadRequest)
+		return
+	}
+	if !h.checkPassword(w, req.Password) {
+		return
+	}
+
+	user, err := h.cosmosService.GetUserByPasswordResetHash(r.Context(), middleware.HashToken(req.Token))
+	if err != nil {
+		h.log.Error("failed to get user by password reset token", slog.String("error", err.Error()))
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	if user == nil || user.PasswordResetExpiresAt == nil || time.Now().After(*user.PasswordResetExpiresAt) {
+		h.log.Debug("invalid or expired password reset token presented")
+		http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		h.log.Error("failed to hash password", slog.String("error", err.Error()))
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+
+	// Clearing the token in the same conditional write makes it single-use, a concurrent reset loses
+	user.PasswordHash = string(hashedPassword)
+	user.PasswordResetHash = ""
+	user.PasswordResetExpiresAt = nil
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		h.log.Error("failed to save new password", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	// Whoever knew the old password must not stay logged in
+	if err := revokeAllSessions(r.Context(), h.cosmosService, h.revocations, user.ID); err != nil {
+		h.log.Error("failed to revoke sessions after password reset", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Password was reset, but failed to log out other sessions", http.StatusInternalServerError)
+		return
+	}
+
+	h.clearAuthCookies(w)
+
+	h.log.Info("password reset", slog.String("userId", user.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(`{"message":"Password has been reset, please log in"}`)); err != nil {
+		h.log.Warn("Failed to write")
+	}
+}
+
+// ChangePassword handles POST requests to change the current user's password
+// @Summary Change password
+// @Description Change the password after checking the current one. Every other session is logged out, and this one gets new tokens
+// @Tags users
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} ErrorResponse "Weak Password"
+// @Failure 401 {string} string "Invalid Current Password"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/password [post]
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to parse change password request", slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Current and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	// Single sign-on accounts and accounts after a forced reset have no password to change
+	if user.PasswordHash == "" {
+		http.Error(w, "This account has no password, use the password reset link instead", http.StatusConflict)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		h.log.Info("password change with wrong current password", slog.String("userId", user.ID), slog.String("ip", middleware.ClientIP(r)))
+		http.Error(w, "Invalid current password", http.StatusUnauthorized)
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		writeError(w, http.StatusBadRequest, ErrCodeWeakPassword, "The new password must differ from the current one")
+		return
+	}
+	if !h.checkPassword(w, req.NewPassword) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		h.log.Error("failed to hash password", slog.String("error", err.Error()))
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+
+	// A pending reset link would set the password again, so it stops working too
+	user.PasswordHash = string(hashedPassword)
+	user.PasswordResetHash = ""
+	user.PasswordResetExpiresAt = nil
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "The account was changed meanwhile, try again", http.StatusConflict)
+			return
+		}
+		h.log.Error("failed to save new password", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	// Log out every session, then start a new one for this client so only it stays logged in
+	if err := revokeAllSessions(r.Context(), h.cosmosService, h.revocations, user.ID); err != nil {
+		h.log.Error("failed to revoke sessions after password change", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		http.Error(w, "Password was changed, but failed to log out other sessions", http.StatusInternalServerError)
+		return
+	}
+	claims, _ := middleware.GetClaims(r.Context())
+	tokens, err := h.issueTokens(r, user, uuid.New().String(), claims != nil && claims.MFA)
+	if err != nil {
+		h.log.Error("failed to issue tokens", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		h.clearAuthCookies(w)
+		http.Error(w, "Password was changed, please log in again", http.StatusInternalServerError)
+		return
+	}
+	h.setAuthCookies(w, tokens)
+
+	h.log.Info("password changed", slog.String("userId", user.ID))
+
+	response := h.loginResponse(user, tokens)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", user.ID), slog.String("error", err.Error()))
+	}
+}
+
+// checkPassword answers with the broken rule and returns false if the new password does not follow the policy
+func (h *AuthHandler) checkPassword(w http.ResponseWriter, newPassword string) bool {
+	err := h.config.PasswordPolicy.Check(newPassword)
+	if err == nil {
+		return true
+	}
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		h.log.Error("failed to check password policy", slog.String("error", err.Error()))
+		http.Error(w, "Failed to check password", http.StatusInternalServerError)
+		return false
+	}
+	h.log.Debug("password rejected by policy", slog.String("reason", policyErr.Err.Error()))
+	writeError(w, http.StatusBadRequest, ErrCodeWeakPassword, policyErr.Message)
+	return false
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+// maxUserAgentLength is how much of the user agent is stored with a session
+const maxUserAgentLength = 512
+
+// revokeAllSessions invalidates every access and refresh token issued to the user so far and ends their sessions
+func revokeAllSessions(ctx context.Context, cosmosService *cosmos.Service, revocations middleware.RevocationStore, userID string) error {
+	if _, err := revocations.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := cosmosService.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+	_, err := cosmosService.DeleteUserSessions(ctx, userID)
+	return err
+}
+
+// SessionsHandler handles requests to list and end the current user's sessions
+type SessionsHandler struct {
+	cosmosService *cosmos.Service
+	log           *slog.Logger
+}
+
+// NewSessionsHandler creates a new SessionsHandler
+func NewSessionsHandler(cosmosService *cosmos.Service, log *slog.Logger) *SessionsHandler {
+	const module = "sessionsHandler"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &SessionsHandler{
+		cosmosService: cosmosService,
+		log:           log,
+	}
+}
+
+// SessionResponse represents a session, a login on one device
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	MFA        bool      `json:"mfa"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"` // The session of this request
+}
+
+// GetSessions handles GET requests to list the current user's sessions
+// @Summary List sessions
+// @Description List the devices the authenticated user is logged in on, most recently seen first
+// @Tags users
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/sessions [get]
+func (h *SessionsHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.cosmosService.GetUserSessions(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to get sessions", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve sessions", http.StatusInternalServerError)
+		return
+	}
+
+	currentID := currentSessionID(r)
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, toSessionResponse(&session, currentID))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// DeleteSession handles DELETE requests to sign a session out remotely
+// @Summary End session
+// @Description Sign out one of the authenticated user's devices. Its access and refresh tokens stop working right away
+// @Tags users
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Session Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/users/me/sessions/{id} [delete]
+func (h *SessionsHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	// Sessions are read within the user's partition, so other users' sessions are not found
+	sessionId := r.PathValue("id")
+	session, err := h.cosmosService.GetSession(r.Context(), userId, sessionId)
+	if err != nil {
+		h.log.Error("failed to get session", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to end session", http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	// Revoke the refresh tokens first, so the device cannot start the session again
+	if _, err := h.cosmosService.RevokeRefreshTokenFamily(r.Context(), sessionId); err != nil {
+		h.log.Error("failed to revoke session refresh tokens", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to end session", http.StatusInternalServerError)
+		return
+	}
+	if err := h.cosmosService.DeleteSession(r.Context(), userId, sessionId); err != nil && !errors.Is(err, cosmos.ErrSessionNotFound) {
+		h.log.Error("failed to delete session", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to end session", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("session ended remotely", slog.String("userId", userId), slog.String("device", session.Device))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]string{
+		"message":   "Session ended successfully",
+		"sessionId": sessionId,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("userId", userId), slog.String("error", err.Error()))
+	}
+}
+
+// currentSessionID returns the session of the request's access token, or "" for API keys and older tokens
+func currentSessionID(r *http.Request) string {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		return ""
+	}
+	return claims.SessionID
+}
+
+// toSessionResponse converts a session to its response
+func toSessionResponse(session *models.Session, currentID string) SessionResponse {
+	return SessionResponse{
+		ID:         session.ID,
+		Device:     session.Device,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		MFA:        session.MFA,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		Current:    currentID != "" && session.ID == currentID,
+	}
+}
+
+// deviceName describes the browser and operating system of a user agent, such as "Firefox on Windows".
+// Clients that are not browsers are named by their product token, such as "curl".
+func deviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := ""
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	system := ""
+	for _, o := range []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"CrOS", "ChromeOS"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, o.token) {
+			system = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	product, _, _ := strings.Cut(userAgent, "/")
+	return strings.TrimSpace(product)
+}
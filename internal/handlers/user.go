@@ -34,11 +34,19 @@ type UserInfoResponse struct {
 	Name     string `json:"name"`
 	UserName string `json:"username"`
 	Role     string `json:"role"`
+	Locale   string `json:"locale,omitempty"`
+	// PendingEmail is the new address of an email change that is waiting for confirmation
+	PendingEmail string `json:"pendingEmail,omitempty"`
+	// EmailVerified is false until the user opened the link from the verification email
+	EmailVerified bool `json:"emailVerified"`
+	MFAEnabled    bool `json:"mfaEnabled"`
+	// UnreadNotifications is only filled in by GET /api/users/me
+	UnreadNotifications int `json:"unreadNotifications"`
 }
 
 // GetUserInfo handles GET requests to retrieve the current user's information
 // @Summary Get current user information
-// @Description Get the email, name, and username of the authenticated user
+// @Description Get the email, name, username and unread notification count of the authenticated user
 // @Tags users
 // @Security Bearer
 // @Produce json
@@ -69,13 +77,26 @@ func (h *UserHandler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Count unread notifications for the inbox badge
+	unread, err := h.cosmosService.CountUnreadNotifications(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to count unread notifications", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve user information", http.StatusInternalServerError)
+		return
+	}
+
 	// Build response
 	response := UserInfoResponse{
-		ID:       user.ID,
-		Email:    user.Email,
-		Name:     user.Name,
-		UserName: user.UserName,
-		Role:     user.Role,
+		ID:                  user.ID,
+		Email:               user.Email,
+		Name:                user.Name,
+		UserName:            user.UserName,
+		Role:                user.Role,
+		Locale:              user.Locale,
+		PendingEmail:        user.PendingEmail,
+		EmailVerified:       user.EmailVerified(),
+		MFAEnabled:          user.MFAEnabled(),
+		UnreadNotifications: unread,
 	}
 
 	// Write response
@@ -90,11 +111,12 @@ func (h *UserHandler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 type UpdateUserProfileRequest struct {
 	Name     string `json:"name,omitempty"`
 	UserName string `json:"username,omitempty"`
+	Locale   string `json:"locale,omitempty"`
 }
 
 // UpdateUserProfile handles PUT requests to update the current user's profile
 // @Summary Update current user's profile
-// @Description Update the name and/or username of the authenticated user
+// @Description Update the name, username and/or email language of the authenticated user
 // @Tags users
 // @Security Bearer
 // @Produce json
@@ -123,9 +145,9 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Validate at least one field is provided
-	if req.Name == "" && req.UserName == "" {
+	if req.Name == "" && req.UserName == "" && req.Locale == "" {
 		h.log.Debug("update profile request with no fields", slog.String("userId", userId))
-		http.Error(w, "At least one field (name or username) must be provided", http.StatusBadRequest)
+		http.Error(w, "At least one field (name, username or locale) must be provided", http.StatusBadRequest)
 		return
 	}
 
@@ -137,6 +159,9 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 	if req.UserName != "" {
 		updates["username"] = req.UserName
 	}
+	if req.Locale != "" {
+		updates["locale"] = req.Locale
+	}
 
 	// Update user in database
 	user, err := h.cosmosService.UpdateUser(r.Context(), userId, updates)
@@ -158,11 +183,15 @@ func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 
 	// Build response
 	response := UserInfoResponse{
-		ID:       user.ID,
-		Email:    user.Email,
-		Name:     user.Name,
-		UserName: user.UserName,
-		Role:     user.Role,
+		ID:            user.ID,
+		Email:         user.Email,
+		Name:          user.Name,
+		UserName:      user.UserName,
+		Role:          user.Role,
+		Locale:        user.Locale,
+		PendingEmail:  user.PendingEmail,
+		EmailVerified: user.EmailVerified(),
+		MFAEnabled:    user.MFAEnabled(),
 	}
 
 	h.log.Info("user profile updated successfully", slog.String("userId", userId))
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+// verificationResendInterval is the minimum time between two verification emails to the same user
+const verificationResendInterval = time.Minute
+
+// VerifyEmailRequest represents the verify email request body
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail marks the user's email address as verified with the token from the verification link
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := middleware.ParsePurposeToken(req.Token, h.keys, middleware.PurposeEmailVerification)
+	if err != nil {
+		h.log.Debug("invalid email verification token presented", slog.String("error", err.Error()))
+		http.Error(w, "Invalid or expired verification token", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.cosmosService.GetUserByID(r.Context(), claims.Subject)
+	if err != nil {
+		h.log.Error("failed to get user for email verification", slog.String("userId", claims.Subject), slog.String("error", err.Error()))
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+	// A link sent to a previous address must not verify the current one
+	if user == nil || user.Email != claims.Email {
+		http.Error(w, "Invalid or expired verification token", http.StatusBadRequest)
+		return
+	}
+
+	if !user.EmailVerified() {
+		markEmailVerified(user, time.Now().UTC())
+		if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+			// The pending role must not be lost to a concurrent change, so the link is opened again
+			if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+				http.Error(w, "The account was changed meanwhile, try again", http.StatusConflict)
+				return
+			}
+			h.log.Error("failed to mark email verified", slog.String("userId", user.ID), slog.String("error", err.Error()))
+			http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+			return
+		}
+		h.log.Info("email verified", slog.String("userId", user.ID), slog.String("role", user.Role))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(`{"message":"Email verified"}`)); err != nil {
+		h.log.Warn("Failed to write")
+	}
+}
+
+// markEmailVerified marks the user's address as verified and grants the role of its domain.
+// Tokens issued before carry the old role until they are refreshed.
+func markEmailVerified(user *models.User, now time.Time) {
+	user.Unverified = false
+	user.EmailVerifiedAt = &now
+	if user.PendingRole != "" {
+		user.Role = user.PendingRole
+		user.PendingRole = ""
+	}
+}
+
+// ResendVerification emails the current user a new verification link
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// Get userId from context (set by auth middleware)
+	userId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.cosmosService.GetUserByID(r.Context(), userId)
+	if err != nil {
+		h.log.Error("failed to get user", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.EmailVerified() {
+		http.Error(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+	if user.VerificationSentAt != nil && time.Since(*user.VerificationSentAt) < verificationResendInterval {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Verification email was sent recently, please wait a minute", http.StatusTooManyRequests)
+		return
+	}
+
+	now := time.Now().UTC()
+	user.VerificationSentAt = &now
+	if err := h.cosmosService.ReplaceUser(r.Context(), user); err != nil {
+		if errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			http.Error(w, "Verification email was sent recently, please wait a minute", http.StatusTooManyRequests)
+			return
+		}
+		h.log.Error("failed to save verification send time", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.sendVerificationEmail(r, user); err != nil {
+		h.log.Error("failed to send verification email", slog.String("userId", userId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if _, err := w.Write([]byte(`{"message":"Verification email sent"}`)); err != nil {
+		h.log.Warn("Failed to write")
+	}
+}
+
+// sendVerificationEmail emails the user a signed verification link for their current address
+func (h *AuthHandler) sendVerificationEmail(r *http.Request, user *models.User) error {
+	token, err := middleware.GeneratePurposeToken(h.keys, middleware.PurposeEmailVerification, user.ID, user.Email, h.config.VerificationTTL)
+	if err != nil {
+		return err
+	}
+	return h.notifier.SendEmailVerification(r.Context(), user, token, h.config.VerificationTTL)
+}
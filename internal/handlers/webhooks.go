@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/middleware"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/webhook"
+)
+
+const (
+	// minWebhookSecretLength is the shortest secret admins may choose themselves
+	minWebhookSecretLength = 16
+	// webhookDeliveriesLimit is how many recent deliveries are listed per subscription
+	webhookDeliveriesLimit = 50
+)
+
+// WebhooksHandler handles admin requests to manage outgoing webhook subscriptions
+type WebhooksHandler struct {
+	cosmosService *cosmos.Service
+	webhooks      *webhook.Service
+	log           *slog.Logger
+}
+
+// NewWebhooksHandler creates a new WebhooksHandler
+func NewWebhooksHandler(cosmosService *cosmos.Service, webhooks *webhook.Service, log *slog.Logger) *WebhooksHandler {
+	const module = "webhooksHandler"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &WebhooksHandler{
+		cosmosService: cosmosService,
+		webhooks:      webhooks,
+		log:           log,
+	}
+}
+
+// WebhookRequest represents the create and update webhook subscription request body
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"` // Empty subscribes to all events
+	Secret string   `json:"secret,omitempty"` // Generated when creating without one; set on update to rotate it
+	Active *bool    `json:"active,omitempty"` // Defaults to true when creating
+}
+
+// WebhookResponse represents a webhook subscription. The secret is only returned when it was created or rotated.
+type WebhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CreateWebhook handles POST requests to register a webhook subscription
+// @Summary Create webhook subscription (admin)
+// @Description Register a URL to receive complaint events, signed with HMAC-SHA256 in the X-Webhook-Signature header
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body WebhookRequest true "Webhook subscription"
+// @Success 201 {object} WebhookResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/webhooks [post]
+func (h *WebhooksHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get adminId from context (set by auth middleware)
+	adminId, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		h.log.Error("failed to get userId from context", slog.String("path", r.URL.Path))
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to parse webhook request", slog.String("adminId", adminId), slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateWebhookRequest(&req); err != nil {
+		h.log.Debug("invalid webhook request", slog.String("adminId", adminId), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	subscription := &models.WebhookSubscription{
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    req.Secret,
+		Active:    req.Active == nil || *req.Active,
+		CreatedBy: adminId,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = webhook.NewSecret()
+	}
+
+	if err := h.cosmosService.CreateWebhookSubscription(r.Context(), subscription); err != nil {
+		h.log.Error("failed to create webhook subscription", slog.String("adminId", adminId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("webhook subscription created", slog.String("adminId", adminId), slog.String("webhookId", subscription.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(toWebhookResponse(subscription, true)); err != nil {
+		h.log.Error("failed to encode response", slog.String("adminId", adminId), slog.String("error", err.Error()))
+	}
+}
+
+// GetWebhooks handles GET requests to list webhook subscriptions
+// @Summary List webhook subscriptions (admin)
+// @Description List all webhook subscriptions without their secrets
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} WebhookResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/webhooks [get]
+func (h *WebhooksHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.cosmosService.GetWebhookSubscriptions(r.Context())
+	if err != nil {
+		h.log.Error("failed to get webhook subscriptions", slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]WebhookResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		response = append(response, toWebhookResponse(&subscriptions[i], false))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("error", err.Error()))
+	}
+}
+
+// GetWebhook handles GET requests to retrieve a webhook subscription
+// @Summary Get webhook subscription (admin)
+// @Description Get a webhook subscription without its secret
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} WebhookResponse
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Webhook Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/webhooks/{id} [get]
+func (h *WebhooksHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId := r.PathValue("id")
+
+	subscription, err := h.cosmosService.GetWebhookSubscription(r.Context(), webhookId)
+	if err != nil {
+		h.log.Error("failed to get webhook subscription", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve webhook", http.StatusInternalServerError)
+		return
+	}
+	if subscription == nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toWebhookResponse(subscription, false)); err != nil {
+		h.log.Error("failed to encode response", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+	}
+}
+
+// UpdateWebhook handles PUT requests to update a webhook subscription
+// @Summary Update webhook subscription (admin)
+// @Description Replace the URL and event filters, enable or disable the subscription, or rotate its secret
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param request body WebhookRequest true "Webhook subscription"
+// @Success 200 {object} WebhookResponse
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Webhook Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/webhooks/{id} [put]
+func (h *WebhooksHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId := r.PathValue("id")
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Debug("failed to parse webhook request", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateWebhookRequest(&req); err != nil {
+		h.log.Debug("invalid webhook request", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.cosmosService.GetWebhookSubscription(r.Context(), webhookId)
+	if err != nil {
+		h.log.Error("failed to get webhook subscription", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve webhook", http.StatusInternalServerError)
+		return
+	}
+	if subscription == nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	subscription.URL = req.URL
+	subscription.Events = req.Events
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	rotated := req.Secret != ""
+	if rotated {
+		subscription.Secret = req.Secret
+	}
+
+	if err := h.cosmosService.UpdateWebhookSubscription(r.Context(), subscription); err != nil {
+		if errors.Is(err, cosmos.ErrWebhookNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error("failed to update webhook subscription", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toWebhookResponse(subscription, rotated)); err != nil {
+		h.log.Error("failed to encode response", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+	}
+}
+
+// DeleteWebhook handles DELETE requests to remove a webhook subscription
+// @Summary Delete webhook subscription (admin)
+// @Description Delete a webhook subscription. Pending deliveries are not attempted any more
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Webhook Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/webhooks/{id} [delete]
+func (h *WebhooksHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId := r.PathValue("id")
+
+	if err := h.cosmosService.DeleteWebhookSubscription(r.Context(), webhookId); err != nil {
+		if errors.Is(err, cosmos.ErrWebhookNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error("failed to delete webhook subscription", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]string{
+		"message":   "Webhook deleted successfully",
+		"webhookId": webhookId,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.Error("failed to encode response", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+	}
+}
+
+// GetWebhookDeliveries handles GET requests to list a subscription's recent deliveries
+// @Summary List webhook deliveries (admin)
+// @Description List the most recent deliveries of a webhook subscription with their attempts, newest first
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/webhooks/{id}/deliveries [get]
+func (h *WebhooksHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookId := r.PathValue("id")
+
+	deliveries, err := h.cosmosService.GetWebhookDeliveries(r.Context(), webhookId, webhookDeliveriesLimit)
+	if err != nil {
+		h.log.Error("failed to get webhook deliveries", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+		http.Error(w, "Failed to retrieve deliveries", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(deliveries); err != nil {
+		h.log.Error("failed to encode response", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+	}
+}
+
+// RedeliverWebhook handles POST requests to send a delivery again
+// @Summary Redeliver webhook (admin)
+// @Description Send a delivery again right away and return it with the new attempt
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict - Delivery is being sent"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/admin/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *WebhooksHandler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId := r.PathValue("id")
+	deliveryId := r.PathValue("deliveryId")
+
+	delivery, err := h.webhooks.Redeliver(r.Context(), webhookId, deliveryId)
+	if err != nil {
+		switch {
+		case errors.Is(err, cosmos.ErrWebhookNotFound):
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+		case errors.Is(err, cosmos.ErrDeliveryNotFound):
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+		case errors.Is(err, cosmos.ErrConcurrentUpdate):
+			http.Error(w, "Delivery is being sent, try again shortly", http.StatusConflict)
+		default:
+			h.log.Error("failed to redeliver webhook", slog.String("webhookId", webhookId), slog.String("deliveryId", deliveryId), slog.String("error", err.Error()))
+			http.Error(w, "Failed to redeliver webhook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.log.Info("webhook redelivered", slog.String("webhookId", webhookId), slog.String("deliveryId", deliveryId), slog.String("status", delivery.Status))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(delivery); err != nil {
+		h.log.Error("failed to encode response", slog.String("webhookId", webhookId), slog.String("error", err.Error()))
+	}
+}
+
+// validateWebhookRequest checks the URL, event filters and secret of a webhook request
+func validateWebhookRequest(req *WebhookRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(models.WebhookEventTypes, event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	if req.Secret != "" && len(req.Secret) < minWebhookSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", minWebhookSecretLength)
+	}
+	return nil
+}
+
+// toWebhookResponse converts a subscription to its response, including the secret only when asked
+func toWebhookResponse(subscription *models.WebhookSubscription, includeSecret bool) WebhookResponse {
+	response := WebhookResponse{
+		ID:        subscription.ID,
+		URL:       subscription.URL,
+		Events:    subscription.Events,
+		Active:    subscription.Active,
+		CreatedBy: subscription.CreatedBy,
+		CreatedAt: subscription.CreatedAt,
+		UpdatedAt: subscription.UpdatedAt,
+	}
+	if response.Events == nil {
+		response.Events = []string{}
+	}
+	if includeSecret {
+		response.Secret = subscription.Secret
+	}
+	return response
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWebhookRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     WebhookRequest
+		wantErr bool
+	}{
+		{name: "all events", req: WebhookRequest{URL: "https://tickets.example.edu/hooks/portal"}},
+		{name: "filtered events", req: WebhookRequest{URL: "http://tickets.internal/hooks", Events: []string{models.WebhookEventComplaintCreated, models.WebhookEventComplaintStatusChanged}}},
+		{name: "custom secret", req: WebhookRequest{URL: "https://tickets.example.edu/hooks", Secret: "0123456789abcdef"}},
+		{name: "missing url", req: WebhookRequest{}, wantErr: true},
+		{name: "relative url", req: WebhookRequest{URL: "/hooks"}, wantErr: true},
+		{name: "unsupported scheme", req: WebhookRequest{URL: "ftp://tickets.example.edu/hooks"}, wantErr: true},
+		{name: "unknown event", req: WebhookRequest{URL: "https://tickets.example.edu/hooks", Events: []string{"user.created"}}, wantErr: true},
+		{name: "short secret", req: WebhookRequest{URL: "https://tickets.example.edu/hooks", Secret: "short"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebhookRequest(&tt.req)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestToWebhookResponse(t *testing.T) {
+	subscription := &models.WebhookSubscription{ID: "hook-1", URL: "https://tickets.example.edu/hooks", Secret: "whsec_abc", Active: true}
+
+	assert.Empty(t, toWebhookResponse(subscription, false).Secret)
+	assert.Equal(t, "whsec_abc", toWebhookResponse(subscription, true).Secret)
+	assert.NotNil(t, toWebhookResponse(subscription, false).Events, "events should encode as an empty list")
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// APIKeyHeader carries a personal API key instead of an access token
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to recognize in logs and secret scanners
+const apiKeyPrefix = "scp_"
+
+// apiKeyTouchInterval limits how often the last use of a key is written
+const apiKeyTouchInterval = time.Minute
+
+// API key scopes. A key only reaches the routes of its scopes, and the routes still check the user's role.
+const (
+	ScopeComplaintsRead  = "complaints:read"  // Read own complaints and approved complaints
+	ScopeComplaintsWrite = "complaints:write" // Create, like and delete complaints
+	ScopeProfileRead     = "profile:read"     // Read the profile and notifications
+	ScopeAdminRead       = "admin:read"       // Read admin lists, for users whose role has the permission
+)
+
+// scopeRoutes lists the routes each scope reaches as "METHOD /path" patterns, where {name} matches one path segment.
+// Routes are listed one by one, so new routes, such as moderation or data export, need a scope that names them.
+var scopeRoutes = map[string][]string{
+	ScopeComplaintsRead: {
+		"GET /api/complaints",
+		"GET /api/complaints/approved",
+	},
+	ScopeComplaintsWrite: {
+		"POST /api/complaints",
+		"DELETE /api/complaints/{id}",
+		"POST /api/complaints/{id}/like",
+		"DELETE /api/complaints/{id}/like",
+	},
+	ScopeProfileRead: {
+		"GET /api/users/me",
+		"GET /api/users/me/notification-preferences",
+		"GET /api/notifications",
+	},
+	ScopeAdminRead: {
+		"GET /api/admin/complaints",
+		"GET /api/admin/users",
+		"GET /api/admin/users/{id}",
+		"GET /api/admin/users/{id}/complaints",
+		"GET /api/admin/audit-log",
+		"GET /api/admin/email-denylist",
+		"GET /api/admin/webhooks",
+		"GET /api/admin/webhooks/{id}",
+		"GET /api/admin/webhooks/{id}/deliveries",
+	},
+}
+
+// APIKeyStore looks up personal API keys for RequireAuth
+type APIKeyStore interface {
+	// GetAPIKey returns the key with the given hash, or nil
+	GetAPIKey(ctx context.Context, hash string) (*models.APIKey, error)
+	// TouchAPIKey stores the last use of the key
+	TouchAPIKey(ctx context.Context, key *models.APIKey) error
+}
+
+// GenerateAPIKey returns a new API key, the hash to store for it and the prefix to show to its owner
+func GenerateAPIKey() (key, hash, prefix string) {
+	token, _ := GenerateToken()
+	key = apiKeyPrefix + token
+	return key, HashToken(key), key[:len(apiKeyPrefix)+8]
+}
+
+// ValidScope reports whether scope is one of the API key scopes
+func ValidScope(scope string) bool {
+	_, ok := scopeRoutes[scope]
+	return ok
+}
+
+// scopesAllow reports whether an API key with the scopes may make the request. Routes outside every scope,
+// such as the auth and API key endpoints, cannot be reached with an API key.
+func scopesAllow(scopes []string, r *http.Request) bool {
+	for _, scope := range scopes {
+		for _, route := range scopeRoutes[scope] {
+			if routeMatches(route, r.Method, r.URL.Path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// routeMatches reports whether the method and path match a "METHOD /path" pattern from scopeRoutes
+func routeMatches(route, method, path string) bool {
+	routeMethod, routePath, _ := strings.Cut(route, " ")
+	if method != routeMethod {
+		return false
+	}
+	want := strings.Split(routePath, "/")
+	got := strings.Split(path, "/")
+	if len(want) != len(got) {
+		return false
+	}
+	for i, segment := range want {
+		if strings.HasPrefix(segment, "{") {
+			if got[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// touchAPIKey records the use of the key, at most once per apiKeyTouchInterval
+func touchAPIKey(ctx context.Context, store APIKeyStore, key *models.APIKey, ip string, now time.Time) error {
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyTouchInterval && key.LastUsedIP == ip {
+		return nil
+	}
+	key.LastUsedAt = &now
+	key.LastUsedIP = ip
+	return store.TouchAPIKey(ctx, key)
+}
+
+// authenticateAPIKey validates the API key of the request and checks that its scopes cover the request. The
+// claims carry only the user; RequireAuth fills in the role from the user document. It writes the error
+// response and returns false when the key is not accepted.
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, opts AuthOptions, log *slog.Logger) (*Claims, bool) {
+	if opts.APIKeys == nil || opts.Users == nil {
+		http.Error(w, "API keys are not accepted", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	now := time.Now().UTC()
+	key, err := opts.APIKeys.GetAPIKey(r.Context(), HashToken(r.Header.Get(APIKeyHeader)))
+	if err != nil {
+		log.Error("failed to get API key", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+		http.Error(w, "Failed to validate API key", http.StatusInternalServerError)
+		return nil, false
+	}
+	// Cosmos DB deletes expired keys in the background, so they may still be found for a while
+	if key == nil || key.Expired(now) {
+		log.Debug("invalid API key presented", slog.String("path", r.URL.Path))
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if !scopesAllow(key.Scopes, r) {
+		log.Debug("API key scopes do not cover the request", slog.String("path", r.URL.Path), slog.String("userId", key.UserID), slog.String("prefix", key.Prefix))
+		http.Error(w, "Forbidden: the API key's scopes do not allow this request", http.StatusForbidden)
+		return nil, false
+	}
+
+	if err := touchAPIKey(r.Context(), opts.APIKeys, key, ClientIP(r), now); err != nil {
+		log.Warn("failed to record API key use", slog.String("userId", key.UserID), slog.String("prefix", key.Prefix), slog.String("error", err.Error()))
+	}
+
+	return &Claims{UserID: key.UserID, APIKeyID: key.ID, Scopes: key.Scopes}, true
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPIKeyStore is an APIKeyStore backed by a map of key hashes
+type fakeAPIKeyStore struct {
+	keys    map[string]*models.APIKey
+	touched int
+}
+
+func (f *fakeAPIKeyStore) GetAPIKey(_ context.Context, hash string) (*models.APIKey, error) {
+	return f.keys[hash], nil
+}
+
+func (f *fakeAPIKeyStore) TouchAPIKey(_ context.Context, key *models.APIKey) error {
+	f.touched++
+	return nil
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, hash, prefix := GenerateAPIKey()
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+	assert.True(t, strings.HasPrefix(key, prefix))
+	assert.Equal(t, HashToken(key), hash)
+
+	other, _, _ := GenerateAPIKey()
+	assert.NotEqual(t, key, other)
+}
+
+func TestScopesAllow(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		method string
+		path   string
+		want   bool
+	}{
+		{name: "read complaints", scopes: []string{ScopeComplaintsRead}, method: http.MethodGet, path: "/api/complaints/approved", want: true},
+		{name: "read scope cannot write", scopes: []string{ScopeComplaintsRead}, method: http.MethodPost, path: "/api/complaints", want: false},
+		{name: "write complaints", scopes: []string{ScopeComplaintsWrite}, method: http.MethodPost, path: "/api/complaints/c-1/like", want: true},
+		{name: "write cannot moderate", scopes: []string{ScopeComplaintsWrite}, method: http.MethodPut, path: "/api/complaints/c-1", want: false},
+		{name: "path must match the route", scopes: []string{ScopeComplaintsRead}, method: http.MethodGet, path: "/api/complaintsx", want: false},
+		{name: "parameter needs a value", scopes: []string{ScopeComplaintsWrite}, method: http.MethodDelete, path: "/api/complaints/", want: false},
+		{name: "profile", scopes: []string{ScopeProfileRead}, method: http.MethodGet, path: "/api/users/me", want: true},
+		{name: "profile cannot update", scopes: []string{ScopeProfileRead}, method: http.MethodPut, path: "/api/users/me", want: false},
+		{name: "profile cannot export", scopes: []string{ScopeProfileRead}, method: http.MethodGet, path: "/api/users/me/export", want: false},
+		{name: "admin user", scopes: []string{ScopeAdminRead}, method: http.MethodGet, path: "/api/admin/users/u-1", want: true},
+		{name: "admin lists", scopes: []string{ScopeAdminRead}, method: http.MethodGet, path: "/api/admin/users", want: true},
+		{name: "admin read cannot manage", scopes: []string{ScopeAdminRead}, method: http.MethodPost, path: "/api/admin/users/u-1/suspend", want: false},
+		{name: "other scope", scopes: []string{ScopeComplaintsRead}, method: http.MethodGet, path: "/api/admin/complaints", want: false},
+		{name: "auth routes", scopes: []string{ScopeComplaintsWrite, ScopeProfileRead}, method: http.MethodPost, path: "/api/auth/logout-all", want: false},
+		{name: "no new keys", scopes: []string{ScopeProfileRead}, method: http.MethodPost, path: "/api/users/me/api-keys", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			assert.Equal(t, tt.want, scopesAllow(tt.scopes, req))
+		})
+	}
+}
+
+func TestRequireAuthAPIKey(t *testing.T) {
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	users := fakeUserStore{
+		"u-1": {ID: "u-1", Email: "staff@example.edu", Role: "staff", Categories: []string{"it"}},
+	}
+	validKey, validHash, _ := GenerateAPIKey()
+	expiredKey, expiredHash, _ := GenerateAPIKey()
+	store := &fakeAPIKeyStore{keys: map[string]*models.APIKey{
+		validHash:   {ID: validHash, UserID: "u-1", Scopes: []string{ScopeComplaintsRead}, ExpiresAt: now.Add(time.Hour)},
+		expiredHash: {ID: expiredHash, UserID: "u-1", Scopes: []string{ScopeComplaintsRead}, ExpiresAt: now.Add(-time.Hour)},
+	}}
+
+	var claims *Claims
+	handler := RequireAuth(AuthOptions{Keys: keys, Users: users, APIKeys: store}, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ = GetClaims(r.Context())
+	}))
+	serve := func(method, path, key string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/complaints", validKey))
+	require.NotNil(t, claims)
+	assert.Equal(t, "u-1", claims.UserID)
+	assert.Equal(t, validHash, claims.APIKeyID)
+	assert.Equal(t, "staff", claims.Role, "the role comes from the user document")
+	assert.Equal(t, []string{"it"}, claims.Categories)
+	assert.Equal(t, 1, store.touched)
+
+	serve(http.MethodGet, "/api/complaints", validKey)
+	assert.Equal(t, 1, store.touched, "uses within a minute from the same IP are not recorded again")
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/complaints", validKey))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/complaints", expiredKey))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/complaints", "scp_unknown"))
+
+	// Without a key store API keys are refused
+	handler = RequireAuth(AuthOptions{Keys: keys, Users: users}, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/complaints", validKey))
+}
@@ -8,7 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/authz"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Context keys for storing user information in request context
@@ -17,99 +20,141 @@ type contextKey string
 const (
 	userIDKey contextKey = "userId"
 	roleKey   contextKey = "role"
+	claimsKey contextKey = "claims"
+	userKey   contextKey = "user"
 )
 
 // Claims Custom claims structure for JWT
 type Claims struct {
-	UserID string `json:"userId"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID       string   `json:"userId"`
+	Email        string   `json:"email"`
+	Role         string   `json:"role"`
+	TokenVersion int      `json:"tokenVersion"`         // Must match the user's current token version
+	MFA          bool     `json:"mfa,omitempty"`        // The session was started with a second factor
+	Categories   []string `json:"categories,omitempty"` // Complaint categories of department staff
+	SessionID    string   `json:"sid,omitempty"`        // The session the token belongs to, see models.Session
+	APIKeyID     string   `json:"-"`                    // Set when the request was authenticated with an API key
+	Scopes       []string `json:"-"`                    // Scopes of the API key
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT creates a JWT token with the provided user information
-func GenerateJWT(userId, email, role, secret string) (string, error) {
-	// Create claims with user information and expiration time
-	claims := Claims{
-		UserID: userId,
-		Email:  email,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// AuthOptions configures RequireAuth
+type AuthOptions struct {
+	Keys *KeyRing
+	// Revocations rejects revoked tokens and tokens issued before a "log out of all devices". Optional.
+	Revocations RevocationStore
+	// Users rejects suspended and deleted users and puts the user document in the request context. Optional.
+	Users UserStore
+	// Sessions rejects tokens whose session has been removed and records session activity. Optional.
+	Sessions SessionStore
+	// APIKeys accepts personal API keys in the X-API-Key header. Requires Users. Optional.
+	APIKeys APIKeyStore
+}
+
+// GenerateJWT creates a JWT access token for the user described by claims that expires after ttl, signed with
+// the current key of the ring. Every token gets a unique ID (jti) so it can be revoked on its own.
+func GenerateJWT(keys *KeyRing, claims Claims, ttl time.Duration) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Subject:   claims.UserID,
+		Issuer:    keys.Issuer(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return keys.Sign(claims)
+}
 
-	// Sign token with secret
-	tokenString, err := token.SignedString([]byte(secret))
+// ParseJWT validates a JWT token against the key ring and returns its claims
+func ParseJWT(tokenString string, keys *KeyRing) (*Claims, error) {
+	token, err := keys.Parse(tokenString, &Claims{})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return tokenString, nil
+	// Tokens without a user ID, such as email verification tokens, are not access tokens
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || claims.UserID == "" {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
 }
 
-// RequireAuth middleware validates JWT token and adds user info to context
-func RequireAuth(secret string, log *slog.Logger) func(next http.Handler) http.Handler {
+// TokenFromRequest returns the access token from the auth cookie, falling back to the Authorization header
+func TokenFromRequest(r *http.Request) (string, error) {
+	cookie, err := r.Cookie("auth_token")
+	if err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", errors.New("authentication required")
+	}
+
+	// Extract token from "Bearer <token>" format
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", errors.New("invalid authorization header format")
+	}
+	return parts[1], nil
+}
+
+// RequireAuth middleware validates the JWT token, or the API key when one is sent, and adds user info to context
+func RequireAuth(opts AuthOptions, log *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			var tokenString string
-
-			// First, try to get token from cookie
-			cookie, err := r.Cookie("auth_token")
-			if err == nil && cookie.Value != "" {
-				tokenString = cookie.Value
-				log.Debug("token found in cookie", slog.String("path", r.URL.Path))
-			} else {
-				// Fall back to Authorization header
-				authHeader := r.Header.Get("Authorization")
-				if authHeader == "" {
-					log.Debug("no token found in cookie or authorization header", slog.String("path", r.URL.Path))
-					http.Error(w, "Authentication required", http.StatusUnauthorized)
+			authenticate := authenticateToken
+			if r.Header.Get(APIKeyHeader) != "" {
+				authenticate = authenticateAPIKey
+			}
+			claims, ok := authenticate(w, r, opts, log)
+			if !ok {
+				return
+			}
+
+			// The user document is read once per request; it also holds the token version and the verification
+			var user *models.User
+			if opts.Users != nil {
+				var err error
+				user, err = opts.Users.GetUserByID(r.Context(), claims.UserID)
+				if err != nil {
+					log.Error("failed to get user for token", slog.String("path", r.URL.Path), slog.String("userId", claims.UserID), slog.String("error", err.Error()))
+					http.Error(w, "Failed to validate token", http.StatusInternalServerError)
 					return
 				}
-
-				// Extract token from "Bearer <token>" format
-				parts := strings.Split(authHeader, " ")
-				if len(parts) != 2 || parts[0] != "Bearer" {
-					log.Debug("invalid authorization header format", slog.String("path", r.URL.Path))
-					http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+				if user == nil {
+					log.Debug("token of deleted user presented", slog.String("path", r.URL.Path), slog.String("userId", claims.UserID))
+					http.Error(w, "Token revoked", http.StatusUnauthorized)
 					return
 				}
-
-				tokenString = parts[1]
-				log.Debug("token found in authorization header", slog.String("path", r.URL.Path))
 			}
-
-			// Parse and validate token
-			token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-				// Verify signing method
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, errors.New("invalid signing method")
-				}
-				return []byte(secret), nil
-			})
-
-			if err != nil {
-				log.Debug("failed to parse JWT token", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+			if claims.APIKeyID == "" && !checkAccessToken(w, r, opts, claims, user, log) {
 				return
 			}
 
-			// Extract claims
-			claims, ok := token.Claims.(*Claims)
-			if !ok || !token.Valid {
-				log.Debug("invalid token claims", slog.String("path", r.URL.Path))
-				http.Error(w, "Invalid token claims", http.StatusUnauthorized)
-				return
+			// Reject suspended users, whose tokens may have been issued before the suspension
+			if user != nil {
+				if user.Suspended() {
+					log.Debug("suspended user presented a token", slog.String("path", r.URL.Path), slog.String("userId", claims.UserID))
+					WriteRestriction(w, ErrCodeAccountSuspended, "Your account has been suspended", user.Suspension)
+					return
+				}
+				// API keys carry no role, so they always act with the user's current one
+				if claims.APIKeyID != "" {
+					claims.Email = user.Email
+					claims.Role = user.Role
+					claims.Categories = user.Categories
+				}
 			}
 
-			// Add userId and role to request context
+			// Add userId, role and claims to request context
 			ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, roleKey, claims.Role)
+			ctx = context.WithValue(ctx, claimsKey, claims)
+			if user != nil {
+				ctx = context.WithValue(ctx, userKey, user)
+			}
 
 			// Call next handler with updated context
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -117,8 +162,50 @@ func RequireAuth(secret string, log *slog.Logger) func(next http.Handler) http.H
 	}
 }
 
-// RequireAdmin middleware checks if user has admin role
-func RequireAdmin(log *slog.Logger) func(next http.Handler) http.Handler {
+// authenticateToken validates the access token of the request. It writes the error response and returns false
+// when the token is missing or invalid; checkAccessToken checks revocation once the user is loaded.
+func authenticateToken(w http.ResponseWriter, r *http.Request, opts AuthOptions, log *slog.Logger) (*Claims, bool) {
+	tokenString, err := TokenFromRequest(r)
+	if err != nil {
+		log.Debug("no valid token in cookie or authorization header", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	// Parse and validate token
+	claims, err := ParseJWT(tokenString, opts.Keys)
+	if err != nil {
+		log.Debug("failed to parse JWT token", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+	return claims, true
+}
+
+// checkAccessToken rejects revoked access tokens and tokens of sessions that were signed out remotely.
+// The token version is taken from user when RequireAuth loaded it. It writes the error response and returns false
+// when the token may not be used.
+func checkAccessToken(w http.ResponseWriter, r *http.Request, opts AuthOptions, claims *Claims, user *models.User, log *slog.Logger) bool {
+	if opts.Revocations != nil {
+		if err := checkRevocation(r.Context(), opts.Revocations, claims, user); err != nil {
+			if errors.Is(err, ErrTokenRevoked) {
+				log.Debug("revoked token presented", slog.String("path", r.URL.Path), slog.String("userId", claims.UserID))
+				http.Error(w, "Token revoked", http.StatusUnauthorized)
+				return false
+			}
+			log.Error("failed to check token revocation", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+			http.Error(w, "Failed to validate token", http.StatusInternalServerError)
+			return false
+		}
+	}
+
+	// Reject tokens of sessions that were signed out remotely
+	return opts.Sessions == nil || checkSession(w, r, opts.Sessions, claims, log)
+}
+
+// RequirePermission middleware checks that the user's role has the permission. Permissions scoped to
+// complaint categories pass here; handlers check the category of the complaint with authz.Allows.
+func RequirePermission(permission authz.Permission, log *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get role from context
@@ -129,11 +216,10 @@ func RequireAdmin(log *slog.Logger) func(next http.Handler) http.Handler {
 				return
 			}
 
-			// Check if role is admin
-			if role != "admin" {
+			if !authz.Has(role, permission) {
 				userId, _ := GetUserID(r.Context())
-				log.Debug("non-admin user attempted admin action", slog.String("userId", userId), slog.String("role", role), slog.String("path", r.URL.Path))
-				http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
+				log.Debug("user without permission attempted a restricted action", slog.String("userId", userId), slog.String("role", role), slog.String("permission", string(permission)), slog.String("path", r.URL.Path))
+				http.Error(w, "Forbidden: missing permission "+string(permission), http.StatusForbidden)
 				return
 			}
 
@@ -143,6 +229,29 @@ func RequireAdmin(log *slog.Logger) func(next http.Handler) http.Handler {
 	}
 }
 
+// RequireMFA middleware rejects sessions that were not started with two-factor authentication.
+// Use it after RequireAuth.
+func RequireMFA(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetClaims(r.Context())
+			if !ok {
+				log.Debug("claims not found in context", slog.String("path", r.URL.Path))
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.MFA {
+				log.Debug("session without two-factor authentication attempted a restricted action", slog.String("userId", claims.UserID), slog.String("path", r.URL.Path))
+				http.Error(w, "Forbidden: two-factor authentication required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // Helper functions to extract user info from context
 
 // GetUserID extracts the user ID from the request context
@@ -151,9 +260,27 @@ func GetUserID(ctx context.Context) (string, bool) {
 	return userId, ok
 }
 
+// GetCategories extracts the complaint categories of department staff from the request context
+func GetCategories(ctx context.Context) []string {
+	if claims, ok := GetClaims(ctx); ok {
+		return claims.Categories
+	}
+	return nil
+}
+
+// GetClaims extracts the validated token claims from the request context
+func GetClaims(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	return claims, ok
+}
+
+// GetUser extracts the user document loaded by RequireAuth when AuthOptions.Users is set
+func GetUser(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userKey).(*models.User)
+	return user, ok
+}
+
 // GetRole extracts the role from the request context
-// Currently unused, but will be needed for complaint handlers to implement role-based logic
-// (e.g., students can only view their own complaints, admins can view all)
 func GetRole(ctx context.Context) (string, bool) {
 	role, ok := ctx.Value(roleKey).(string)
 	return role, ok
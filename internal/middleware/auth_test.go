@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireAuthTokenExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		wantStatus int
+	}{
+		{name: "valid token", ttl: time.Minute, wantStatus: http.StatusOK},
+		{name: "expired token", ttl: -time.Minute, wantStatus: http.StatusUnauthorized},
+	}
+
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RequireAuth(AuthOptions{Keys: keys}, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId, _ := GetUserID(r.Context())
+		assert.Equal(t, "user-1", userId)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := GenerateJWT(keys, Claims{UserID: "user-1", Email: "student@example.edu", Role: "student"}, tt.ttl)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestRequireAuthRevocation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	store := NewMemoryRevocationStore()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RequireAuth(AuthOptions{Keys: keys, Revocations: store}, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	first, err := GenerateJWT(keys, Claims{UserID: "user-1", Email: "student@example.edu", Role: "student"}, time.Minute)
+	require.NoError(t, err)
+	second, err := GenerateJWT(keys, Claims{UserID: "user-1", Email: "student@example.edu", Role: "student"}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(first))
+
+	// Revoking one token leaves the others valid
+	claims, err := ParseJWT(first, keys)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.ID)
+	require.NoError(t, RevokeClaims(ctx, store, claims))
+	assert.Equal(t, http.StatusUnauthorized, serve(first))
+	assert.Equal(t, http.StatusOK, serve(second))
+
+	// Logging out of all devices rejects every token with an older version
+	version, err := store.RevokeAll(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, serve(second))
+
+	third, err := GenerateJWT(keys, Claims{UserID: "user-1", Email: "student@example.edu", Role: "student", TokenVersion: version}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(third))
+}
+
+// fakeUserStore is a UserStore backed by a map
+type fakeUserStore map[string]*models.User
+
+func (f fakeUserStore) GetUserByID(_ context.Context, id string) (*models.User, error) {
+	return f[id], nil
+}
+
+func TestRequireAuthSuspendedUser(t *testing.T) {
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tomorrow, yesterday := now.Add(24*time.Hour), now.Add(-24*time.Hour)
+	users := fakeUserStore{
+		"active":    {ID: "active"},
+		"suspended": {ID: "suspended", Suspension: &models.Restriction{Reason: "Spam", CreatedBy: "admin-1", CreatedAt: now, ExpiresAt: &tomorrow}},
+		"expired":   {ID: "expired", Suspension: &models.Restriction{Reason: "Spam", CreatedBy: "admin-1", CreatedAt: now, ExpiresAt: &yesterday}},
+	}
+	var loaded *models.User
+	handler := RequireAuth(AuthOptions{Keys: keys, Users: users}, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loaded, _ = GetUser(r.Context())
+	}))
+
+	serve := func(userID string) *httptest.ResponseRecorder {
+		token, err := GenerateJWT(keys, Claims{UserID: userID, Email: userID + "@example.edu", Role: "student"}, time.Minute)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("active")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, loaded)
+	assert.Equal(t, "active", loaded.ID)
+
+	rec = serve("suspended")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var body RestrictionResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, ErrCodeAccountSuspended, body.Code)
+	assert.Equal(t, "Spam", body.Reason)
+	require.NotNil(t, body.Until)
+	assert.WithinDuration(t, tomorrow, *body.Until, time.Second)
+
+	assert.Equal(t, http.StatusOK, serve("expired").Code, "expired suspensions no longer apply")
+
+	assert.Equal(t, http.StatusUnauthorized, serve("deleted").Code)
+}
+
+// countingUserStore is a UserStore that counts its reads
+type countingUserStore struct {
+	users fakeUserStore
+	reads int
+}
+
+func (c *countingUserStore) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	c.reads++
+	return c.users.GetUserByID(ctx, id)
+}
+
+func TestRequireAuthReadsUserOnce(t *testing.T) {
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	users := &countingUserStore{users: fakeUserStore{"user-1": {ID: "user-1", TokenVersion: 2}}}
+	// The memory store knows no version, so only the user document can reject the old token
+	opts := AuthOptions{Keys: keys, Revocations: NewMemoryRevocationStore(), Users: users}
+	handler := RequireAuth(opts, log)(RequireVerifiedEmail(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	serve := func(version int) int {
+		token, err := GenerateJWT(keys, Claims{UserID: "user-1", Email: "user@example.edu", Role: "student", TokenVersion: version}, time.Minute)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/complaints", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(2))
+	assert.Equal(t, 1, users.reads)
+	assert.Equal(t, http.StatusUnauthorized, serve(1), "tokens before the user's version are revoked")
+}
+
+func TestRequireMFA(t *testing.T) {
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RequireAuth(AuthOptions{Keys: keys}, log)(RequireMFA(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		name       string
+		mfa        bool
+		wantStatus int
+	}{
+		{name: "two-factor session", mfa: true, wantStatus: http.StatusOK},
+		{name: "password-only session", mfa: false, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := GenerateJWT(keys, Claims{UserID: "admin-1", Email: "admin@example.edu", Role: "admin", MFA: tt.mfa}, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/complaints", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name       string
+		role       string
+		permission authz.Permission
+		wantStatus int
+	}{
+		{name: "admin manages users", role: "admin", permission: authz.UsersManage, wantStatus: http.StatusOK},
+		{name: "moderator moderates", role: "moderator", permission: authz.ComplaintsModerate, wantStatus: http.StatusOK},
+		{name: "scoped staff pass to the handler", role: "staff", permission: authz.ComplaintsRead, wantStatus: http.StatusOK},
+		{name: "auditor cannot moderate", role: "auditor", permission: authz.ComplaintsModerate, wantStatus: http.StatusForbidden},
+		{name: "moderator cannot manage webhooks", role: "moderator", permission: authz.WebhooksManage, wantStatus: http.StatusForbidden},
+		{name: "student has no permissions", role: "student", permission: authz.ComplaintsRead, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireAuth(AuthOptions{Keys: keys}, log)(RequirePermission(tt.permission, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+			token, err := GenerateJWT(keys, Claims{UserID: "user-1", Email: "user@example.edu", Role: tt.role}, time.Minute)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/complaints", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestMemoryRevocationStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryRevocationStore()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Revoke(ctx, "jti-1", "user-1", now.Add(time.Minute)))
+	require.NoError(t, store.Revoke(ctx, "jti-expired", "user-1", now.Add(-time.Minute)))
+
+	revoked, err := store.IsRevoked(ctx, "jti-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	assert.NotContains(t, store.revoked, "jti-expired")
+
+	// Entries are dropped once the token has expired
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, store.Revoke(ctx, "jti-2", "user-1", now.Add(time.Minute)))
+	assert.NotContains(t, store.revoked, "jti-1")
+}
+
+func TestGenerateToken(t *testing.T) {
+	token, hash := GenerateToken()
+	other, otherHash := GenerateToken()
+
+	assert.Len(t, token, 52)
+	assert.NotEqual(t, token, other)
+	assert.NotEqual(t, hash, otherHash)
+	assert.Equal(t, hash, HashToken(token))
+	assert.NotContains(t, hash, token)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireCSRF(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RequireCSRF(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name       string
+		method     string
+		authCookie bool
+		refresh    bool
+		csrfCookie string
+		csrfHeader string
+		apiKey     bool
+		wantStatus int
+	}{
+		{name: "cookie with matching token", method: http.MethodPost, authCookie: true, csrfCookie: "token-1", csrfHeader: "token-1", wantStatus: http.StatusOK},
+		{name: "cookie without header", method: http.MethodPost, authCookie: true, csrfCookie: "token-1", wantStatus: http.StatusForbidden},
+		{name: "cookie with other token", method: http.MethodDelete, authCookie: true, csrfCookie: "token-1", csrfHeader: "token-2", wantStatus: http.StatusForbidden},
+		{name: "cookie without CSRF cookie", method: http.MethodPut, authCookie: true, csrfHeader: "token-1", wantStatus: http.StatusForbidden},
+		{name: "safe method", method: http.MethodGet, authCookie: true, wantStatus: http.StatusOK},
+		{name: "bearer token", method: http.MethodPost, wantStatus: http.StatusOK},
+		{name: "API key", method: http.MethodPost, authCookie: true, apiKey: true, wantStatus: http.StatusOK},
+		{name: "refresh cookie without header", method: http.MethodPost, refresh: true, csrfCookie: "token-1", wantStatus: http.StatusForbidden},
+		{name: "refresh cookie with matching token", method: http.MethodPost, refresh: true, csrfCookie: "token-1", csrfHeader: "token-1", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/complaints", nil)
+			if tt.authCookie {
+				req.AddCookie(&http.Cookie{Name: "auth_token", Value: "access-token"})
+			}
+			if tt.refresh {
+				req.AddCookie(&http.Cookie{Name: RefreshCookieName, Value: "refresh-token"})
+			}
+			if tt.csrfCookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.csrfCookie})
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set(CSRFHeader, tt.csrfHeader)
+			}
+			if tt.apiKey {
+				req.Header.Set(APIKeyHeader, "scp_key")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// keyReloadInterval is how often the key ring reloads keys created by other instances and rotates
+const keyReloadInterval = time.Minute
+
+var errNoSigningKey = errors.New("no active signing key")
+
+// KeyStore persists signing keys shared by all instances
+type KeyStore interface {
+	GetSigningKeys(ctx context.Context) ([]models.SigningKey, error)
+	CreateSigningKey(ctx context.Context, key *models.SigningKey) error
+}
+
+// KeyRingConfig configures a KeyRing
+type KeyRingConfig struct {
+	Algorithm string        // Algorithm of newly created keys, AlgorithmRS256 or AlgorithmEdDSA
+	Rotation  time.Duration // How long each key signs tokens
+	VerifyFor time.Duration // How long a key keeps verifying tokens after it stopped signing, at least the token lifetime
+	Issuer    string        // Issuer (iss) set on and required from tokens
+	// EncryptionKey is the base64 of a 32 byte AES-256 key. Private keys are sealed with it before they are stored,
+	// so reading the KeyStore is not enough to sign tokens.
+	EncryptionKey string
+}
+
+// KeyRing signs access tokens with the current key and verifies them with any key that has not expired.
+// The key for the next rotation period is created one period in advance, so every instance and every
+// service using the JWKS knows it before the first token is signed with it.
+type KeyRing struct {
+	store  KeyStore
+	config KeyRingConfig
+	aead   cipher.AEAD // Seals private keys with the key encryption key
+	log    *slog.Logger
+	now    func() time.Time
+
+	mu   sync.RWMutex
+	keys []*ringKey // Ordered by activation time
+}
+
+// ringKey is a parsed signing key
+type ringKey struct {
+	id          string
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	activatesAt time.Time
+	expiresAt   time.Time
+}
+
+// NewKeyRing creates a KeyRing. Call Load before using it.
+func NewKeyRing(store KeyStore, config KeyRingConfig, log *slog.Logger) (*KeyRing, error) {
+	if config.Algorithm != AlgorithmRS256 && config.Algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", config.Algorithm)
+	}
+	if config.Rotation <= 0 {
+		return nil, errors.New("key rotation interval must be positive")
+	}
+	encryptionKey, err := base64.StdEncoding.DecodeString(config.EncryptionKey)
+	if err != nil || len(encryptionKey) != 32 {
+		return nil, errors.New("key encryption key must be 32 base64-encoded bytes")
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	const module = "keyRing"
+	return &KeyRing{
+		store:  store,
+		config: config,
+		aead:   aead,
+		log:    log.With(slog.String("module", module)),
+		now:    time.Now,
+	}, nil
+}
+
+// Load creates the keys of the current and the next rotation period if they are missing and reloads all keys
+func (k *KeyRing) Load(ctx context.Context) error {
+	stored, err := k.store.GetSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := k.now().UTC().Truncate(k.config.Rotation)
+	for _, activatesAt := range []time.Time{current, current.Add(k.config.Rotation)} {
+		id := k.keyID(activatesAt)
+		if slices.ContainsFunc(stored, func(key models.SigningKey) bool { return key.ID == id }) {
+			continue
+		}
+
+		key, err := k.newKey(id, activatesAt)
+		if err != nil {
+			return err
+		}
+		if err := k.store.CreateSigningKey(ctx, key); err != nil {
+			if !errors.Is(err, cosmos.ErrSigningKeyExists) {
+				return err
+			}
+			continue // Created by another instance, loaded below
+		}
+		k.log.Info("signing key created", slog.String("kid", id), slog.Time("activatesAt", activatesAt))
+	}
+
+	stored, err = k.store.GetSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*ringKey, 0, len(stored))
+	for _, key := range stored {
+		parsed, err := k.parseSigningKey(key)
+		if err != nil {
+			k.log.Error("skipping invalid signing key", slog.String("kid", key.ID), slog.String("error", err.Error()))
+			continue
+		}
+		keys = append(keys, parsed)
+	}
+	slices.SortFunc(keys, func(a, b *ringKey) int { return a.activatesAt.Compare(b.activatesAt) })
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// Run reloads and rotates keys until ctx is canceled
+func (k *KeyRing) Run(ctx context.Context) {
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Load(ctx); err != nil && ctx.Err() == nil {
+				k.log.Error("failed to reload signing keys", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Sign signs the claims with the current key
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	key := k.signingKey()
+	if key == nil {
+		return "", errNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// Parse validates a token signed by any key of the ring and fills claims
+func (k *KeyRing) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA})}
+	if k.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(k.config.Issuer))
+	}
+	return jwt.ParseWithClaims(tokenString, claims, k.verificationKey, options...)
+}
+
+// Issuer returns the issuer set on tokens
+func (k *KeyRing) Issuer() string {
+	return k.config.Issuer
+}
+
+// verificationKey returns the public key named by the token's kid header
+func (k *KeyRing) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no key ID")
+	}
+
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.id != kid {
+			continue
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("signing method does not match key")
+		}
+		if now.After(key.expiresAt) {
+			return nil, errors.New("signing key expired")
+		}
+		return key.private.Public(), nil
+	}
+	return nil, fmt.Errorf("unknown key ID %q", kid)
+}
+
+// signingKey returns the most recently activated key
+func (k *KeyRing) signingKey() *ringKey {
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.keys[i].activatesAt.After(now) && now.Before(k.keys[i].expiresAt) {
+			return k.keys[i]
+		}
+	}
+	return nil
+}
+
+// keyID returns the deterministic ID of the key activating at activatesAt, so instances rotating
+// at the same time create the same key only once
+func (k *KeyRing) keyID(activatesAt time.Time) string {
+	return strings.ToLower(k.config.Algorithm) + "-" + activatesAt.UTC().Format("20060102T150405Z")
+}
+
+// newKey generates a key activating at activatesAt
+func (k *KeyRing) newKey(id string, activatesAt time.Time) (*models.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch k.config.Algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	// The key ID is bound to the ciphertext, so a sealed key cannot be swapped onto another record
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := k.aead.Seal(nonce, nonce, der, []byte(id))
+
+	return &models.SigningKey{
+		ID:                  id,
+		Algorithm:           k.config.Algorithm,
+		PublicKey:           string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		EncryptedPrivateKey: base64.StdEncoding.EncodeToString(sealed),
+		ActivatesAt:         activatesAt,
+		ExpiresAt:           activatesAt.Add(k.config.Rotation + k.config.VerifyFor),
+		CreatedAt:           k.now().UTC(),
+	}, nil
+}
+
+// parseSigningKey decrypts and parses a stored key
+func (k *KeyRing) parseSigningKey(key models.SigningKey) (*ringKey, error) {
+	der, err := k.privateKeyDER(key)
+	if err != nil {
+		return nil, err
+	}
+	private, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &ringKey{id: key.ID, activatesAt: key.ActivatesAt, expiresAt: key.ExpiresAt}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if key.Algorithm != AlgorithmRS256 {
+			return nil, fmt.Errorf("RSA key stored for algorithm %q", key.Algorithm)
+		}
+		parsed.method, parsed.private = jwt.SigningMethodRS256, private
+	case ed25519.PrivateKey:
+		if key.Algorithm != AlgorithmEdDSA {
+			return nil, fmt.Errorf("Ed25519 key stored for algorithm %q", key.Algorithm)
+		}
+		parsed.method, parsed.private = jwt.SigningMethodEdDSA, private
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+	return parsed, nil
+}
+
+// privateKeyDER returns the PKCS #8 DER of a stored private key
+func (k *KeyRing) privateKeyDER(key models.SigningKey) ([]byte, error) {
+	if key.EncryptedPrivateKey == "" {
+		// Created before keys were encrypted; such keys are only read until they expire
+		block, _ := pem.Decode([]byte(key.PrivateKey))
+		if block == nil {
+			return nil, errors.New("invalid PEM")
+		}
+		return block.Bytes, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(key.EncryptedPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := k.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("encrypted private key is too short")
+	}
+	der, err := k.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(key.ID))
+	if err != nil {
+		return nil, errors.New("failed to decrypt private key, was the key encryption key changed?")
+	}
+	return der, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that currently verify tokens, including the next key that does not sign yet
+func (k *KeyRing) JWKS() JWKS {
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		if now.After(key.expiresAt) {
+			continue
+		}
+
+		jwk := JWK{KeyID: key.id, Use: "sig", Algorithm: key.method.Alg()}
+		switch public := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
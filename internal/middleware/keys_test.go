@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryKeyStore is a KeyStore shared by the key rings of a test
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]models.SigningKey
+}
+
+func (m *memoryKeyStore) GetSigningKeys(context.Context) ([]models.SigningKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]models.SigningKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (m *memoryKeyStore) CreateSigningKey(_ context.Context, key *models.SigningKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.keys == nil {
+		m.keys = make(map[string]models.SigningKey)
+	}
+	if _, ok := m.keys[key.ID]; ok {
+		return cosmos.ErrSigningKeyExists
+	}
+	m.keys[key.ID] = *key
+	return nil
+}
+
+// testEncryptionKey is the key encryption key of test key rings
+var testEncryptionKey = base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+// newTestKeyRing creates a loaded key ring whose clock is read from now
+func newTestKeyRing(t *testing.T, store KeyStore, algorithm string, now *time.Time) *KeyRing {
+	t.Helper()
+	ring, err := NewKeyRing(store, KeyRingConfig{
+		Algorithm:     algorithm,
+		Rotation:      24 * time.Hour,
+		VerifyFor:     time.Hour,
+		Issuer:        "portal-test",
+		EncryptionKey: testEncryptionKey,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	ring.now = func() time.Time { return *now }
+	require.NoError(t, ring.Load(context.Background()))
+	return ring
+}
+
+func testClaims(now time.Time) *Claims {
+	return &Claims{
+		UserID: "user-1",
+		Role:   "student",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "portal-test",
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+func TestKeyRingSignAndParse(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			now := time.Now()
+			ring := newTestKeyRing(t, &memoryKeyStore{}, algorithm, &now)
+
+			token, err := ring.Sign(testClaims(now))
+			require.NoError(t, err)
+
+			claims, err := ParseJWT(token, ring)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims.UserID)
+
+			jwks := ring.JWKS()
+			assert.Len(t, jwks.Keys, 2) // current and next
+			for _, key := range jwks.Keys {
+				assert.Equal(t, algorithm, key.Algorithm)
+				assert.Equal(t, "sig", key.Use)
+			}
+		})
+	}
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	store := &memoryKeyStore{}
+	now := time.Now().UTC().Truncate(24 * time.Hour).Add(23 * time.Hour)
+	signer := newTestKeyRing(t, store, AlgorithmEdDSA, &now)
+	verifier := newTestKeyRing(t, store, AlgorithmEdDSA, &now)
+
+	// Long-lived, so only the key expiry can reject it
+	oldClaims := testClaims(now)
+	oldClaims.ExpiresAt = jwt.NewNumericDate(now.Add(72 * time.Hour))
+	oldToken, err := signer.Sign(oldClaims)
+	require.NoError(t, err)
+
+	// After the period ends the signer uses the next key, which the other instance already knows
+	now = now.Add(2 * time.Hour)
+	newToken, err := signer.Sign(testClaims(now))
+	require.NoError(t, err)
+	assert.NotEqual(t, tokenKeyID(t, oldToken), tokenKeyID(t, newToken))
+
+	for _, token := range []string{oldToken, newToken} {
+		_, err := ParseJWT(token, verifier)
+		assert.NoError(t, err)
+	}
+
+	// Reloading creates the key of the following period
+	require.NoError(t, signer.Load(context.Background()))
+	assert.Len(t, store.keys, 3)
+
+	// Keys stop verifying after their verification window
+	now = now.Add(24 * time.Hour)
+	_, err = ParseJWT(oldToken, verifier)
+	assert.ErrorContains(t, err, "signing key expired")
+}
+
+func TestKeyRingRejectsForeignTokens(t *testing.T) {
+	now := time.Now()
+	ring := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	other := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+
+	// HMAC token using the public key as secret
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(now))
+	hmac.Header["kid"] = ring.signingKey().id
+	hmacToken, err := hmac.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	otherToken, err := other.Sign(testClaims(now))
+	require.NoError(t, err)
+
+	wrongIssuer := testClaims(now)
+	wrongIssuer.Issuer = "someone-else"
+	wrongIssuerToken, err := ring.Sign(wrongIssuer)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{"hmac": hmacToken, "other ring": otherToken, "wrong issuer": wrongIssuerToken} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseJWT(token, ring)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func tokenKeyID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	require.NoError(t, err)
+	return parsed.Header["kid"].(string)
+}
+
+func TestKeyRingEncryptsStoredKeys(t *testing.T) {
+	store := &memoryKeyStore{}
+	now := time.Now()
+	ring := newTestKeyRing(t, store, AlgorithmEdDSA, &now)
+	token, err := ring.Sign(testClaims(now))
+	require.NoError(t, err)
+
+	for _, key := range store.keys {
+		assert.Empty(t, key.PrivateKey)
+		assert.NotEmpty(t, key.EncryptedPrivateKey)
+		assert.Contains(t, key.PublicKey, "BEGIN PUBLIC KEY")
+	}
+
+	// An instance with another key encryption key cannot use the stored keys
+	other, err := NewKeyRing(store, KeyRingConfig{
+		Algorithm:     AlgorithmEdDSA,
+		Rotation:      24 * time.Hour,
+		VerifyFor:     time.Hour,
+		Issuer:        "portal-test",
+		EncryptionKey: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")),
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	other.now = func() time.Time { return now }
+	require.NoError(t, other.Load(context.Background()))
+	_, err = ParseJWT(token, other)
+	assert.Error(t, err)
+	_, err = other.Sign(testClaims(now))
+	assert.ErrorIs(t, err, errNoSigningKey)
+}
+
+func TestNewKeyRingRequiresEncryptionKey(t *testing.T) {
+	for _, encryptionKey := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		_, err := NewKeyRing(&memoryKeyStore{}, KeyRingConfig{
+			Algorithm:     AlgorithmEdDSA,
+			Rotation:      time.Hour,
+			EncryptionKey: encryptionKey,
+		}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token purposes
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposeMFAChallenge      = "mfa_challenge"
+	PurposeEmailChange       = "email_change"
+)
+
+// PurposeClaims are the claims of single-purpose tokens sent in links, such as email verification.
+// They have no userId claim, so they are never accepted as access tokens.
+type PurposeClaims struct {
+	Purpose string `json:"purpose"`
+	Email   string `json:"email,omitempty"` // The link is only valid while the account still has this email
+	jwt.RegisteredClaims
+}
+
+// GeneratePurposeToken creates a signed token for the user that is only accepted for the given purpose
+func GeneratePurposeToken(keys *KeyRing, purpose, userId, email string, ttl time.Duration) (string, error) {
+	claims := PurposeClaims{
+		Purpose: purpose,
+		Email:   email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userId,
+			Issuer:    keys.Issuer(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return keys.Sign(claims)
+}
+
+// ParsePurposeToken validates a token created by GeneratePurposeToken for the given purpose
+func ParsePurposeToken(tokenString string, keys *KeyRing, purpose string) (*PurposeClaims, error) {
+	token, err := keys.Parse(tokenString, &PurposeClaims{})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*PurposeClaims)
+	if !ok || !token.Valid || claims.Subject == "" {
+		return nil, errors.New("invalid token claims")
+	}
+	if claims.Purpose != purpose {
+		return nil, errors.New("token issued for another purpose")
+	}
+	return claims, nil
+}
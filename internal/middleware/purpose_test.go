@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurposeTokens(t *testing.T) {
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+
+	token, err := GeneratePurposeToken(keys, PurposeEmailVerification, "user-1", "student@example.edu", time.Hour)
+	require.NoError(t, err)
+
+	claims, err := ParsePurposeToken(token, keys, PurposeEmailVerification)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "student@example.edu", claims.Email)
+
+	// Verification tokens are not access tokens and the other way round
+	_, err = ParseJWT(token, keys)
+	assert.Error(t, err)
+
+	accessToken, err := GenerateJWT(keys, Claims{UserID: "user-1", Email: "student@example.edu", Role: "student"}, time.Hour)
+	require.NoError(t, err)
+	_, err = ParsePurposeToken(accessToken, keys, PurposeEmailVerification)
+	assert.Error(t, err)
+
+	_, err = ParsePurposeToken(token, keys, "password_reset")
+	assert.Error(t, err)
+
+	expired, err := GeneratePurposeToken(keys, PurposeEmailVerification, "user-1", "student@example.edu", -time.Minute)
+	require.NoError(t, err)
+	_, err = ParsePurposeToken(expired, keys, PurposeEmailVerification)
+	assert.Error(t, err)
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RequireVerifiedEmail(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		wantStatus int
+	}{
+		{name: "verified", user: &models.User{ID: "verified"}, wantStatus: http.StatusOK},
+		{name: "unverified", user: &models.User{ID: "unverified", Unverified: true}, wantStatus: http.StatusForbidden},
+		{name: "no user in context", wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/complaints", nil)
+			if tt.user != nil {
+				req = req.WithContext(context.WithValue(req.Context(), userKey, tt.user))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
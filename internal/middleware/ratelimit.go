@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter allows a number of requests per key in fixed time windows. Counters are kept per instance.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+// rateWindow counts the requests of one key in the current window
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter creates a RateLimiter allowing limit requests per key and window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow counts a request for key and reports whether it is allowed, and if not, when to retry
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	current, ok := l.windows[key]
+	if !ok || now.Sub(current.start) >= l.window {
+		// Drop windows that ended, so the map does not grow with every client ever seen
+		if !ok && len(l.windows) > 0 {
+			for k, w := range l.windows {
+				if now.Sub(w.start) >= l.window {
+					delete(l.windows, k)
+				}
+			}
+		}
+		current = &rateWindow{start: now}
+		l.windows[key] = current
+	}
+
+	if current.count >= l.limit {
+		return false, current.start.Add(l.window).Sub(now)
+	}
+	current.count++
+	return true, 0
+}
+
+// RateLimit middleware limits requests per client IP. Use it after chi's RealIP middleware,
+// which puts the client IP from X-Forwarded-For or X-Real-IP into RemoteAddr.
+func RateLimit(limiter *RateLimiter, log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ClientIP(r)
+			if ok, retryAfter := limiter.Allow(ip); !ok {
+				log.Warn("rate limit exceeded", slog.String("event", "rate_limited"), slog.String("ip", ip), slog.String("path", r.URL.Path))
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the client IP of the request without the port
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	ok, _ := limiter.Allow("203.0.113.7")
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("203.0.113.7")
+	assert.True(t, ok)
+
+	now = now.Add(20 * time.Second)
+	ok, retryAfter := limiter.Allow("203.0.113.7")
+	assert.False(t, ok)
+	assert.Equal(t, 40*time.Second, retryAfter)
+
+	// Other keys have their own counters
+	ok, _ = limiter.Allow("198.51.100.1")
+	assert.True(t, ok)
+
+	// A new window starts after the old one ended
+	now = now.Add(40 * time.Second)
+	ok, _ = limiter.Allow("203.0.113.7")
+	assert.True(t, ok)
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Minute)
+	handler := RateLimit(limiter, slog.New(slog.NewTextHandler(io.Discard, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request("203.0.113.7:1234").Code)
+
+	// The port does not matter, only the IP
+	rec := request("203.0.113.7:5678")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, request("198.51.100.1:1234").Code)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// Error codes returned in RestrictionResponse
+const (
+	ErrCodeAccountSuspended = "account_suspended"
+	ErrCodePostingBanned    = "posting_banned"
+	ErrCodeLikingBanned     = "liking_banned"
+)
+
+// RestrictionResponse is the body of 403 responses to users an admin has restricted
+type RestrictionResponse struct {
+	Code    string     `json:"code"`
+	Message string     `json:"message"`
+	Reason  string     `json:"reason,omitempty"`
+	Since   time.Time  `json:"since"`
+	Until   *time.Time `json:"until,omitempty"` // Absent for restrictions without expiry
+}
+
+// WriteRestriction writes a 403 response that explains the restriction
+func WriteRestriction(w http.ResponseWriter, code, message string, restriction *models.Restriction) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(RestrictionResponse{
+		Code:    code,
+		Message: message,
+		Reason:  restriction.Reason,
+		Since:   restriction.CreatedAt,
+		Until:   restriction.ExpiresAt,
+	})
+}
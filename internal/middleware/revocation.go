@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+var (
+	// ErrTokenRevoked is returned for tokens that were revoked or issued before the user's current token version
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrTokenSubjectNotFound is returned by a RevocationStore when the token's user no longer exists
+	ErrTokenSubjectNotFound = errors.New("token subject not found")
+)
+
+// RevocationStore keeps revoked access tokens until they expire and the per-user token version
+// that invalidates every token issued before it
+type RevocationStore interface {
+	// Revoke rejects the token with the given JWT ID until it expires
+	Revoke(ctx context.Context, jti, userID string, expiresAt time.Time) error
+	// IsRevoked reports whether the token with the given JWT ID was revoked
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// TokenVersion returns the user's current token version
+	TokenVersion(ctx context.Context, userID string) (int, error)
+	// RevokeAll increments the user's token version and returns the new one
+	RevokeAll(ctx context.Context, userID string) (int, error)
+}
+
+// RevokeClaims revokes the token the claims were parsed from
+func RevokeClaims(ctx context.Context, store RevocationStore, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil // Issued before tokens had an ID, it expires on its own
+	}
+	return store.Revoke(ctx, claims.ID, claims.UserID, claims.ExpiresAt.Time)
+}
+
+// checkRevocation returns ErrTokenRevoked if the token was revoked on its own or by a newer token version.
+// The version is read from user if it is loaded already, and from the store otherwise.
+func checkRevocation(ctx context.Context, store RevocationStore, claims *Claims, user *models.User) error {
+	if claims.ID != "" {
+		revoked, err := store.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+
+	if user != nil {
+		if claims.TokenVersion < user.TokenVersion {
+			return ErrTokenRevoked
+		}
+		return nil
+	}
+	version, err := store.TokenVersion(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrTokenSubjectNotFound) {
+			return ErrTokenRevoked
+		}
+		return err
+	}
+	if claims.TokenVersion < version {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// MemoryRevocationStore is a RevocationStore for a single instance. Its state is lost on restart.
+type MemoryRevocationStore struct {
+	mu       sync.Mutex
+	revoked  map[string]time.Time // JWT ID to expiry
+	versions map[string]int
+	now      func() time.Time
+}
+
+// NewMemoryRevocationStore creates an empty MemoryRevocationStore
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked:  make(map[string]time.Time),
+		versions: make(map[string]int),
+		now:      time.Now,
+	}
+}
+
+// Revoke rejects the token with the given JWT ID until it expires
+func (m *MemoryRevocationStore) Revoke(_ context.Context, jti, _ string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop entries of tokens that expired meanwhile
+	now := m.now()
+	for id, exp := range m.revoked {
+		if now.After(exp) {
+			delete(m.revoked, id)
+		}
+	}
+
+	if now.Before(expiresAt) {
+		m.revoked[jti] = expiresAt
+	}
+	return nil
+}
+
+// IsRevoked reports whether the token with the given JWT ID was revoked
+func (m *MemoryRevocationStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exp, ok := m.revoked[jti]
+	return ok && m.now().Before(exp), nil
+}
+
+// TokenVersion returns the user's current token version
+func (m *MemoryRevocationStore) TokenVersion(_ context.Context, userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.versions[userID], nil
+}
+
+// RevokeAll increments the user's token version and returns the new one
+func (m *MemoryRevocationStore) RevokeAll(_ context.Context, userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[userID]++
+	return m.versions[userID], nil
+}
+
+// CosmosRevocationStore is a RevocationStore shared by all instances. Revoked tokens are kept in the
+// revoked-tokens container and the token version on the user document.
+type CosmosRevocationStore struct {
+	cosmos *cosmos.Service
+}
+
+// NewCosmosRevocationStore creates a CosmosRevocationStore
+func NewCosmosRevocationStore(cosmosService *cosmos.Service) *CosmosRevocationStore {
+	return &CosmosRevocationStore{cosmos: cosmosService}
+}
+
+// Revoke rejects the token with the given JWT ID until it expires
+func (c *CosmosRevocationStore) Revoke(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	if !time.Now().Before(expiresAt) {
+		return nil
+	}
+	return c.cosmos.RevokeAccessToken(ctx, &models.RevokedToken{
+		ID:        jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		RevokedAt: time.Now().UTC(),
+	})
+}
+
+// IsRevoked reports whether the token with the given JWT ID was revoked
+func (c *CosmosRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return c.cosmos.IsAccessTokenRevoked(ctx, jti)
+}
+
+// TokenVersion returns the user's current token version
+func (c *CosmosRevocationStore) TokenVersion(ctx context.Context, userID string) (int, error) {
+	user, err := c.cosmos.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil {
+		return 0, ErrTokenSubjectNotFound
+	}
+	return user.TokenVersion, nil
+}
+
+// RevokeAll increments the user's token version and returns the new one
+func (c *CosmosRevocationStore) RevokeAll(ctx context.Context, userID string) (int, error) {
+	version, err := c.cosmos.IncrementTokenVersion(ctx, userID)
+	if errors.Is(err, cosmos.ErrUserNotFound) {
+		return 0, ErrTokenSubjectNotFound
+	}
+	return version, err
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// sessionTouchInterval limits how often the last-seen time of a session is written
+const sessionTouchInterval = time.Minute
+
+// SessionStore looks up the sessions that access tokens belong to for RequireAuth
+type SessionStore interface {
+	// GetSession returns the session of the user, or nil if it was removed
+	GetSession(ctx context.Context, userID, sessionID string) (*models.Session, error)
+	// TouchSession stores the last-seen time and IP of the session, unless it was removed meanwhile
+	TouchSession(ctx context.Context, session *models.Session) error
+}
+
+// checkSession rejects tokens whose session has been removed and records that the session was seen. Tokens
+// issued before sessions were tracked carry no session ID and are accepted until they expire.
+func checkSession(w http.ResponseWriter, r *http.Request, store SessionStore, claims *Claims, log *slog.Logger) bool {
+	if claims.SessionID == "" {
+		return true
+	}
+
+	session, err := store.GetSession(r.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		log.Error("failed to get session", slog.String("path", r.URL.Path), slog.String("userId", claims.UserID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to validate token", http.StatusInternalServerError)
+		return false
+	}
+	if session == nil {
+		log.Debug("token of removed session presented", slog.String("path", r.URL.Path), slog.String("userId", claims.UserID))
+		http.Error(w, "Session ended", http.StatusUnauthorized)
+		return false
+	}
+
+	if err := touchSession(r.Context(), store, session, ClientIP(r), time.Now().UTC()); err != nil {
+		log.Warn("failed to record session activity", slog.String("userId", claims.UserID), slog.String("error", err.Error()))
+	}
+	return true
+}
+
+// touchSession records the activity of the session, at most once per sessionTouchInterval
+func touchSession(ctx context.Context, store SessionStore, session *models.Session, ip string, now time.Time) error {
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval && session.IP == ip {
+		return nil
+	}
+	session.LastSeenAt = now
+	session.IP = ip
+	return store.TouchSession(ctx, session)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSessionStore is a SessionStore backed by a map of session IDs
+type fakeSessionStore struct {
+	sessions map[string]*models.Session
+	touched  int
+}
+
+func (f *fakeSessionStore) GetSession(_ context.Context, userID, sessionID string) (*models.Session, error) {
+	if session, ok := f.sessions[sessionID]; ok && session.UserID == userID {
+		return session, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeSessionStore) TouchSession(_ context.Context, session *models.Session) error {
+	f.touched++
+	return nil
+}
+
+func TestRequireAuthSession(t *testing.T) {
+	now := time.Now()
+	keys := newTestKeyRing(t, &memoryKeyStore{}, AlgorithmEdDSA, &now)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &fakeSessionStore{sessions: map[string]*models.Session{
+		"laptop": {ID: "laptop", UserID: "u-1", LastSeenAt: now.Add(-time.Hour)},
+	}}
+	handler := RequireAuth(AuthOptions{Keys: keys, Sessions: store}, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(sessionID string) int {
+		token, err := GenerateJWT(keys, Claims{UserID: "u-1", Role: "student", SessionID: sessionID}, time.Minute)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("laptop"))
+	assert.Equal(t, 1, store.touched)
+	assert.Equal(t, http.StatusOK, serve("laptop"))
+	assert.Equal(t, 1, store.touched, "activity within a minute from the same IP is not recorded again")
+
+	assert.Equal(t, http.StatusUnauthorized, serve("phone"), "removed sessions are rejected")
+	assert.Equal(t, http.StatusOK, serve(""), "tokens from before session tracking are accepted")
+}
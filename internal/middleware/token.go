@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// RefreshCookieName is the cookie holding the refresh token. It is only sent to the auth endpoints.
+const RefreshCookieName = "refresh_token"
+
+// RefreshCookiePath limits the refresh cookie to the auth endpoints
+const RefreshCookiePath = "/api/auth"
+
+// GenerateToken returns a new random opaque token (refresh, password reset, ...) and the hash to store for it
+func GenerateToken() (token, hash string) {
+	token = rand.Text() + rand.Text()
+	return token, HashToken(token)
+}
+
+// HashToken returns the hex SHA-256 hash under which an opaque token is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// UserStore looks up users for middleware that needs more than the token claims
+type UserStore interface {
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+}
+
+// RequireVerifiedEmail middleware rejects users who have not verified their email yet. Use it after RequireAuth
+// with AuthOptions.Users set, which puts the user in the request context.
+func RequireVerifiedEmail(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := GetUser(r.Context())
+			if !ok {
+				log.Error("user not found in context", slog.String("path", r.URL.Path))
+				http.Error(w, "Failed to check email verification", http.StatusInternalServerError)
+				return
+			}
+			if !user.EmailVerified() {
+				log.Debug("unverified user attempted a restricted action", slog.String("userId", user.ID), slog.String("path", r.URL.Path))
+				http.Error(w, "Forbidden: verify your email address first", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
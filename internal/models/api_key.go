@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIKey is a personal API key a user created for an integration. Only the hash of the key is stored.
+type APIKey struct {
+	ID         string     `json:"id"` // SHA-256 hash of the key
+	UserID     string     `json:"userId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Start of the key, so users can tell their keys apart
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	LastUsedIP string     `json:"lastUsedIp,omitempty"`
+	TTL        int        `json:"ttl"` // Seconds until Cosmos DB deletes the record
+}
+
+// Expired reports whether the key has expired at now
+func (k *APIKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AuditEntry records an action an admin took on an account
+type AuditEntry struct {
+	ID        string            `json:"id"`
+	Action    string            `json:"action"`   // One of the Audit constants
+	ActorID   string            `json:"actorId"`  // The admin who took the action
+	TargetID  string            `json:"targetId"` // The affected user, also the partition key
+	Details   map[string]string `json:"details,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+const (
+	AuditUserRoleChanged   = "user.role_changed"
+	AuditUserSuspended     = "user.suspended"
+	AuditUserReactivated   = "user.reactivated"
+	AuditUserBanned        = "user.banned"
+	AuditUserUnbanned      = "user.unbanned"
+	AuditUserPasswordReset = "user.password_reset" // An admin forced a password reset
+	AuditUserUnlocked      = "user.unlocked"
+	AuditUserDeleted       = "user.deleted" // The user deleted their own account
+)
@@ -13,11 +13,14 @@ type Complaint struct {
 	ID          string    `json:"id"`
 	UserID      string    `json:"userId"`
 	Description string    `json:"description"`
+	Category    string    `json:"category,omitempty"` // Empty on complaints created before categories were introduced
 	Status      string    `json:"status"`
 	Comments    []Comment `json:"comments,omitempty"`
 	Likes       []string  `json:"likes,omitempty"` // Array of user IDs who liked this complaint
 	LikeCount   int       `json:"likeCount"`       // Total number of likes
 	CreatedAt   time.Time `json:"createdAt"`
+	// StatusChangedAt is set whenever an admin updates the status
+	StatusChangedAt time.Time `json:"statusChangedAt,omitempty"`
 }
 
 // ComplaintResponse is the response DTO for complaints with user-specific like information
@@ -25,6 +28,7 @@ type ComplaintResponse struct {
 	ID          string    `json:"id"`
 	UserID      string    `json:"userId"`
 	Description string    `json:"description"`
+	Category    string    `json:"category,omitempty"`
 	Status      string    `json:"status"`
 	Comments    []Comment `json:"comments,omitempty"`
 	LikeCount   int       `json:"likeCount"` // Total number of likes
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// DeniedEmail is an email address that may not register, managed by admins
+type DeniedEmail struct {
+	ID        string    `json:"id"` // Lower-case email address
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// OutboxEmail is a rendered email waiting in the outbox to be delivered by the outbox worker
+type OutboxEmail struct {
+	ID            string    `json:"id"`
+	To            string    `json:"to"`
+	Subject       string    `json:"subject"`
+	HTMLBody      string    `json:"htmlBody"`
+	TextBody      string    `json:"textBody"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"lastError,omitempty"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	LockedUntil   time.Time `json:"lockedUntil,omitempty"` // Lease held by the worker currently sending the email
+	CreatedAt     time.Time `json:"createdAt"`
+	SentAt        time.Time `json:"sentAt,omitempty"`
+	TTL           int       `json:"ttl,omitempty"`   // Seconds until Cosmos DB deletes the record, set once it is sent or given up
+	ETag          string    `json:"_etag,omitempty"` // Cosmos DB ETag used for optimistic concurrency
+}
+
+const (
+	EmailStatusPending string = "pending"
+	EmailStatusSending string = "sending"
+	EmailStatusSent    string = "sent"
+	EmailStatusFailed  string = "failed"
+)
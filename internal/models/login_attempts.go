@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// LoginAttempts tracks failed logins for one email address, whether or not an account exists for it
+type LoginAttempts struct {
+	ID            string     `json:"id"`       // Lower-case email address
+	Failures      int        `json:"failures"` // Consecutive failed attempts
+	LastFailureAt time.Time  `json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+	TTL           int        `json:"ttl"`             // Seconds until Cosmos DB deletes the record
+	ETag          string     `json:"_etag,omitempty"` // Cosmos DB ETag used for optimistic concurrency
+}
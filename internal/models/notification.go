@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Notification is an entry in a user's in-app notification inbox
+type Notification struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"userId"`
+	Type        string     `json:"type"`
+	ComplaintID string     `json:"complaintId,omitempty"`
+	ActorID     string     `json:"actorId,omitempty"` // User who triggered the notification, e.g. the admin or the liker
+	Status      string     `json:"status,omitempty"`  // New complaint status for status change notifications
+	Message     string     `json:"message"`
+	Read        bool       `json:"read"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ReadAt      *time.Time `json:"readAt,omitempty"`
+}
+
+const (
+	NotificationStatusChanged  string = "status_changed"
+	NotificationNewComment     string = "new_comment"
+	NotificationComplaintLiked string = "complaint_liked"
+	NotificationEscalation     string = "escalation"
+)
+
+// DigestItem is a notification held back for a user's next digest email
+type DigestItem struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"userId"`
+	Type        string    `json:"type"`
+	ComplaintID string    `json:"complaintId,omitempty"`
+	Summary     string    `json:"summary"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// NotificationPreferences stores, per event type, how a user wants to be notified.
+// There is one document per user and its ID is the user ID.
+type NotificationPreferences struct {
+	ID            string                     `json:"id"`
+	UserID        string                     `json:"userId"`
+	Events        map[string]EventPreference `json:"events"`
+	WebhookURL    string                     `json:"webhookUrl,omitempty"`    // Target of the webhook channel
+	WebhookSecret string                     `json:"webhookSecret,omitempty"` // Key for the HMAC-SHA256 signature of each webhook delivery
+	AdminDigest   string                     `json:"adminDigest,omitempty"`   // How often admins get the complaint summary email, ignored for students
+	UpdatedAt     time.Time                  `json:"updatedAt"`
+}
+
+// EventPreference selects the channels used for one event type and whether emails are sent immediately or in a digest
+type EventPreference struct {
+	Channels []string `json:"channels"`
+	Delivery string   `json:"delivery"`
+}
+
+// Event types users can set preferences for
+const (
+	EventStatusChanged  string = NotificationStatusChanged
+	EventNewComment     string = NotificationNewComment
+	EventComplaintLiked string = NotificationComplaintLiked
+	EventEscalation     string = NotificationEscalation
+)
+
+// Notification channels
+const (
+	ChannelInApp   string = "in_app"
+	ChannelEmail   string = "email"
+	ChannelWebhook string = "webhook"
+)
+
+// Delivery modes. The digest mode only affects the email channel.
+const (
+	DeliveryImmediate string = "immediate"
+	DeliveryDigest    string = "digest"
+)
+
+// Admin digest frequencies
+const (
+	AdminDigestDaily  string = "daily"
+	AdminDigestWeekly string = "weekly"
+	AdminDigestOff    string = "off"
+)
+
+// NotificationEventTypes lists every event type that has preferences
+var NotificationEventTypes = []string{EventStatusChanged, EventNewComment, EventComplaintLiked, EventEscalation}
+
+// defaultEventPreferences applies to users who have not saved a preference for an event type
+var defaultEventPreferences = map[string]EventPreference{
+	EventStatusChanged:  {Channels: []string{ChannelInApp, ChannelEmail}, Delivery: DeliveryImmediate},
+	EventNewComment:     {Channels: []string{ChannelInApp}, Delivery: DeliveryImmediate},
+	EventComplaintLiked: {Channels: []string{ChannelInApp}, Delivery: DeliveryImmediate},
+	EventEscalation:     {Channels: []string{ChannelInApp, ChannelEmail}, Delivery: DeliveryImmediate},
+}
+
+// DefaultNotificationPreferences returns the preferences used for a user who has never saved any
+func DefaultNotificationPreferences(userID string) *NotificationPreferences {
+	prefs := &NotificationPreferences{
+		ID:     userID,
+		UserID: userID,
+		Events: make(map[string]EventPreference, len(defaultEventPreferences)),
+	}
+	for eventType, pref := range defaultEventPreferences {
+		prefs.Events[eventType] = EventPreference{
+			Channels: slices.Clone(pref.Channels),
+			Delivery: pref.Delivery,
+		}
+	}
+	return prefs
+}
+
+// For returns the preference for an event type, falling back to the default when the user has not set one
+func (p *NotificationPreferences) For(eventType string) EventPreference {
+	if p != nil {
+		if pref, ok := p.Events[eventType]; ok {
+			return pref
+		}
+	}
+	return defaultEventPreferences[eventType]
+}
+
+// Has reports whether the channel is enabled
+func (p EventPreference) Has(channel string) bool {
+	return slices.Contains(p.Channels, channel)
+}
+
+// IsDigest reports whether emails for the event are collected into a digest
+func (p EventPreference) IsDigest() bool {
+	return p.Delivery == DeliveryDigest
+}
+
+// AdminDigestFrequency returns how often an admin receives the summary email, daily unless set otherwise
+func (p *NotificationPreferences) AdminDigestFrequency() string {
+	if p == nil || p.AdminDigest == "" {
+		return AdminDigestDaily
+	}
+	return p.AdminDigest
+}
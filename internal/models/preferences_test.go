@@ -0,0 +1,58 @@
+package models
+
+import "testing"
+
+func TestNotificationPreferences_For(t *testing.T) {
+	prefs := &NotificationPreferences{
+		Events: map[string]EventPreference{
+			EventComplaintLiked: {Channels: []string{ChannelEmail}, Delivery: DeliveryDigest},
+		},
+	}
+
+	liked := prefs.For(EventComplaintLiked)
+	if !liked.Has(ChannelEmail) || liked.Has(ChannelInApp) {
+		t.Errorf("got channels %v, want only email", liked.Channels)
+	}
+	if !liked.IsDigest() {
+		t.Errorf("got delivery %q, want digest", liked.Delivery)
+	}
+
+	// Event types the user has not configured fall back to the defaults
+	status := prefs.For(EventStatusChanged)
+	if !status.Has(ChannelInApp) || !status.Has(ChannelEmail) || status.IsDigest() {
+		t.Errorf("got %+v, want default status change preference", status)
+	}
+
+	var missing *NotificationPreferences
+	if !missing.For(EventNewComment).Has(ChannelInApp) {
+		t.Error("nil preferences should use the defaults")
+	}
+}
+
+func TestDefaultNotificationPreferences(t *testing.T) {
+	prefs := DefaultNotificationPreferences("user-1")
+	if prefs.ID != "user-1" || prefs.UserID != "user-1" {
+		t.Errorf("got id %q userId %q, want user-1", prefs.ID, prefs.UserID)
+	}
+
+	for _, eventType := range NotificationEventTypes {
+		if _, ok := prefs.Events[eventType]; !ok {
+			t.Errorf("missing default preference for %q", eventType)
+		}
+	}
+
+	// Changing a user's copy must not change the shared defaults
+	prefs.Events[EventStatusChanged].Channels[0] = ChannelWebhook
+	if DefaultNotificationPreferences("user-2").For(EventStatusChanged).Has(ChannelWebhook) {
+		t.Error("defaults were modified through a returned copy")
+	}
+}
+
+func TestUserIDFromWebhookID(t *testing.T) {
+	if userID, ok := UserIDFromWebhookID(UserWebhookID("user-1")); !ok || userID != "user-1" {
+		t.Errorf("got %q, %v, want user-1, true", userID, ok)
+	}
+	if _, ok := UserIDFromWebhookID("subscription-1"); ok {
+		t.Error("admin subscription IDs must not map to a user")
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// RefreshToken is a server-side record of an issued refresh token. Only the hash of the token is stored.
+// Tokens issued by rotating another token share its FamilyID, so reuse of an old token can revoke the whole chain.
+type RefreshToken struct {
+	ID         string     `json:"id"` // SHA-256 hash of the token
+	UserID     string     `json:"userId"`
+	FamilyID   string     `json:"familyId"`
+	MFA        bool       `json:"mfa,omitempty"` // The family started with a two-factor login, carried over to rotated tokens
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UsedAt     *time.Time `json:"usedAt,omitempty"`     // Set when the token was rotated; using it again is reuse
+	ReplacedBy string     `json:"replacedBy,omitempty"` // Hash of the token issued in its place
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	TTL        int        `json:"ttl"`             // Seconds until Cosmos DB deletes the record
+	ETag       string     `json:"_etag,omitempty"` // Cosmos DB ETag used for optimistic concurrency
+}
+
+// Revoked reports whether the token was revoked
+func (t *RefreshToken) Revoked() bool {
+	return t.RevokedAt != nil
+}
+
+// Used reports whether the token was already rotated
+func (t *RefreshToken) Used() bool {
+	return t.UsedAt != nil
+}
+
+// RevokedToken records a revoked access token by its JWT ID until the token would have expired anyway
+type RevokedToken struct {
+	ID        string    `json:"id"` // JWT ID (jti)
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	RevokedAt time.Time `json:"revokedAt"`
+	TTL       int       `json:"ttl"` // Seconds until Cosmos DB deletes the record
+}
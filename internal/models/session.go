@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Session is a login on one device. Its ID is the rotation family ID of the session's refresh tokens, and access
+// tokens carry it as the sid claim, so removing the session logs the device out.
+type Session struct {
+	ID         string    `json:"id"` // Refresh token family ID
+	UserID     string    `json:"userId"`
+	Device     string    `json:"device"` // Browser and operating system from the user agent
+	UserAgent  string    `json:"userAgent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	MFA        bool      `json:"mfa,omitempty"` // Started with two-factor authentication
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"` // When its refresh token expires, unless it is refreshed
+	TTL        int       `json:"ttl"`       // Seconds until Cosmos DB deletes the record
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SigningKey is a key pair for signing access tokens. A key is published in the JWKS as soon as it is created,
+// signs tokens from ActivatesAt until the next key activates, and verifies tokens until ExpiresAt.
+type SigningKey struct {
+	ID                  string    `json:"id"`                            // Key ID (kid)
+	Algorithm           string    `json:"algorithm"`                     // "RS256" or "EdDSA"
+	PublicKey           string    `json:"publicKey,omitempty"`           // PKIX PEM, readable without the key encryption key
+	EncryptedPrivateKey string    `json:"encryptedPrivateKey,omitempty"` // Base64 AES-256-GCM nonce and sealed PKCS #8 DER, with the key ID as additional data
+	PrivateKey          string    `json:"privateKey,omitempty"`          // Unencrypted PKCS #8 PEM of keys created before encryption
+	ActivatesAt         time.Time `json:"activatesAt"`
+	ExpiresAt           time.Time `json:"expiresAt"`
+	CreatedAt           time.Time `json:"createdAt"`
+	TTL                 int       `json:"ttl"` // Seconds until Cosmos DB deletes the record
+}
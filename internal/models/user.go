@@ -10,11 +10,112 @@ type User struct {
 	Name         string    `json:"name"`
 	UserName     string    `json:"username"`
 	PasswordHash string    `json:"passwordHash,omitempty"`
-	Role         string    `json:"role"` // "student" or "admin"
+	Role         string    `json:"role"`                 // One of the Role constants
+	Locale       string    `json:"locale,omitempty"`     // Preferred language for emails, e.g. "en" or "uk"
+	TokenVersion int       `json:"tokenVersion"`         // Incremented to invalidate every token issued so far
+	Categories   []string  `json:"categories,omitempty"` // Complaint categories of department staff
 	CreatedAt    time.Time `json:"createdAt"`
+
+	// Unverified is set on new accounts until the email address is verified. Accounts created before
+	// verification was introduced do not have it and count as verified.
+	Unverified         bool       `json:"unverified,omitempty"`
+	EmailVerifiedAt    *time.Time `json:"emailVerifiedAt,omitempty"`
+	VerificationSentAt *time.Time `json:"verificationSentAt,omitempty"`
+	// PendingRole is the role the email domain grants, applied once the address is verified
+	PendingRole string `json:"pendingRole,omitempty"`
+
+	// Pending email change, committed when the link sent to the new address is opened. A new request replaces it.
+	PendingEmail      string     `json:"pendingEmail,omitempty"`
+	EmailChangeSentAt *time.Time `json:"emailChangeSentAt,omitempty"`
+
+	// Pending password reset, only the SHA-256 hash of the emailed token is stored
+	PasswordResetHash      string     `json:"passwordResetHash,omitempty"`
+	PasswordResetExpiresAt *time.Time `json:"passwordResetExpiresAt,omitempty"`
+
+	// Two-factor authentication. The secret is only used once MFAEnabledAt is set; enrollment keeps the
+	// unconfirmed secret in MFAPendingSecret. Recovery codes are stored as SHA-256 hashes.
+	MFASecret        string     `json:"mfaSecret,omitempty"`
+	MFAPendingSecret string     `json:"mfaPendingSecret,omitempty"`
+	MFAEnabledAt     *time.Time `json:"mfaEnabledAt,omitempty"`
+	MFARecoveryCodes []string   `json:"mfaRecoveryCodes,omitempty"`
+	MFALastStep      int64      `json:"mfaLastStep,omitempty"` // Time step of the last accepted code, so codes cannot be replayed
+
+	// OIDCSubject links the account to the university identity provider (the "sub" claim). Accounts created
+	// through single sign-on have no password.
+	OIDCSubject string `json:"oidcSubject,omitempty"`
+
+	// Suspension is set while an admin has suspended the account. Suspended users cannot log in or use their tokens.
+	Suspension *Restriction `json:"suspension,omitempty"`
+	// Bans keep the user from single actions, keyed by RestrictionPosting or RestrictionLiking
+	Bans map[string]*Restriction `json:"bans,omitempty"`
+
+	ETag string `json:"_etag,omitempty"` // Cosmos DB ETag used for optimistic concurrency
 }
 
+// Restriction is a limit an admin put on an account
+type Restriction struct {
+	Reason    string     `json:"reason,omitempty"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Nil until lifted by an admin
+}
+
+// Active reports whether the restriction applies at now. A nil restriction never applies.
+func (r *Restriction) Active(now time.Time) bool {
+	return r != nil && (r.ExpiresAt == nil || now.Before(*r.ExpiresAt))
+}
+
+// Restriction kinds
 const (
-	RoleStudent string = "student"
-	RoleAdmin   string = "admin"
+	RestrictionSuspension = "suspension"
+	RestrictionPosting    = "posting" // May not create complaints
+	RestrictionLiking     = "liking"  // May not like complaints
 )
+
+// ValidBan reports whether kind is a restriction that can be applied as a ban
+func ValidBan(kind string) bool {
+	return kind == RestrictionPosting || kind == RestrictionLiking
+}
+
+// EmailVerified reports whether the user's email address is verified
+func (u *User) EmailVerified() bool {
+	return !u.Unverified
+}
+
+// Suspended reports whether an admin has suspended the account and the suspension has not expired
+func (u *User) Suspended() bool {
+	return u.Suspension.Active(time.Now())
+}
+
+// ActiveBan returns the ban of the given kind if it applies at now, otherwise nil
+func (u *User) ActiveBan(kind string, now time.Time) *Restriction {
+	if ban := u.Bans[kind]; ban.Active(now) {
+		return ban
+	}
+	return nil
+}
+
+// MFAEnabled reports whether the user has confirmed two-factor authentication
+func (u *User) MFAEnabled() bool {
+	return u.MFAEnabledAt != nil && u.MFASecret != ""
+}
+
+const (
+	RoleStudent   string = "student"
+	RoleAdmin     string = "admin"
+	RoleModerator string = "moderator" // Approves and rejects complaints
+	RoleStaff     string = "staff"     // Department staff, limited to their complaint categories
+	RoleAuditor   string = "auditor"   // Read-only access to complaints and users
+)
+
+// DeletedUserID replaces the ID of a deleted user on the complaints and comments that are kept
+const DeletedUserID = "deleted-user"
+
+// ValidRole reports whether role is one of the Role constants
+func ValidRole(role string) bool {
+	switch role {
+	case RoleStudent, RoleAdmin, RoleModerator, RoleStaff, RoleAuditor:
+		return true
+	}
+	return false
+}
@@ -193,3 +193,34 @@ func TestEmptyUserValidation(t *testing.T) {
 		t.Error("expected empty Role")
 	}
 }
+
+func TestUserRestrictions(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		user      User
+		suspended bool
+		banned    bool
+	}{
+		{name: "no restrictions", user: User{}},
+		{name: "permanent suspension", user: User{Suspension: &Restriction{}}, suspended: true},
+		{name: "expired suspension", user: User{Suspension: &Restriction{ExpiresAt: &past}}},
+		{name: "temporary posting ban", user: User{Bans: map[string]*Restriction{RestrictionPosting: {ExpiresAt: &future}}}, banned: true},
+		{name: "expired posting ban", user: User{Bans: map[string]*Restriction{RestrictionPosting: {ExpiresAt: &past}}}},
+		{name: "liking ban only", user: User{Bans: map[string]*Restriction{RestrictionLiking: {}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.Suspended(); got != tt.suspended {
+				t.Errorf("Suspended() = %v, want %v", got, tt.suspended)
+			}
+			if got := tt.user.ActiveBan(RestrictionPosting, now) != nil; got != tt.banned {
+				t.Errorf("ActiveBan(posting) != nil = %v, want %v", got, tt.banned)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookSubscription is an admin-registered endpoint that receives complaint events
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"` // Event types to deliver; empty means all events
+	Secret    string    `json:"secret"` // Key for the HMAC-SHA256 signature of each delivery
+	Active    bool      `json:"active"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WebhookDelivery is one event queued for, or delivered to, a webhook subscription or a user's personal webhook
+type WebhookDelivery struct {
+	ID             string           `json:"id"`
+	SubscriptionID string           `json:"subscriptionId"` // UserWebhookID of the user for personal webhooks
+	URL            string           `json:"url,omitempty"`  // Set for personal webhooks, which have no subscription
+	Event          string           `json:"event"`
+	Payload        string           `json:"payload"` // JSON body sent as-is, so the signature stays stable across retries
+	Status         string           `json:"status"`
+	AttemptCount   int              `json:"attemptCount"` // Attempts since the delivery was last queued, drives the backoff
+	Attempts       []WebhookAttempt `json:"attempts,omitempty"`
+	NextAttemptAt  time.Time        `json:"nextAttemptAt"`
+	LockedUntil    time.Time        `json:"lockedUntil,omitempty"` // Lease held by the worker currently delivering
+	CreatedAt      time.Time        `json:"createdAt"`
+	DeliveredAt    time.Time        `json:"deliveredAt,omitempty"`
+	ETag           string           `json:"_etag,omitempty"` // Cosmos DB ETag used for optimistic concurrency
+}
+
+// UserWebhookID returns the subscription ID under which a user's personal webhook deliveries are stored
+func UserWebhookID(userID string) string {
+	return userWebhookPrefix + userID
+}
+
+// UserIDFromWebhookID returns the user whose personal webhook deliveries are stored under the subscription ID
+func UserIDFromWebhookID(subscriptionID string) (string, bool) {
+	return strings.CutPrefix(subscriptionID, userWebhookPrefix)
+}
+
+const userWebhookPrefix = "user:"
+
+// WebhookAttempt records the outcome of one delivery attempt
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Webhook event types
+const (
+	WebhookEventComplaintCreated       string = "complaint.created"
+	WebhookEventComplaintStatusChanged string = "complaint.status_changed"
+	WebhookEventComplaintDeleted       string = "complaint.deleted"
+	WebhookEventComplaintLiked         string = "complaint.liked"
+)
+
+// WebhookEventTypes lists every event type subscriptions can filter on
+var WebhookEventTypes = []string{
+	WebhookEventComplaintCreated,
+	WebhookEventComplaintStatusChanged,
+	WebhookEventComplaintDeleted,
+	WebhookEventComplaintLiked,
+}
+
+const (
+	WebhookDeliveryPending    string = "pending"
+	WebhookDeliveryDelivering string = "delivering"
+	WebhookDeliverySucceeded  string = "succeeded"
+	WebhookDeliveryFailed     string = "failed"
+)
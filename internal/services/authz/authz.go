@@ -0,0 +1,101 @@
+// Package authz maps roles to permissions, optionally scoped to complaint categories
+package authz
+
+import (
+	"slices"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// Permission is an action on a kind of resource
+type Permission string
+
+const (
+	ComplaintsRead     Permission = "complaints:read"     // List the complaints of all users
+	ComplaintsModerate Permission = "complaints:moderate" // Approve, reject and comment on complaints
+	ComplaintsDelete   Permission = "complaints:delete"   // Delete complaints of other users
+	UsersRead          Permission = "users:read"          // View accounts
+	UsersManage        Permission = "users:manage"        // Change roles, suspend and unlock accounts, manage the registration denylist
+	AuditRead          Permission = "audit:read"          // Read the audit log of admin actions
+	WebhooksManage     Permission = "webhooks:manage"     // Manage outgoing webhook subscriptions
+)
+
+// Grant gives a role a permission
+type Grant struct {
+	Permission Permission
+	Scoped     bool // Only for complaints in the categories assigned to the user
+}
+
+// roleGrants are the permissions of each role. Students have none: they only act on their own complaints.
+var roleGrants = map[string][]Grant{
+	models.RoleAdmin: {
+		{Permission: ComplaintsRead},
+		{Permission: ComplaintsModerate},
+		{Permission: ComplaintsDelete},
+		{Permission: UsersRead},
+		{Permission: UsersManage},
+		{Permission: AuditRead},
+		{Permission: WebhooksManage},
+	},
+	models.RoleModerator: {
+		{Permission: ComplaintsRead},
+		{Permission: ComplaintsModerate},
+	},
+	models.RoleStaff: {
+		{Permission: ComplaintsRead, Scoped: true},
+		{Permission: ComplaintsModerate, Scoped: true},
+	},
+	models.RoleAuditor: {
+		{Permission: ComplaintsRead},
+		{Permission: UsersRead},
+		{Permission: AuditRead},
+	},
+}
+
+// Has reports whether the role has the permission, in any scope
+func Has(role string, permission Permission) bool {
+	_, ok := grant(role, permission)
+	return ok
+}
+
+// Allows reports whether a user with the role and categories has the permission for a complaint in category
+func Allows(role string, categories []string, permission Permission, category string) bool {
+	g, ok := grant(role, permission)
+	if !ok {
+		return false
+	}
+	return !g.Scoped || (category != "" && slices.Contains(categories, category))
+}
+
+// Scope returns the categories in which a user with the role and categories has the permission.
+// all is true when the permission is not limited to categories; categories is empty when it is not granted.
+func Scope(role string, categories []string, permission Permission) (all bool, allowed []string) {
+	g, ok := grant(role, permission)
+	if !ok {
+		return false, nil
+	}
+	if !g.Scoped {
+		return true, nil
+	}
+	return false, categories
+}
+
+// Privileged reports whether the role has any permission beyond a student's
+func Privileged(role string) bool {
+	return len(roleGrants[role]) > 0
+}
+
+// Permissions returns the permissions of the role
+func Permissions(role string) []Grant {
+	return slices.Clone(roleGrants[role])
+}
+
+// grant returns the role's grant of the permission
+func grant(role string, permission Permission) (Grant, bool) {
+	for _, g := range roleGrants[role] {
+		if g.Permission == permission {
+			return g, true
+		}
+	}
+	return Grant{}, false
+}
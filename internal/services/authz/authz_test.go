@@ -0,0 +1,69 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHas(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission Permission
+		want       bool
+	}{
+		{models.RoleAdmin, UsersManage, true},
+		{models.RoleAdmin, ComplaintsDelete, true},
+		{models.RoleModerator, ComplaintsModerate, true},
+		{models.RoleModerator, UsersManage, false},
+		{models.RoleStaff, ComplaintsModerate, true},
+		{models.RoleStaff, ComplaintsDelete, false},
+		{models.RoleAuditor, ComplaintsRead, true},
+		{models.RoleAuditor, ComplaintsModerate, false},
+		{models.RoleAuditor, AuditRead, true},
+		{models.RoleModerator, AuditRead, false},
+		{models.RoleStudent, ComplaintsRead, false},
+		{"unknown", ComplaintsRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+" "+string(tt.permission), func(t *testing.T) {
+			assert.Equal(t, tt.want, Has(tt.role, tt.permission))
+		})
+	}
+}
+
+func TestAllowsScopedToCategories(t *testing.T) {
+	facilities := []string{"facilities"}
+
+	assert.True(t, Allows(models.RoleStaff, facilities, ComplaintsModerate, "facilities"))
+	assert.False(t, Allows(models.RoleStaff, facilities, ComplaintsModerate, "it"))
+	assert.False(t, Allows(models.RoleStaff, facilities, ComplaintsModerate, ""), "uncategorized complaints are out of every scope")
+	assert.False(t, Allows(models.RoleStaff, nil, ComplaintsRead, "facilities"))
+
+	// Unscoped grants ignore the user's categories
+	assert.True(t, Allows(models.RoleModerator, nil, ComplaintsModerate, "it"))
+	assert.True(t, Allows(models.RoleAdmin, facilities, ComplaintsModerate, ""))
+	assert.False(t, Allows(models.RoleAuditor, nil, ComplaintsModerate, "it"))
+}
+
+func TestScope(t *testing.T) {
+	all, categories := Scope(models.RoleAdmin, []string{"it"}, ComplaintsRead)
+	assert.True(t, all)
+	assert.Empty(t, categories)
+
+	all, categories = Scope(models.RoleStaff, []string{"it", "housing"}, ComplaintsRead)
+	assert.False(t, all)
+	assert.Equal(t, []string{"it", "housing"}, categories)
+
+	all, categories = Scope(models.RoleStudent, []string{"it"}, ComplaintsRead)
+	assert.False(t, all)
+	assert.Empty(t, categories)
+}
+
+func TestPrivileged(t *testing.T) {
+	assert.True(t, Privileged(models.RoleAdmin))
+	assert.True(t, Privileged(models.RoleAuditor))
+	assert.False(t, Privileged(models.RoleStudent))
+}
@@ -0,0 +1,210 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// GetComplaintsInvolvingUser retrieves the complaints of other users that the user liked or commented on
+func (s *Service) GetComplaintsInvolvingUser(ctx context.Context, userID string) ([]models.Complaint, error) {
+	query := "SELECT * FROM c WHERE c.userId != @userId AND (ARRAY_CONTAINS(c.likes, @userId) OR " +
+		"EXISTS(SELECT VALUE comment FROM comment IN c.comments WHERE comment.adminId = @userId))"
+	return s.queryComplaints(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@userId", Value: userID},
+	})
+}
+
+// ReplaceComplaint stores a changed complaint
+func (s *Service) ReplaceComplaint(ctx context.Context, complaint *models.Complaint) error {
+	containerClient, err := s.client.NewContainer(s.database, s.complaintsContainer)
+	if err != nil {
+		s.log.Error("failed to get complaints container", slog.String("error", err.Error()))
+		return err
+	}
+
+	complaintBytes, err := json.Marshal(complaint)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(complaint.UserID)
+	if _, err := containerClient.ReplaceItem(ctx, partitionKey, complaint.ID, complaintBytes, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrComplaintNotFound
+		}
+		s.log.Error("failed to replace complaint", slog.String("complaintId", complaint.ID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// ReassignComplaint moves a complaint to another owner. The owner is the partition key, so the complaint is
+// created in the new partition before it is deleted from the old one.
+func (s *Service) ReassignComplaint(ctx context.Context, complaint *models.Complaint, userID string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.complaintsContainer)
+	if err != nil {
+		s.log.Error("failed to get complaints container", slog.String("error", err.Error()))
+		return err
+	}
+
+	oldPartitionKey := azcosmos.NewPartitionKeyString(complaint.UserID)
+	moved := *complaint
+	moved.UserID = userID
+	complaintBytes, err := json.Marshal(&moved)
+	if err != nil {
+		return err
+	}
+
+	// Upsert, so a move that failed after this step can be repeated
+	if _, err := containerClient.UpsertItem(ctx, azcosmos.NewPartitionKeyString(userID), complaintBytes, nil); err != nil {
+		s.log.Error("failed to create reassigned complaint", slog.String("complaintId", complaint.ID), slog.String("error", err.Error()))
+		return err
+	}
+	if _, err := containerClient.DeleteItem(ctx, oldPartitionKey, complaint.ID, nil); err != nil && !isStatusCode(err, http.StatusNotFound) {
+		s.log.Error("failed to delete reassigned complaint", slog.String("complaintId", complaint.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	*complaint = moved
+	return nil
+}
+
+// DeleteUserNotifications removes every in-app notification and pending digest item of a user
+func (s *Service) DeleteUserNotifications(ctx context.Context, userID string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.notificationsContainer)
+	if err != nil {
+		s.log.Error("failed to get notifications container", slog.String("error", err.Error()))
+		return err
+	}
+
+	notifications, err := s.GetNotifications(ctx, userID, false)
+	if err != nil {
+		return err
+	}
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	for _, notification := range notifications {
+		if _, err := containerClient.DeleteItem(ctx, partitionKey, notification.ID, nil); err != nil && !isStatusCode(err, http.StatusNotFound) {
+			s.log.Error("failed to delete notification", slog.String("userId", userID), slog.String("error", err.Error()))
+			return err
+		}
+	}
+
+	items, err := s.GetDigestItems(ctx, userID, time.Now().UTC().Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	return s.DeleteDigestItems(ctx, items)
+}
+
+// DeleteOutboxEmailsTo removes the outbox emails addressed to any of the addresses, sent or not.
+// Their bodies and recipient are personal data, and the recipient no longer has an account to email.
+func (s *Service) DeleteOutboxEmailsTo(ctx context.Context, addresses ...string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.emailOutboxContainer)
+	if err != nil {
+		s.log.Error("failed to get email outbox container", slog.String("error", err.Error()))
+		return err
+	}
+
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+		queryOptions := &azcosmos.QueryOptions{
+			QueryParameters: []azcosmos.QueryParameter{
+				{Name: "@to", Value: address},
+			},
+		}
+		// Cross-partition query, emails are partitioned by their ID.
+		pager := containerClient.NewQueryItemsPager("SELECT VALUE c.id FROM c WHERE c.to = @to", azcosmos.PartitionKey{}, queryOptions)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				s.log.Error("failed to query outbox emails", slog.String("error", err.Error()))
+				return err
+			}
+			for _, item := range page.Items {
+				var id string
+				if err := json.Unmarshal(item, &id); err != nil {
+					return err
+				}
+				if _, err := containerClient.DeleteItem(ctx, azcosmos.NewPartitionKeyString(id), id, nil); err != nil && !isStatusCode(err, http.StatusNotFound) {
+					s.log.Error("failed to delete outbox email", slog.String("emailId", id), slog.String("error", err.Error()))
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteUserWebhookDeliveries removes the deliveries to a user's personal webhook, sent or not
+func (s *Service) DeleteUserWebhookDeliveries(ctx context.Context, userID string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.deliveriesContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook deliveries container", slog.String("error", err.Error()))
+		return err
+	}
+
+	subscriptionID := models.UserWebhookID(userID)
+	partitionKey := azcosmos.NewPartitionKeyString(subscriptionID)
+	pager := containerClient.NewQueryItemsPager("SELECT VALUE c.id FROM c", partitionKey, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query webhook deliveries", slog.String("userId", userID), slog.String("error", err.Error()))
+			return err
+		}
+		for _, item := range page.Items {
+			var id string
+			if err := json.Unmarshal(item, &id); err != nil {
+				return err
+			}
+			if _, err := containerClient.DeleteItem(ctx, partitionKey, id, nil); err != nil && !isStatusCode(err, http.StatusNotFound) {
+				s.log.Error("failed to delete webhook delivery", slog.String("userId", userID), slog.String("deliveryId", id), slog.String("error", err.Error()))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteNotificationPreferences removes a user's notification preferences, if they saved any
+func (s *Service) DeleteNotificationPreferences(ctx context.Context, userID string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.preferencesContainer)
+	if err != nil {
+		s.log.Error("failed to get notification preferences container", slog.String("error", err.Error()))
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	if _, err := containerClient.DeleteItem(ctx, partitionKey, userID, nil); err != nil && !isStatusCode(err, http.StatusNotFound) {
+		s.log.Error("failed to delete notification preferences", slog.String("userId", userID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// DeleteUser removes a user document
+func (s *Service) DeleteUser(ctx context.Context, userID string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.usersContainer)
+	if err != nil {
+		s.log.Error("failed to get users container", slog.String("error", err.Error()))
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	if _, err := containerClient.DeleteItem(ctx, partitionKey, userID, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrUserNotFound
+		}
+		s.log.Error("failed to delete user", slog.String("userId", userID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cosmos
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// GetComplaintsCreatedBetween retrieves complaints created in [from, to), newest first
+func (s *Service) GetComplaintsCreatedBetween(ctx context.Context, from, to time.Time) ([]models.Complaint, error) {
+	query := "SELECT * FROM c WHERE c.createdAt >= @from AND c.createdAt < @to"
+	complaints, err := s.queryComplaints(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@from", Value: from.UTC().Format(time.RFC3339Nano)},
+		{Name: "@to", Value: to.UTC().Format(time.RFC3339Nano)},
+	})
+	slices.SortFunc(complaints, func(a, b models.Complaint) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return complaints, err
+}
+
+// GetComplaintsStatusChangedBetween retrieves complaints whose status changed in [from, to), newest change first
+func (s *Service) GetComplaintsStatusChangedBetween(ctx context.Context, from, to time.Time) ([]models.Complaint, error) {
+	query := "SELECT * FROM c WHERE c.statusChangedAt >= @from AND c.statusChangedAt < @to"
+	complaints, err := s.queryComplaints(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@from", Value: from.UTC().Format(time.RFC3339Nano)},
+		{Name: "@to", Value: to.UTC().Format(time.RFC3339Nano)},
+	})
+	slices.SortFunc(complaints, func(a, b models.Complaint) int { return b.StatusChangedAt.Compare(a.StatusChangedAt) })
+	return complaints, err
+}
+
+// GetPendingComplaintsCreatedBefore retrieves pending complaints created before the cutoff, oldest first
+func (s *Service) GetPendingComplaintsCreatedBefore(ctx context.Context, before time.Time) ([]models.Complaint, error) {
+	query := "SELECT * FROM c WHERE c.status = @status AND c.createdAt < @before"
+	complaints, err := s.queryComplaints(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@status", Value: models.StatusPending},
+		{Name: "@before", Value: before.UTC().Format(time.RFC3339Nano)},
+	})
+	slices.SortFunc(complaints, func(a, b models.Complaint) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return complaints, err
+}
+
+// GetMostLikedPendingComplaints retrieves the pending complaints with the most likes
+func (s *Service) GetMostLikedPendingComplaints(ctx context.Context, limit int) ([]models.Complaint, error) {
+	query := "SELECT * FROM c WHERE c.status = @status AND c.likeCount > 0"
+	complaints, err := s.queryComplaints(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@status", Value: models.StatusPending},
+	})
+	slices.SortFunc(complaints, func(a, b models.Complaint) int { return cmp.Compare(b.LikeCount, a.LikeCount) })
+	if len(complaints) > limit {
+		complaints = complaints[:limit]
+	}
+	return complaints, err
+}
+
+// queryComplaints runs a cross-partition complaints query.
+// The gateway does not serve cross-partition TOP and ORDER BY, so callers sort and limit the results themselves.
+func (s *Service) queryComplaints(ctx context.Context, query string, params []azcosmos.QueryParameter) ([]models.Complaint, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.complaintsContainer)
+	if err != nil {
+		s.log.Error("failed to get complaints container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: params,
+	}
+
+	// Cross-partition query across all users.
+	pager := containerClient.NewQueryItemsPager(query, azcosmos.PartitionKey{}, queryOptions)
+
+	var complaints []models.Complaint
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query complaints", slog.String("query", query), slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var complaint models.Complaint
+			if err := json.Unmarshal(item, &complaint); err != nil {
+				s.log.Error("failed to unmarshal complaint", slog.String("error", err.Error()))
+				return nil, err
+			}
+			complaints = append(complaints, complaint)
+		}
+	}
+
+	return complaints, nil
+}
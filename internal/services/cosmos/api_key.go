@@ -0,0 +1,145 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// CreateAPIKey stores a personal API key. Cosmos DB deletes it once it has expired.
+func (s *Service) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	containerClient, err := s.client.NewContainer(s.database, s.apiKeysContainer)
+	if err != nil {
+		s.log.Error("failed to get API keys container", slog.String("error", err.Error()))
+		return err
+	}
+
+	key.TTL = ttlUntil(key.ExpiresAt)
+	keyBytes, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(key.ID)
+	if _, err := containerClient.CreateItem(ctx, partitionKey, keyBytes, nil); err != nil {
+		s.log.Error("failed to create API key", slog.String("userId", key.UserID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetAPIKey retrieves an API key by the key hash, or nil if it does not exist
+func (s *Service) GetAPIKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.apiKeysContainer)
+	if err != nil {
+		s.log.Error("failed to get API keys container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(hash)
+	response, err := containerClient.ReadItem(ctx, partitionKey, hash, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, nil // not found
+		}
+		s.log.Error("failed to read API key", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var key models.APIKey
+	if err := json.Unmarshal(response.Value, &key); err != nil {
+		s.log.Error("failed to unmarshal API key", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetUserAPIKeys retrieves the API keys of a user, newest first
+func (s *Service) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.apiKeysContainer)
+	if err != nil {
+		s.log.Error("failed to get API keys container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.userId = @userId"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@userId", Value: userID},
+		},
+	}
+
+	// Cross-partition query, keys are partitioned by their hash. The gateway does not serve cross-partition ORDER BY,
+	// so the keys are sorted below.
+	pager := containerClient.NewQueryItemsPager(query, azcosmos.PartitionKey{}, queryOptions)
+
+	keys := []models.APIKey{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query API keys", slog.String("userId", userID), slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var key models.APIKey
+			if err := json.Unmarshal(item, &key); err != nil {
+				s.log.Error("failed to unmarshal API key", slog.String("error", err.Error()))
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	slices.SortFunc(keys, func(a, b models.APIKey) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return keys, nil
+}
+
+// TouchAPIKey stores when and from where the key was last used. Concurrent requests may overwrite each other.
+func (s *Service) TouchAPIKey(ctx context.Context, key *models.APIKey) error {
+	containerClient, err := s.client.NewContainer(s.database, s.apiKeysContainer)
+	if err != nil {
+		s.log.Error("failed to get API keys container", slog.String("error", err.Error()))
+		return err
+	}
+
+	key.TTL = ttlUntil(key.ExpiresAt)
+	keyBytes, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(key.ID)
+	if _, err := containerClient.ReplaceItem(ctx, partitionKey, key.ID, keyBytes, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil // Revoked meanwhile
+		}
+		s.log.Error("failed to update API key", slog.String("userId", key.UserID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// DeleteAPIKey revokes an API key by its hash
+func (s *Service) DeleteAPIKey(ctx context.Context, hash string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.apiKeysContainer)
+	if err != nil {
+		s.log.Error("failed to get API keys container", slog.String("error", err.Error()))
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(hash)
+	if _, err := containerClient.DeleteItem(ctx, partitionKey, hash, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		s.log.Error("failed to delete API key", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// CreateAuditEntry appends an entry to the audit log
+func (s *Service) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	containerClient, err := s.client.NewContainer(s.database, s.auditLogContainer)
+	if err != nil {
+		s.log.Error("failed to get audit log container", slog.String("error", err.Error()))
+		return err
+	}
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(entry.TargetID)
+	if _, err := containerClient.CreateItem(ctx, partitionKey, entryBytes, nil); err != nil {
+		s.log.Error("failed to create audit entry", slog.String("action", entry.Action), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetAuditEntries retrieves the newest audit log entries, optionally only those about targetID or with the given action
+func (s *Service) GetAuditEntries(ctx context.Context, targetID, action string, limit int) ([]models.AuditEntry, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.auditLogContainer)
+	if err != nil {
+		s.log.Error("failed to get audit log container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	query := "SELECT * FROM c"
+	conditions := []string{}
+	params := []azcosmos.QueryParameter{}
+	partitionKey := azcosmos.PartitionKey{} // Cross-partition query unless filtered by target
+	if targetID != "" {
+		conditions = append(conditions, "c.targetId = @targetId")
+		params = append(params, azcosmos.QueryParameter{Name: "@targetId", Value: targetID})
+		partitionKey = azcosmos.NewPartitionKeyString(targetID)
+	}
+	if action != "" {
+		conditions = append(conditions, "c.action = @action")
+		params = append(params, azcosmos.QueryParameter{Name: "@action", Value: action})
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// The gateway does not serve cross-partition TOP and ORDER BY, so only a single target is sorted and limited here
+	if targetID != "" {
+		query += " ORDER BY c.createdAt DESC OFFSET 0 LIMIT @limit"
+		params = append(params, azcosmos.QueryParameter{Name: "@limit", Value: limit})
+	}
+
+	pager := containerClient.NewQueryItemsPager(query, partitionKey, &azcosmos.QueryOptions{QueryParameters: params})
+
+	entries := []models.AuditEntry{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query audit log", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var entry models.AuditEntry
+			if err := json.Unmarshal(item, &entry); err != nil {
+				s.log.Error("failed to unmarshal audit entry", slog.String("error", err.Error()))
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b models.AuditEntry) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
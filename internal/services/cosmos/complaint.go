@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
@@ -27,6 +28,7 @@ func ToComplaintResponse(complaint *models.Complaint, currentUserID string) *mod
 		ID:          complaint.ID,
 		UserID:      complaint.UserID,
 		Description: complaint.Description,
+		Category:    complaint.Category,
 		Status:      complaint.Status,
 		Comments:    complaint.Comments,
 		LikeCount:   complaint.LikeCount,
@@ -182,12 +184,13 @@ func (s *Service) UpdateComplaintStatus(ctx context.Context, id, status string)
 	return nil
 }
 
-// UpdateComplaintStatusWithComment updates the status of a complaint and optionally adds a comment from an admin
-func (s *Service) UpdateComplaintStatusWithComment(ctx context.Context, id, status, comment, adminID string) error {
+// UpdateComplaintStatusWithComment updates the status of a complaint and optionally adds a comment from an admin.
+// It returns the updated complaint, or nil if the complaint does not exist.
+func (s *Service) UpdateComplaintStatusWithComment(ctx context.Context, id, status, comment, adminID string) (*models.Complaint, error) {
 	containerClient, err := s.client.NewContainer(s.database, s.complaintsContainer)
 	if err != nil {
 		s.log.Error("failed to get complaints container", slog.String("error", err.Error()))
-		return err
+		return nil, err
 	}
 
 	// First, find the complaint to get the partition key (userId)
@@ -205,14 +208,14 @@ func (s *Service) UpdateComplaintStatusWithComment(ctx context.Context, id, stat
 		page, err := pager.NextPage(ctx)
 		if err != nil {
 			s.log.Error("failed to query complaint for update", slog.String("complaintId", id), slog.String("error", err.Error()))
-			return err
+			return nil, err
 		}
 
 		for _, item := range page.Items {
 			var c models.Complaint
 			if err := json.Unmarshal(item, &c); err != nil {
 				s.log.Error("failed to unmarshal complaint", slog.String("complaintId", id), slog.String("error", err.Error()))
-				return err
+				return nil, err
 			}
 			complaint = &c
 			break
@@ -224,14 +227,16 @@ func (s *Service) UpdateComplaintStatusWithComment(ctx context.Context, id, stat
 
 	if complaint == nil {
 		s.log.Debug("complaint not found for update", slog.String("complaintId", id))
-		return nil // complaint not found
+		return nil, nil // complaint not found
 	}
 
 	// Update the status
+	now := time.Now().UTC()
 	oldStatus := complaint.Status
 	complaint.Status = status
+	complaint.StatusChangedAt = now
 
-	// Add comment if provided
+	// Add comment if provided (it shares the status change timestamp so notifications can pair them)
 	if comment != "" {
 		if complaint.Comments == nil {
 			complaint.Comments = []models.Comment{}
@@ -240,7 +245,7 @@ func (s *Service) UpdateComplaintStatusWithComment(ctx context.Context, id, stat
 			ID:        uuid.New().String(),
 			AdminID:   adminID,
 			Content:   comment,
-			CreatedAt: time.Now(),
+			CreatedAt: now,
 		}
 		complaint.Comments = append(complaint.Comments, newComment)
 	}
@@ -249,7 +254,7 @@ func (s *Service) UpdateComplaintStatusWithComment(ctx context.Context, id, stat
 	complaintBytes, err := json.Marshal(complaint)
 	if err != nil {
 		s.log.Error("failed to marshal updated complaint", slog.String("complaintId", id), slog.String("error", err.Error()))
-		return err
+		return nil, err
 	}
 
 	// Replace the item using the partition key
@@ -257,7 +262,7 @@ func (s *Service) UpdateComplaintStatusWithComment(ctx context.Context, id, stat
 	_, err = containerClient.ReplaceItem(ctx, partitionKey, id, complaintBytes, nil)
 	if err != nil {
 		s.log.Error("failed to update complaint status in cosmos", slog.String("complaintId", id), slog.String("error", err.Error()))
-		return err
+		return nil, err
 	}
 
 	if comment != "" {
@@ -265,7 +270,7 @@ func (s *Service) UpdateComplaintStatusWithComment(ctx context.Context, id, stat
 	} else {
 		s.log.Info("complaint status updated", slog.String("complaintId", id), slog.String("oldStatus", oldStatus), slog.String("newStatus", status))
 	}
-	return nil
+	return complaint, nil
 }
 
 // GetComplaintByID retrieves a single complaint by its ID
@@ -307,8 +312,9 @@ func (s *Service) GetComplaintByID(ctx context.Context, id string) (*models.Comp
 	return nil, nil
 }
 
-// GetAllComplaints retrieves all complaints, optionally filtered by status.
-func (s *Service) GetAllComplaints(ctx context.Context, status string) ([]models.Complaint, error) {
+// GetAllComplaints retrieves all complaints, optionally filtered by status. A non-nil categories limits the
+// result to complaints in these categories.
+func (s *Service) GetAllComplaints(ctx context.Context, status string, categories []string) ([]models.Complaint, error) {
 	containerClient, err := s.client.NewContainer(s.database, s.complaintsContainer)
 	if err != nil {
 		s.log.Error("failed to get complaints container", slog.String("error", err.Error()))
@@ -316,14 +322,20 @@ func (s *Service) GetAllComplaints(ctx context.Context, status string) ([]models
 	}
 
 	query := "SELECT * FROM c"
-	var queryOptions *azcosmos.QueryOptions
+	var conditions []string
+	var params []azcosmos.QueryParameter
 	if status != "" {
-		query = "SELECT * FROM c WHERE c.status = @status"
-		queryOptions = &azcosmos.QueryOptions{
-			QueryParameters: []azcosmos.QueryParameter{
-				{Name: "@status", Value: status},
-			},
-		}
+		conditions = append(conditions, "c.status = @status")
+		params = append(params, azcosmos.QueryParameter{Name: "@status", Value: status})
+	}
+	if categories != nil {
+		conditions = append(conditions, "ARRAY_CONTAINS(@categories, c.category)")
+		params = append(params, azcosmos.QueryParameter{Name: "@categories", Value: categories})
+	}
+	var queryOptions *azcosmos.QueryOptions
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+		queryOptions = &azcosmos.QueryOptions{QueryParameters: params}
 	}
 
 	// Cross-partition query across all users.
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"log/slog"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 )
 
@@ -14,14 +15,38 @@ var (
 	ErrUsernameAlreadyExists = errors.New("user with this username already exists")
 	ErrUserNotFound          = errors.New("user not found")
 	ErrComplaintNotFound     = errors.New("complaint not found")
+	ErrEmailAlreadyQueued    = errors.New("email already queued")
+	ErrConcurrentUpdate      = errors.New("item was modified concurrently")
+	ErrNotificationNotFound  = errors.New("notification not found")
+	ErrWebhookNotFound       = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound      = errors.New("webhook delivery not found")
+	ErrDeliveryAlreadyQueued = errors.New("webhook delivery already queued")
+	ErrSigningKeyExists      = errors.New("signing key already exists")
+	ErrDeniedEmailNotFound   = errors.New("email is not on the denylist")
+	ErrAPIKeyNotFound        = errors.New("API key not found")
+	ErrSessionNotFound       = errors.New("session not found")
 )
 
 type Service struct {
-	client              *azcosmos.Client
-	database            string
-	usersContainer      string
-	complaintsContainer string
-	log                 *slog.Logger
+	client                 *azcosmos.Client
+	database               string
+	usersContainer         string
+	complaintsContainer    string
+	emailOutboxContainer   string
+	notificationsContainer string
+	preferencesContainer   string
+	digestItemsContainer   string
+	webhooksContainer      string
+	deliveriesContainer    string
+	refreshTokensContainer string
+	revokedTokensContainer string
+	signingKeysContainer   string
+	denylistContainer      string
+	loginAttemptsContainer string
+	auditLogContainer      string
+	apiKeysContainer       string
+	sessionsContainer      string
+	log                    *slog.Logger
 }
 
 // NewCosmosService creates a new CosmosService with the given endpoint, key, and database
@@ -43,14 +68,34 @@ func NewCosmosService(endpoint, key, database string, log *slog.Logger) (*Servic
 	log.Info("cosmos DB service initialized", slog.String("database", database))
 
 	return &Service{
-		client:              client,
-		database:            database,
-		usersContainer:      "users",
-		complaintsContainer: "complaints",
-		log:                 log,
+		client:                 client,
+		database:               database,
+		usersContainer:         "users",
+		complaintsContainer:    "complaints",
+		emailOutboxContainer:   "email-outbox",
+		notificationsContainer: "notifications",
+		preferencesContainer:   "notification-preferences",
+		digestItemsContainer:   "digest-items",
+		webhooksContainer:      "webhook-subscriptions",
+		deliveriesContainer:    "webhook-deliveries",
+		refreshTokensContainer: "refresh-tokens",
+		revokedTokensContainer: "revoked-tokens",
+		signingKeysContainer:   "signing-keys",
+		denylistContainer:      "email-denylist",
+		loginAttemptsContainer: "login-attempts",
+		auditLogContainer:      "audit-log",
+		apiKeysContainer:       "api-keys",
+		sessionsContainer:      "sessions",
+		log:                    log,
 	}, nil
 }
 
+// isStatusCode reports whether err is a Cosmos DB response error with the given HTTP status code
+func isStatusCode(err error, statusCode int) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == statusCode
+}
+
 // PublicServiceTest For testing only
 type PublicServiceTest struct {
 	Client              *azcosmos.Client
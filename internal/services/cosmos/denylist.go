@@ -0,0 +1,107 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// AddDeniedEmail puts an email address on the registration denylist. Adding it again replaces the entry.
+func (s *Service) AddDeniedEmail(ctx context.Context, entry *models.DeniedEmail) error {
+	containerClient, err := s.client.NewContainer(s.database, s.denylistContainer)
+	if err != nil {
+		s.log.Error("failed to get email denylist container", slog.String("error", err.Error()))
+		return err
+	}
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(entry.ID)
+	if _, err := containerClient.UpsertItem(ctx, partitionKey, entryBytes, nil); err != nil {
+		s.log.Error("failed to add denied email", slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Info("email added to denylist", slog.String("createdBy", entry.CreatedBy))
+	return nil
+}
+
+// RemoveDeniedEmail removes an email address from the registration denylist
+func (s *Service) RemoveDeniedEmail(ctx context.Context, email string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.denylistContainer)
+	if err != nil {
+		s.log.Error("failed to get email denylist container", slog.String("error", err.Error()))
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(email)
+	if _, err := containerClient.DeleteItem(ctx, partitionKey, email, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrDeniedEmailNotFound
+		}
+		s.log.Error("failed to remove denied email", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// IsEmailDenied reports whether the lower-case email address is on the registration denylist
+func (s *Service) IsEmailDenied(ctx context.Context, email string) (bool, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.denylistContainer)
+	if err != nil {
+		s.log.Error("failed to get email denylist container", slog.String("error", err.Error()))
+		return false, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(email)
+	if _, err := containerClient.ReadItem(ctx, partitionKey, email, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return false, nil
+		}
+		s.log.Error("failed to read denied email", slog.String("error", err.Error()))
+		return false, err
+	}
+	return true, nil
+}
+
+// GetDeniedEmails retrieves the whole registration denylist, newest first
+func (s *Service) GetDeniedEmails(ctx context.Context) ([]models.DeniedEmail, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.denylistContainer)
+	if err != nil {
+		s.log.Error("failed to get email denylist container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Cross-partition query across the denylist. The gateway does not serve cross-partition ORDER BY,
+	// so the entries are sorted below.
+	pager := containerClient.NewQueryItemsPager("SELECT * FROM c", azcosmos.PartitionKey{}, nil)
+
+	entries := []models.DeniedEmail{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query email denylist", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var entry models.DeniedEmail
+			if err := json.Unmarshal(item, &entry); err != nil {
+				s.log.Error("failed to unmarshal denied email", slog.String("error", err.Error()))
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b models.DeniedEmail) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return entries, nil
+}
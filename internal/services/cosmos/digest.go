@@ -0,0 +1,146 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/google/uuid"
+)
+
+// AddDigestItem stores a notification for the user's next digest email.
+// Items whose ID is derived from the triggering event are only stored once.
+func (s *Service) AddDigestItem(ctx context.Context, item *models.DigestItem) error {
+	// Auto-generate ID if not provided
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+
+	containerClient, err := s.client.NewContainer(s.database, s.digestItemsContainer)
+	if err != nil {
+		s.log.Error("failed to get digest items container", slog.String("error", err.Error()))
+		return err
+	}
+
+	itemBytes, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(item.UserID)
+	_, err = containerClient.CreateItem(ctx, partitionKey, itemBytes, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusConflict) {
+			return nil
+		}
+		s.log.Error("failed to add digest item", slog.String("userId", item.UserID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetDigestUserIDs returns the IDs of users with digest items created before the cutoff
+func (s *Service) GetDigestUserIDs(ctx context.Context, before time.Time) ([]string, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.digestItemsContainer)
+	if err != nil {
+		s.log.Error("failed to get digest items container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// The gateway does not serve cross-partition DISTINCT, so users with several items are deduplicated here
+	query := "SELECT VALUE c.userId FROM c WHERE c.createdAt < @before"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@before", Value: before.UTC().Format(time.RFC3339Nano)},
+		},
+	}
+
+	// Cross-partition query across all users.
+	pager := containerClient.NewQueryItemsPager(query, azcosmos.PartitionKey{}, queryOptions)
+
+	var userIDs []string
+	seen := make(map[string]bool)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query digest users", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var userID string
+			if err := json.Unmarshal(item, &userID); err != nil {
+				s.log.Error("failed to unmarshal digest user ID", slog.String("error", err.Error()))
+				return nil, err
+			}
+			if !seen[userID] {
+				seen[userID] = true
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+
+	return userIDs, nil
+}
+
+// GetDigestItems retrieves a user's digest items created before the cutoff, oldest first
+func (s *Service) GetDigestItems(ctx context.Context, userID string, before time.Time) ([]models.DigestItem, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.digestItemsContainer)
+	if err != nil {
+		s.log.Error("failed to get digest items container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.userId = @userId AND c.createdAt < @before ORDER BY c.createdAt"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@userId", Value: userID},
+			{Name: "@before", Value: before.UTC().Format(time.RFC3339Nano)},
+		},
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	pager := containerClient.NewQueryItemsPager(query, partitionKey, queryOptions)
+
+	var items []models.DigestItem
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query digest items", slog.String("userId", userID), slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var digestItem models.DigestItem
+			if err := json.Unmarshal(item, &digestItem); err != nil {
+				s.log.Error("failed to unmarshal digest item", slog.String("error", err.Error()))
+				return nil, err
+			}
+			items = append(items, digestItem)
+		}
+	}
+
+	return items, nil
+}
+
+// DeleteDigestItems removes digest items once they have been sent
+func (s *Service) DeleteDigestItems(ctx context.Context, items []models.DigestItem) error {
+	containerClient, err := s.client.NewContainer(s.database, s.digestItemsContainer)
+	if err != nil {
+		s.log.Error("failed to get digest items container", slog.String("error", err.Error()))
+		return err
+	}
+
+	for _, item := range items {
+		partitionKey := azcosmos.NewPartitionKeyString(item.UserID)
+		if _, err := containerClient.DeleteItem(ctx, partitionKey, item.ID, nil); err != nil && !isStatusCode(err, http.StatusNotFound) {
+			s.log.Error("failed to delete digest item", slog.String("userId", item.UserID), slog.String("itemId", item.ID), slog.String("error", err.Error()))
+			return err
+		}
+	}
+	return nil
+}
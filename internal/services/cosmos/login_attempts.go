@@ -0,0 +1,95 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// GetLoginAttempts retrieves the failed login record of an email address, or nil if there is none
+func (s *Service) GetLoginAttempts(ctx context.Context, email string) (*models.LoginAttempts, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.loginAttemptsContainer)
+	if err != nil {
+		s.log.Error("failed to get login attempts container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(email)
+	response, err := containerClient.ReadItem(ctx, partitionKey, email, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, nil // not found
+		}
+		s.log.Error("failed to read login attempts", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var attempts models.LoginAttempts
+	if err := json.Unmarshal(response.Value, &attempts); err != nil {
+		s.log.Error("failed to unmarshal login attempts", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+// SaveLoginAttempts stores the failed login record of an email address until keepUntil.
+// A record read before is only replaced if it did not change since, and a new one is only created if there is
+// none yet; otherwise ErrConcurrentUpdate is returned. On success the record's ETag is refreshed.
+func (s *Service) SaveLoginAttempts(ctx context.Context, attempts *models.LoginAttempts, keepUntil time.Time) error {
+	containerClient, err := s.client.NewContainer(s.database, s.loginAttemptsContainer)
+	if err != nil {
+		s.log.Error("failed to get login attempts container", slog.String("error", err.Error()))
+		return err
+	}
+
+	attempts.TTL = ttlUntil(keepUntil)
+	attemptsBytes, err := json.Marshal(attempts)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(attempts.ID)
+	var response azcosmos.ItemResponse
+	if attempts.ETag == "" {
+		response, err = containerClient.CreateItem(ctx, partitionKey, attemptsBytes, nil)
+	} else {
+		etag := azcore.ETag(attempts.ETag)
+		response, err = containerClient.ReplaceItem(ctx, partitionKey, attempts.ID, attemptsBytes, &azcosmos.ItemOptions{IfMatchEtag: &etag})
+	}
+	if err != nil {
+		// Created, changed or deleted by another request since it was read
+		if isStatusCode(err, http.StatusConflict) || isStatusCode(err, http.StatusPreconditionFailed) || isStatusCode(err, http.StatusNotFound) {
+			return ErrConcurrentUpdate
+		}
+		s.log.Error("failed to save login attempts", slog.String("error", err.Error()))
+		return err
+	}
+
+	attempts.ETag = string(response.ETag)
+	return nil
+}
+
+// DeleteLoginAttempts removes the failed login record of an email address, which lifts any lockout
+func (s *Service) DeleteLoginAttempts(ctx context.Context, email string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.loginAttemptsContainer)
+	if err != nil {
+		s.log.Error("failed to get login attempts container", slog.String("error", err.Error()))
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(email)
+	if _, err := containerClient.DeleteItem(ctx, partitionKey, email, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil
+		}
+		s.log.Error("failed to delete login attempts", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/google/uuid"
+)
+
+// CreateNotification inserts a notification into the notifications container.
+// Notifications whose ID is derived from the triggering event are only created once.
+func (s *Service) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	// Auto-generate ID if not provided
+	if notification.ID == "" {
+		notification.ID = uuid.New().String()
+	}
+
+	containerClient, err := s.client.NewContainer(s.database, s.notificationsContainer)
+	if err != nil {
+		s.log.Error("failed to get notifications container", slog.String("error", err.Error()))
+		return err
+	}
+
+	notificationBytes, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	// Use UserID as partition key so that a user's inbox is a single-partition query
+	partitionKey := azcosmos.NewPartitionKeyString(notification.UserID)
+	_, err = containerClient.CreateItem(ctx, partitionKey, notificationBytes, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusConflict) {
+			s.log.Debug("notification already exists", slog.String("notificationId", notification.ID), slog.String("userId", notification.UserID))
+			return nil
+		}
+		s.log.Error("failed to create notification", slog.String("notificationId", notification.ID), slog.String("userId", notification.UserID), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Debug("notification created", slog.String("notificationId", notification.ID), slog.String("userId", notification.UserID), slog.String("type", notification.Type))
+	return nil
+}
+
+// GetNotifications retrieves a user's notifications, newest first, optionally only the unread ones
+func (s *Service) GetNotifications(ctx context.Context, userID string, unreadOnly bool) ([]models.Notification, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.notificationsContainer)
+	if err != nil {
+		s.log.Error("failed to get notifications container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.userId = @userId ORDER BY c.createdAt DESC"
+	if unreadOnly {
+		query = "SELECT * FROM c WHERE c.userId = @userId AND c.read = false ORDER BY c.createdAt DESC"
+	}
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@userId", Value: userID},
+		},
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	pager := containerClient.NewQueryItemsPager(query, partitionKey, queryOptions)
+
+	notifications := []models.Notification{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query notifications", slog.String("userId", userID), slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var notification models.Notification
+			if err := json.Unmarshal(item, &notification); err != nil {
+				s.log.Error("failed to unmarshal notification", slog.String("error", err.Error()))
+				return nil, err
+			}
+			notifications = append(notifications, notification)
+		}
+	}
+
+	s.log.Debug("notifications retrieved", slog.String("userId", userID), slog.Bool("unreadOnly", unreadOnly), slog.Int("count", len(notifications)))
+	return notifications, nil
+}
+
+// CountUnreadNotifications returns the number of unread notifications of a user
+func (s *Service) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.notificationsContainer)
+	if err != nil {
+		s.log.Error("failed to get notifications container", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	query := "SELECT VALUE COUNT(1) FROM c WHERE c.userId = @userId AND c.read = false"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@userId", Value: userID},
+		},
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	pager := containerClient.NewQueryItemsPager(query, partitionKey, queryOptions)
+
+	count := 0
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to count unread notifications", slog.String("userId", userID), slog.String("error", err.Error()))
+			return 0, err
+		}
+
+		for _, item := range page.Items {
+			var pageCount int
+			if err := json.Unmarshal(item, &pageCount); err != nil {
+				s.log.Error("failed to unmarshal unread notification count", slog.String("error", err.Error()))
+				return 0, err
+			}
+			count += pageCount
+		}
+	}
+
+	return count, nil
+}
+
+// MarkNotificationRead marks a single notification of a user as read
+func (s *Service) MarkNotificationRead(ctx context.Context, userID, notificationID string) (*models.Notification, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.notificationsContainer)
+	if err != nil {
+		s.log.Error("failed to get notifications container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Reading within the user's partition guarantees users can only touch their own notifications
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	response, err := containerClient.ReadItem(ctx, partitionKey, notificationID, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			s.log.Debug("notification not found", slog.String("notificationId", notificationID), slog.String("userId", userID))
+			return nil, ErrNotificationNotFound
+		}
+		s.log.Error("failed to read notification", slog.String("notificationId", notificationID), slog.String("userId", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var notification models.Notification
+	if err := json.Unmarshal(response.Value, &notification); err != nil {
+		s.log.Error("failed to unmarshal notification", slog.String("notificationId", notificationID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if notification.Read {
+		return &notification, nil
+	}
+
+	if err := s.replaceNotificationAsRead(ctx, containerClient, &notification, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("notification marked as read", slog.String("notificationId", notificationID), slog.String("userId", userID))
+	return &notification, nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user as read and returns how many were updated
+func (s *Service) MarkAllNotificationsRead(ctx context.Context, userID string) (int, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.notificationsContainer)
+	if err != nil {
+		s.log.Error("failed to get notifications container", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	unread, err := s.GetNotifications(ctx, userID, true)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	for i := range unread {
+		if err := s.replaceNotificationAsRead(ctx, containerClient, &unread[i], now); err != nil {
+			return i, err
+		}
+	}
+
+	s.log.Debug("all notifications marked as read", slog.String("userId", userID), slog.Int("count", len(unread)))
+	return len(unread), nil
+}
+
+// replaceNotificationAsRead sets the read flag on a notification and stores it
+func (s *Service) replaceNotificationAsRead(ctx context.Context, containerClient *azcosmos.ContainerClient, notification *models.Notification, readAt time.Time) error {
+	notification.Read = true
+	notification.ReadAt = &readAt
+
+	notificationBytes, err := json.Marshal(notification)
+	if err != nil {
+		s.log.Error("failed to marshal notification", slog.String("notificationId", notification.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(notification.UserID)
+	_, err = containerClient.ReplaceItem(ctx, partitionKey, notification.ID, notificationBytes, nil)
+	if err != nil {
+		s.log.Error("failed to mark notification as read", slog.String("notificationId", notification.ID), slog.String("userId", notification.UserID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
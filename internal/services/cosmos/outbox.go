@@ -0,0 +1,145 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/google/uuid"
+)
+
+// EnqueueEmail inserts an email into the outbox container.
+// Callers that derive the ID from the triggering event get ErrEmailAlreadyQueued when the event is processed twice.
+func (s *Service) EnqueueEmail(ctx context.Context, email *models.OutboxEmail) error {
+	// Auto-generate ID if not provided
+	if email.ID == "" {
+		email.ID = uuid.New().String()
+	}
+
+	now := time.Now().UTC()
+	if email.Status == "" {
+		email.Status = models.EmailStatusPending
+	}
+	if email.CreatedAt.IsZero() {
+		email.CreatedAt = now
+	}
+	if email.NextAttemptAt.IsZero() {
+		email.NextAttemptAt = now
+	}
+
+	containerClient, err := s.client.NewContainer(s.database, s.emailOutboxContainer)
+	if err != nil {
+		s.log.Error("failed to get email outbox container", slog.String("error", err.Error()))
+		return err
+	}
+
+	emailBytes, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(email.ID)
+	_, err = containerClient.CreateItem(ctx, partitionKey, emailBytes, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusConflict) {
+			s.log.Debug("email already queued", slog.String("emailId", email.ID))
+			return ErrEmailAlreadyQueued
+		}
+		s.log.Error("failed to enqueue email", slog.String("emailId", email.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Debug("email queued", slog.String("emailId", email.ID))
+	return nil
+}
+
+// GetDueOutboxEmails retrieves up to limit emails that are ready to be sent:
+// pending emails whose next attempt is due and emails whose sending lease has expired
+func (s *Service) GetDueOutboxEmails(ctx context.Context, now time.Time, limit int) ([]models.OutboxEmail, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.emailOutboxContainer)
+	if err != nil {
+		s.log.Error("failed to get email outbox container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// The gateway does not serve cross-partition TOP and ORDER BY, so the oldest due emails are picked here
+	query := "SELECT * FROM c WHERE (c.status = @pending AND c.nextAttemptAt <= @now) OR (c.status = @sending AND c.lockedUntil <= @now)"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@pending", Value: models.EmailStatusPending},
+			{Name: "@sending", Value: models.EmailStatusSending},
+			{Name: "@now", Value: now.UTC().Format(time.RFC3339Nano)},
+		},
+	}
+
+	// Cross-partition query across the outbox.
+	pager := containerClient.NewQueryItemsPager(query, azcosmos.PartitionKey{}, queryOptions)
+
+	var emails []models.OutboxEmail
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query due outbox emails", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var email models.OutboxEmail
+			if err := json.Unmarshal(item, &email); err != nil {
+				s.log.Error("failed to unmarshal outbox email", slog.String("error", err.Error()))
+				return nil, err
+			}
+			emails = append(emails, email)
+		}
+	}
+
+	slices.SortFunc(emails, func(a, b models.OutboxEmail) int { return a.NextAttemptAt.Compare(b.NextAttemptAt) })
+	if len(emails) > limit {
+		emails = emails[:limit]
+	}
+
+	s.log.Debug("due outbox emails retrieved", slog.Int("count", len(emails)))
+	return emails, nil
+}
+
+// UpdateOutboxEmail replaces an outbox email, failing with ErrConcurrentUpdate if it changed since it was read.
+// On success the email's ETag is refreshed so the caller can keep updating it.
+func (s *Service) UpdateOutboxEmail(ctx context.Context, email *models.OutboxEmail) error {
+	containerClient, err := s.client.NewContainer(s.database, s.emailOutboxContainer)
+	if err != nil {
+		s.log.Error("failed to get email outbox container", slog.String("error", err.Error()))
+		return err
+	}
+
+	emailBytes, err := json.Marshal(email)
+	if err != nil {
+		s.log.Error("failed to marshal outbox email", slog.String("emailId", email.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	var itemOptions *azcosmos.ItemOptions
+	if email.ETag != "" {
+		etag := azcore.ETag(email.ETag)
+		itemOptions = &azcosmos.ItemOptions{IfMatchEtag: &etag}
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(email.ID)
+	response, err := containerClient.ReplaceItem(ctx, partitionKey, email.ID, emailBytes, itemOptions)
+	if err != nil {
+		if isStatusCode(err, http.StatusPreconditionFailed) {
+			s.log.Debug("outbox email modified concurrently", slog.String("emailId", email.ID))
+			return ErrConcurrentUpdate
+		}
+		s.log.Error("failed to update outbox email", slog.String("emailId", email.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	email.ETag = string(response.ETag)
+	return nil
+}
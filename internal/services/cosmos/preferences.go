@@ -0,0 +1,67 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// GetNotificationPreferences retrieves a user's notification preferences, or nil if they never saved any
+func (s *Service) GetNotificationPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.preferencesContainer)
+	if err != nil {
+		s.log.Error("failed to get notification preferences container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	response, err := containerClient.ReadItem(ctx, partitionKey, userID, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, nil // not found
+		}
+		s.log.Error("failed to read notification preferences", slog.String("userId", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var prefs models.NotificationPreferences
+	if err := json.Unmarshal(response.Value, &prefs); err != nil {
+		s.log.Error("failed to unmarshal notification preferences", slog.String("userId", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// SaveNotificationPreferences creates or replaces a user's notification preferences
+func (s *Service) SaveNotificationPreferences(ctx context.Context, prefs *models.NotificationPreferences) error {
+	containerClient, err := s.client.NewContainer(s.database, s.preferencesContainer)
+	if err != nil {
+		s.log.Error("failed to get notification preferences container", slog.String("error", err.Error()))
+		return err
+	}
+
+	// One document per user, keyed by the user ID
+	prefs.ID = prefs.UserID
+	prefs.UpdatedAt = time.Now().UTC()
+
+	prefsBytes, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(prefs.UserID)
+	_, err = containerClient.UpsertItem(ctx, partitionKey, prefsBytes, nil)
+	if err != nil {
+		s.log.Error("failed to save notification preferences", slog.String("userId", prefs.UserID), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Info("notification preferences saved", slog.String("userId", prefs.UserID))
+	return nil
+}
@@ -0,0 +1,165 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// CreateRefreshToken stores a refresh token record. Cosmos DB deletes it once it has expired.
+func (s *Service) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	containerClient, err := s.client.NewContainer(s.database, s.refreshTokensContainer)
+	if err != nil {
+		s.log.Error("failed to get refresh tokens container", slog.String("error", err.Error()))
+		return err
+	}
+
+	token.TTL = ttlUntil(token.ExpiresAt)
+	tokenBytes, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(token.ID)
+	if _, err := containerClient.CreateItem(ctx, partitionKey, tokenBytes, nil); err != nil {
+		s.log.Error("failed to create refresh token", slog.String("userId", token.UserID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token record by the token hash, or nil if it does not exist
+func (s *Service) GetRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.refreshTokensContainer)
+	if err != nil {
+		s.log.Error("failed to get refresh tokens container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(hash)
+	response, err := containerClient.ReadItem(ctx, partitionKey, hash, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, nil // not found
+		}
+		s.log.Error("failed to read refresh token", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var token models.RefreshToken
+	if err := json.Unmarshal(response.Value, &token); err != nil {
+		s.log.Error("failed to unmarshal refresh token", slog.String("error", err.Error()))
+		return nil, err
+	}
+	token.ETag = string(response.ETag)
+
+	return &token, nil
+}
+
+// UpdateRefreshToken replaces a refresh token record, failing with ErrConcurrentUpdate if it changed since it was read
+func (s *Service) UpdateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	containerClient, err := s.client.NewContainer(s.database, s.refreshTokensContainer)
+	if err != nil {
+		s.log.Error("failed to get refresh tokens container", slog.String("error", err.Error()))
+		return err
+	}
+
+	token.TTL = ttlUntil(token.ExpiresAt)
+	tokenBytes, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	var itemOptions *azcosmos.ItemOptions
+	if token.ETag != "" {
+		etag := azcore.ETag(token.ETag)
+		itemOptions = &azcosmos.ItemOptions{IfMatchEtag: &etag}
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(token.ID)
+	response, err := containerClient.ReplaceItem(ctx, partitionKey, token.ID, tokenBytes, itemOptions)
+	if err != nil {
+		if isStatusCode(err, http.StatusPreconditionFailed) {
+			return ErrConcurrentUpdate
+		}
+		s.log.Error("failed to update refresh token", slog.String("userId", token.UserID), slog.String("error", err.Error()))
+		return err
+	}
+
+	token.ETag = string(response.ETag)
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes every token in a rotation family and returns how many were revoked
+func (s *Service) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (int, error) {
+	query := "SELECT * FROM c WHERE c.familyId = @familyId AND NOT IS_DEFINED(c.revokedAt)"
+	return s.revokeRefreshTokens(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@familyId", Value: familyID},
+	})
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a user and returns how many were revoked
+func (s *Service) RevokeUserRefreshTokens(ctx context.Context, userID string) (int, error) {
+	query := "SELECT * FROM c WHERE c.userId = @userId AND NOT IS_DEFINED(c.revokedAt)"
+	return s.revokeRefreshTokens(ctx, query, []azcosmos.QueryParameter{
+		{Name: "@userId", Value: userID},
+	})
+}
+
+// revokeRefreshTokens revokes every token matched by the query
+func (s *Service) revokeRefreshTokens(ctx context.Context, query string, params []azcosmos.QueryParameter) (int, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.refreshTokensContainer)
+	if err != nil {
+		s.log.Error("failed to get refresh tokens container", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: params,
+	}
+
+	// Cross-partition query, tokens are partitioned by their hash.
+	pager := containerClient.NewQueryItemsPager(query, azcosmos.PartitionKey{}, queryOptions)
+
+	now := time.Now().UTC()
+	count := 0
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query refresh tokens", slog.String("error", err.Error()))
+			return count, err
+		}
+
+		for _, item := range page.Items {
+			var token models.RefreshToken
+			if err := json.Unmarshal(item, &token); err != nil {
+				s.log.Error("failed to unmarshal refresh token", slog.String("error", err.Error()))
+				return count, err
+			}
+
+			// No ETag check: revoking wins over any concurrent rotation
+			token.ETag = ""
+			token.RevokedAt = &now
+			if err := s.UpdateRefreshToken(ctx, &token); err != nil {
+				if isStatusCode(err, http.StatusNotFound) {
+					continue // expired meanwhile
+				}
+				return count, err
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// ttlUntil returns the Cosmos DB TTL in seconds for a record that is no longer needed after expiresAt
+func ttlUntil(expiresAt time.Time) int {
+	return max(int(time.Until(expiresAt).Seconds())+1, 1)
+}
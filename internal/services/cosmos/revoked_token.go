@@ -0,0 +1,88 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// RevokeAccessToken records a revoked access token. Cosmos DB deletes the record once the token has expired.
+func (s *Service) RevokeAccessToken(ctx context.Context, token *models.RevokedToken) error {
+	containerClient, err := s.client.NewContainer(s.database, s.revokedTokensContainer)
+	if err != nil {
+		s.log.Error("failed to get revoked tokens container", slog.String("error", err.Error()))
+		return err
+	}
+
+	token.TTL = ttlUntil(token.ExpiresAt)
+	tokenBytes, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	// Upsert, revoking the same token twice is not an error
+	partitionKey := azcosmos.NewPartitionKeyString(token.ID)
+	if _, err := containerClient.UpsertItem(ctx, partitionKey, tokenBytes, nil); err != nil {
+		s.log.Error("failed to revoke access token", slog.String("userId", token.UserID), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Debug("access token revoked", slog.String("userId", token.UserID))
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether the access token with the given JWT ID was revoked
+func (s *Service) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.revokedTokensContainer)
+	if err != nil {
+		s.log.Error("failed to get revoked tokens container", slog.String("error", err.Error()))
+		return false, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(jti)
+	if _, err := containerClient.ReadItem(ctx, partitionKey, jti, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return false, nil
+		}
+		s.log.Error("failed to read revoked token", slog.String("error", err.Error()))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// IncrementTokenVersion atomically increments a user's token version, invalidating every token issued before,
+// and returns the new version
+func (s *Service) IncrementTokenVersion(ctx context.Context, userID string) (int, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.usersContainer)
+	if err != nil {
+		s.log.Error("failed to get users container", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	patch := azcosmos.PatchOperations{}
+	patch.AppendIncrement("/tokenVersion", 1)
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	response, err := containerClient.PatchItem(ctx, partitionKey, userID, patch, &azcosmos.ItemOptions{EnableContentResponseOnWrite: true})
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return 0, ErrUserNotFound
+		}
+		s.log.Error("failed to increment token version", slog.String("userId", userID), slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	var user models.User
+	if err := json.Unmarshal(response.Value, &user); err != nil {
+		s.log.Error("failed to unmarshal user", slog.String("userId", userID), slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	s.log.Info("token version incremented", slog.String("userId", userID), slog.Int("tokenVersion", user.TokenVersion))
+	return user.TokenVersion, nil
+}
@@ -0,0 +1,164 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// SaveSession creates or updates a session. Cosmos DB deletes it once its refresh token has expired.
+func (s *Service) SaveSession(ctx context.Context, session *models.Session) error {
+	containerClient, err := s.client.NewContainer(s.database, s.sessionsContainer)
+	if err != nil {
+		s.log.Error("failed to get sessions container", slog.String("error", err.Error()))
+		return err
+	}
+
+	session.TTL = ttlUntil(session.ExpiresAt)
+	sessionBytes, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(session.UserID)
+	if _, err := containerClient.UpsertItem(ctx, partitionKey, sessionBytes, nil); err != nil {
+		s.log.Error("failed to save session", slog.String("userId", session.UserID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// TouchSession stores the last-seen time and IP of a session. A session removed meanwhile is not created again.
+func (s *Service) TouchSession(ctx context.Context, session *models.Session) error {
+	containerClient, err := s.client.NewContainer(s.database, s.sessionsContainer)
+	if err != nil {
+		s.log.Error("failed to get sessions container", slog.String("error", err.Error()))
+		return err
+	}
+
+	session.TTL = ttlUntil(session.ExpiresAt)
+	sessionBytes, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(session.UserID)
+	if _, err := containerClient.ReplaceItem(ctx, partitionKey, session.ID, sessionBytes, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil // Signed out meanwhile
+		}
+		s.log.Error("failed to update session", slog.String("userId", session.UserID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetSession retrieves a session of a user, or nil if it does not exist
+func (s *Service) GetSession(ctx context.Context, userID, sessionID string) (*models.Session, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.sessionsContainer)
+	if err != nil {
+		s.log.Error("failed to get sessions container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Reading within the user's partition guarantees users can only see their own sessions
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	response, err := containerClient.ReadItem(ctx, partitionKey, sessionID, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, nil // not found
+		}
+		s.log.Error("failed to read session", slog.String("userId", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var session models.Session
+	if err := json.Unmarshal(response.Value, &session); err != nil {
+		s.log.Error("failed to unmarshal session", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetUserSessions retrieves the sessions of a user, most recently seen first
+func (s *Service) GetUserSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.sessionsContainer)
+	if err != nil {
+		s.log.Error("failed to get sessions container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.userId = @userId ORDER BY c.lastSeenAt DESC"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@userId", Value: userID},
+		},
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	pager := containerClient.NewQueryItemsPager(query, partitionKey, queryOptions)
+
+	sessions := []models.Session{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query sessions", slog.String("userId", userID), slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var session models.Session
+			if err := json.Unmarshal(item, &session); err != nil {
+				s.log.Error("failed to unmarshal session", slog.String("error", err.Error()))
+				return nil, err
+			}
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession removes a session of a user
+func (s *Service) DeleteSession(ctx context.Context, userID, sessionID string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.sessionsContainer)
+	if err != nil {
+		s.log.Error("failed to get sessions container", slog.String("error", err.Error()))
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(userID)
+	if _, err := containerClient.DeleteItem(ctx, partitionKey, sessionID, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrSessionNotFound
+		}
+		s.log.Error("failed to delete session", slog.String("userId", userID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// DeleteUserSessions removes every session of a user and returns how many were removed
+func (s *Service) DeleteUserSessions(ctx context.Context, userID string) (int, error) {
+	sessions, err := s.GetUserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, session := range sessions {
+		if err := s.DeleteSession(ctx, userID, session.ID); err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				continue // expired meanwhile
+			}
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
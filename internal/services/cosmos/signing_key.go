@@ -0,0 +1,71 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// CreateSigningKey stores a token signing key. Cosmos DB deletes it once it no longer verifies tokens.
+// It returns ErrSigningKeyExists if another instance already created a key with the same ID.
+func (s *Service) CreateSigningKey(ctx context.Context, key *models.SigningKey) error {
+	containerClient, err := s.client.NewContainer(s.database, s.signingKeysContainer)
+	if err != nil {
+		s.log.Error("failed to get signing keys container", slog.String("error", err.Error()))
+		return err
+	}
+
+	key.TTL = ttlUntil(key.ExpiresAt)
+	keyBytes, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(key.ID)
+	if _, err := containerClient.CreateItem(ctx, partitionKey, keyBytes, nil); err != nil {
+		if isStatusCode(err, http.StatusConflict) {
+			return ErrSigningKeyExists
+		}
+		s.log.Error("failed to create signing key", slog.String("kid", key.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Info("signing key created", slog.String("kid", key.ID), slog.Time("activatesAt", key.ActivatesAt))
+	return nil
+}
+
+// GetSigningKeys retrieves all signing keys that have not been deleted yet
+func (s *Service) GetSigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.signingKeysContainer)
+	if err != nil {
+		s.log.Error("failed to get signing keys container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Cross-partition query, there are only a few keys.
+	pager := containerClient.NewQueryItemsPager("SELECT * FROM c", azcosmos.PartitionKey{}, nil)
+
+	var keys []models.SigningKey
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query signing keys", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var key models.SigningKey
+			if err := json.Unmarshal(item, &key); err != nil {
+				s.log.Error("failed to unmarshal signing key", slog.String("error", err.Error()))
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
 	"github.com/google/uuid"
@@ -18,7 +22,7 @@ func (s *Service) CreateUser(ctx context.Context, user *models.User) error {
 	}
 
 	// Validate role
-	if user.Role != models.RoleAdmin && user.Role != models.RoleStudent {
+	if !models.ValidRole(user.Role) {
 		s.log.Error("invalid user role", slog.String("role", user.Role))
 		return ErrInvalidRole
 	}
@@ -93,6 +97,10 @@ func (s *Service) GetUserByID(ctx context.Context, id string) (*models.User, err
 	partitionKey := azcosmos.NewPartitionKeyString(id)
 	response, err := containerClient.ReadItem(ctx, partitionKey, id, nil)
 	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			s.log.Debug("user not found by ID", slog.String("userId", id))
+			return nil, nil // not found
+		}
 		s.log.Error("failed to read user by ID", slog.String("userId", id), slog.String("error", err.Error()))
 		return nil, err
 	}
@@ -151,7 +159,7 @@ func (s *Service) GetUserByUsername(ctx context.Context, username string) (*mode
 	return nil, nil // not found
 }
 
-// UpdateUser updates user information (name and/or username)
+// UpdateUser updates user information (name, username and/or locale)
 func (s *Service) UpdateUser(ctx context.Context, userID string, updates map[string]interface{}) (*models.User, error) {
 	containerClient, err := s.client.NewContainer(s.database, s.usersContainer)
 	if err != nil {
@@ -171,9 +179,13 @@ func (s *Service) UpdateUser(ctx context.Context, userID string, updates map[str
 		return nil, ErrUserNotFound
 	}
 
-	// Apply updates
+	// Only the changed fields are patched, so a concurrent change to the rest of the user,
+	// such as a token version increment or a suspension, is not overwritten
+	patch := azcosmos.PatchOperations{}
+	changed := false
 	if name, ok := updates["name"].(string); ok && name != "" {
-		user.Name = name
+		patch.AppendSet("/name", name)
+		changed = true
 	}
 
 	if username, ok := updates["username"].(string); ok && username != "" {
@@ -187,24 +199,238 @@ func (s *Service) UpdateUser(ctx context.Context, userID string, updates map[str
 			s.log.Debug("username already taken", slog.String("username", username))
 			return nil, ErrUsernameAlreadyExists
 		}
-		user.UserName = username
+		patch.AppendSet("/username", username)
+		changed = true
 	}
 
-	// Marshal the updated user
-	userBytes, err := json.Marshal(user)
-	if err != nil {
-		s.log.Error("failed to marshal updated user", slog.String("userId", userID), slog.String("error", err.Error()))
-		return nil, err
+	if locale, ok := updates["locale"].(string); ok && locale != "" {
+		patch.AppendSet("/locale", locale)
+		changed = true
+	}
+
+	if !changed {
+		return user, nil
 	}
 
-	// Replace the item using the partition key
 	partitionKey := azcosmos.NewPartitionKeyString(userID)
-	_, err = containerClient.ReplaceItem(ctx, partitionKey, userID, userBytes, nil)
+	response, err := containerClient.PatchItem(ctx, partitionKey, userID, patch, &azcosmos.ItemOptions{EnableContentResponseOnWrite: true})
 	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, ErrUserNotFound
+		}
 		s.log.Error("failed to update user in cosmos", slog.String("userId", userID), slog.String("error", err.Error()))
 		return nil, err
 	}
 
+	user = &models.User{}
+	if err := json.Unmarshal(response.Value, user); err != nil {
+		s.log.Error("failed to unmarshal updated user", slog.String("userId", userID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
 	s.log.Info("user updated successfully", slog.String("userId", userID))
 	return user, nil
 }
+
+// GetUsersByRole retrieves all users with the given role
+func (s *Service) GetUsersByRole(ctx context.Context, role string) ([]models.User, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.usersContainer)
+	if err != nil {
+		s.log.Error("failed to get users container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.role = @role"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@role", Value: role},
+		},
+	}
+
+	// Cross-partition query across all users.
+	pager := containerClient.NewQueryItemsPager(query, azcosmos.PartitionKey{}, queryOptions)
+
+	var users []models.User
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query users by role", slog.String("role", role), slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var user models.User
+			if err := json.Unmarshal(item, &user); err != nil {
+				s.log.Error("failed to unmarshal user", slog.String("error", err.Error()))
+				return nil, err
+			}
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+// SearchUsers retrieves up to limit users, newest first. A non-empty query matches the email, username or name
+// case-insensitively, and a non-empty role only returns users with that role.
+func (s *Service) SearchUsers(ctx context.Context, query, role string, limit int) ([]models.User, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.usersContainer)
+	if err != nil {
+		s.log.Error("failed to get users container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	sql := "SELECT * FROM c"
+	conditions := []string{}
+	params := []azcosmos.QueryParameter{}
+	if query != "" {
+		conditions = append(conditions, "(CONTAINS(c.email, @query, true) OR CONTAINS(c.username, @query, true) OR CONTAINS(c.name, @query, true))")
+		params = append(params, azcosmos.QueryParameter{Name: "@query", Value: query})
+	}
+	if role != "" {
+		conditions = append(conditions, "c.role = @role")
+		params = append(params, azcosmos.QueryParameter{Name: "@role", Value: role})
+	}
+	if len(conditions) > 0 {
+		sql += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Cross-partition query across all users. The gateway does not serve cross-partition TOP and ORDER BY,
+	// so the matches are sorted and limited below.
+	pager := containerClient.NewQueryItemsPager(sql, azcosmos.PartitionKey{}, &azcosmos.QueryOptions{QueryParameters: params})
+
+	users := []models.User{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to search users", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var user models.User
+			if err := json.Unmarshal(item, &user); err != nil {
+				s.log.Error("failed to unmarshal user", slog.String("error", err.Error()))
+				return nil, err
+			}
+			users = append(users, user)
+		}
+	}
+
+	slices.SortFunc(users, func(a, b models.User) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// GetUserByPasswordResetHash retrieves the user with a pending password reset for the given token hash, or nil
+func (s *Service) GetUserByPasswordResetHash(ctx context.Context, hash string) (*models.User, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.usersContainer)
+	if err != nil {
+		s.log.Error("failed to get users container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.passwordResetHash = @hash"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@hash", Value: hash},
+		},
+	}
+
+	// Cross-partition query, users are partitioned by ID.
+	pager := containerClient.NewQueryItemsPager(query, azcosmos.PartitionKey{}, queryOptions)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query user by password reset token", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var user models.User
+			if err := json.Unmarshal(item, &user); err != nil {
+				s.log.Error("failed to unmarshal user", slog.String("error", err.Error()))
+				return nil, err
+			}
+			return &user, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetUserByOIDCSubject retrieves the user linked to the identity provider subject, or nil
+func (s *Service) GetUserByOIDCSubject(ctx context.Context, subject string) (*models.User, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.usersContainer)
+	if err != nil {
+		s.log.Error("failed to get users container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	query := "SELECT * FROM c WHERE c.oidcSubject = @subject"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@subject", Value: subject},
+		},
+	}
+
+	// Cross-partition query, users are partitioned by ID.
+	pager := containerClient.NewQueryItemsPager(query, azcosmos.PartitionKey{}, queryOptions)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query user by OIDC subject", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var user models.User
+			if err := json.Unmarshal(item, &user); err != nil {
+				s.log.Error("failed to unmarshal user", slog.String("error", err.Error()))
+				return nil, err
+			}
+			return &user, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ReplaceUser replaces a user document. If the user carries an ETag, it fails with ErrConcurrentUpdate when the
+// document changed since it was read.
+func (s *Service) ReplaceUser(ctx context.Context, user *models.User) error {
+	containerClient, err := s.client.NewContainer(s.database, s.usersContainer)
+	if err != nil {
+		s.log.Error("failed to get users container", slog.String("error", err.Error()))
+		return err
+	}
+
+	userBytes, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	var itemOptions *azcosmos.ItemOptions
+	if user.ETag != "" {
+		etag := azcore.ETag(user.ETag)
+		itemOptions = &azcosmos.ItemOptions{IfMatchEtag: &etag}
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(user.ID)
+	response, err := containerClient.ReplaceItem(ctx, partitionKey, user.ID, userBytes, itemOptions)
+	if err != nil {
+		if isStatusCode(err, http.StatusPreconditionFailed) {
+			return ErrConcurrentUpdate
+		}
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrUserNotFound
+		}
+		s.log.Error("failed to replace user", slog.String("userId", user.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	user.ETag = string(response.ETag)
+	return nil
+}
@@ -0,0 +1,317 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/google/uuid"
+)
+
+// CreateWebhookSubscription stores a new webhook subscription
+func (s *Service) CreateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	// Auto-generate ID if not provided
+	if subscription.ID == "" {
+		subscription.ID = uuid.New().String()
+	}
+
+	containerClient, err := s.client.NewContainer(s.database, s.webhooksContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook subscriptions container", slog.String("error", err.Error()))
+		return err
+	}
+
+	subscriptionBytes, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(subscription.ID)
+	if _, err := containerClient.CreateItem(ctx, partitionKey, subscriptionBytes, nil); err != nil {
+		s.log.Error("failed to create webhook subscription", slog.String("webhookId", subscription.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Info("webhook subscription created", slog.String("webhookId", subscription.ID), slog.String("url", subscription.URL))
+	return nil
+}
+
+// GetWebhookSubscriptions retrieves all webhook subscriptions, oldest first
+func (s *Service) GetWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.webhooksContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook subscriptions container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Cross-partition query across all subscriptions. The gateway does not serve cross-partition ORDER BY,
+	// so they are sorted below.
+	pager := containerClient.NewQueryItemsPager("SELECT * FROM c", azcosmos.PartitionKey{}, nil)
+
+	var subscriptions []models.WebhookSubscription
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query webhook subscriptions", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var subscription models.WebhookSubscription
+			if err := json.Unmarshal(item, &subscription); err != nil {
+				s.log.Error("failed to unmarshal webhook subscription", slog.String("error", err.Error()))
+				return nil, err
+			}
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	slices.SortFunc(subscriptions, func(a, b models.WebhookSubscription) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return subscriptions, nil
+}
+
+// GetWebhookSubscription retrieves a webhook subscription by ID, or nil if it does not exist
+func (s *Service) GetWebhookSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.webhooksContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook subscriptions container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(id)
+	response, err := containerClient.ReadItem(ctx, partitionKey, id, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, nil // not found
+		}
+		s.log.Error("failed to read webhook subscription", slog.String("webhookId", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var subscription models.WebhookSubscription
+	if err := json.Unmarshal(response.Value, &subscription); err != nil {
+		s.log.Error("failed to unmarshal webhook subscription", slog.String("webhookId", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+// UpdateWebhookSubscription replaces a webhook subscription
+func (s *Service) UpdateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	containerClient, err := s.client.NewContainer(s.database, s.webhooksContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook subscriptions container", slog.String("error", err.Error()))
+		return err
+	}
+
+	subscription.UpdatedAt = time.Now().UTC()
+	subscriptionBytes, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(subscription.ID)
+	if _, err := containerClient.ReplaceItem(ctx, partitionKey, subscription.ID, subscriptionBytes, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrWebhookNotFound
+		}
+		s.log.Error("failed to update webhook subscription", slog.String("webhookId", subscription.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Info("webhook subscription updated", slog.String("webhookId", subscription.ID))
+	return nil
+}
+
+// DeleteWebhookSubscription deletes a webhook subscription. Its delivery history is kept.
+func (s *Service) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	containerClient, err := s.client.NewContainer(s.database, s.webhooksContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook subscriptions container", slog.String("error", err.Error()))
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(id)
+	if _, err := containerClient.DeleteItem(ctx, partitionKey, id, nil); err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrWebhookNotFound
+		}
+		s.log.Error("failed to delete webhook subscription", slog.String("webhookId", id), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Info("webhook subscription deleted", slog.String("webhookId", id))
+	return nil
+}
+
+// CreateWebhookDelivery queues a delivery for a webhook subscription.
+// Callers that derive the ID from the triggering event get ErrDeliveryAlreadyQueued when the event is processed twice.
+func (s *Service) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	// Auto-generate ID if not provided
+	if delivery.ID == "" {
+		delivery.ID = uuid.New().String()
+	}
+
+	containerClient, err := s.client.NewContainer(s.database, s.deliveriesContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook deliveries container", slog.String("error", err.Error()))
+		return err
+	}
+
+	deliveryBytes, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(delivery.SubscriptionID)
+	if _, err := containerClient.CreateItem(ctx, partitionKey, deliveryBytes, nil); err != nil {
+		if isStatusCode(err, http.StatusConflict) {
+			s.log.Debug("webhook delivery already queued", slog.String("deliveryId", delivery.ID))
+			return ErrDeliveryAlreadyQueued
+		}
+		s.log.Error("failed to create webhook delivery", slog.String("webhookId", delivery.SubscriptionID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// GetWebhookDeliveries retrieves up to limit of a subscription's most recent deliveries, newest first
+func (s *Service) GetWebhookDeliveries(ctx context.Context, subscriptionID string, limit int) ([]models.WebhookDelivery, error) {
+	query := "SELECT TOP @limit * FROM c WHERE c.subscriptionId = @subscriptionId ORDER BY c.createdAt DESC"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@limit", Value: limit},
+			{Name: "@subscriptionId", Value: subscriptionID},
+		},
+	}
+	return s.queryWebhookDeliveries(ctx, query, azcosmos.NewPartitionKeyString(subscriptionID), queryOptions)
+}
+
+// GetDueWebhookDeliveries retrieves up to limit deliveries that are ready to be attempted:
+// pending deliveries whose next attempt is due and deliveries whose lease has expired
+func (s *Service) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	query := "SELECT * FROM c WHERE (c.status = @pending AND c.nextAttemptAt <= @now) OR (c.status = @delivering AND c.lockedUntil <= @now)"
+	queryOptions := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@pending", Value: models.WebhookDeliveryPending},
+			{Name: "@delivering", Value: models.WebhookDeliveryDelivering},
+			{Name: "@now", Value: now.UTC().Format(time.RFC3339Nano)},
+		},
+	}
+	// Cross-partition query across all subscriptions. The gateway does not serve cross-partition TOP and ORDER BY,
+	// so the oldest due deliveries are picked here.
+	deliveries, err := s.queryWebhookDeliveries(ctx, query, azcosmos.PartitionKey{}, queryOptions)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(deliveries, func(a, b models.WebhookDelivery) int { return a.NextAttemptAt.Compare(b.NextAttemptAt) })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDelivery retrieves a delivery of a subscription, or nil if it does not exist
+func (s *Service) GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*models.WebhookDelivery, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.deliveriesContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook deliveries container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(subscriptionID)
+	response, err := containerClient.ReadItem(ctx, partitionKey, id, nil)
+	if err != nil {
+		if isStatusCode(err, http.StatusNotFound) {
+			return nil, nil // not found
+		}
+		s.log.Error("failed to read webhook delivery", slog.String("deliveryId", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var delivery models.WebhookDelivery
+	if err := json.Unmarshal(response.Value, &delivery); err != nil {
+		s.log.Error("failed to unmarshal webhook delivery", slog.String("deliveryId", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+	delivery.ETag = string(response.ETag)
+
+	return &delivery, nil
+}
+
+// UpdateWebhookDelivery replaces a delivery, failing with ErrConcurrentUpdate if it changed since it was read.
+// On success the delivery's ETag is refreshed so the caller can keep updating it.
+func (s *Service) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	containerClient, err := s.client.NewContainer(s.database, s.deliveriesContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook deliveries container", slog.String("error", err.Error()))
+		return err
+	}
+
+	deliveryBytes, err := json.Marshal(delivery)
+	if err != nil {
+		s.log.Error("failed to marshal webhook delivery", slog.String("deliveryId", delivery.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	var itemOptions *azcosmos.ItemOptions
+	if delivery.ETag != "" {
+		etag := azcore.ETag(delivery.ETag)
+		itemOptions = &azcosmos.ItemOptions{IfMatchEtag: &etag}
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(delivery.SubscriptionID)
+	response, err := containerClient.ReplaceItem(ctx, partitionKey, delivery.ID, deliveryBytes, itemOptions)
+	if err != nil {
+		if isStatusCode(err, http.StatusPreconditionFailed) {
+			s.log.Debug("webhook delivery modified concurrently", slog.String("deliveryId", delivery.ID))
+			return ErrConcurrentUpdate
+		}
+		if isStatusCode(err, http.StatusNotFound) {
+			return ErrDeliveryNotFound
+		}
+		s.log.Error("failed to update webhook delivery", slog.String("deliveryId", delivery.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	delivery.ETag = string(response.ETag)
+	return nil
+}
+
+// queryWebhookDeliveries runs a webhook deliveries query
+func (s *Service) queryWebhookDeliveries(ctx context.Context, query string, partitionKey azcosmos.PartitionKey, queryOptions *azcosmos.QueryOptions) ([]models.WebhookDelivery, error) {
+	containerClient, err := s.client.NewContainer(s.database, s.deliveriesContainer)
+	if err != nil {
+		s.log.Error("failed to get webhook deliveries container", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	pager := containerClient.NewQueryItemsPager(query, partitionKey, queryOptions)
+
+	var deliveries []models.WebhookDelivery
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			s.log.Error("failed to query webhook deliveries", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var delivery models.WebhookDelivery
+			if err := json.Unmarshal(item, &delivery); err != nil {
+				s.log.Error("failed to unmarshal webhook delivery", slog.String("error", err.Error()))
+				return nil, err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return deliveries, nil
+}
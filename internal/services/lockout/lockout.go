@@ -0,0 +1,256 @@
+// Package lockout locks accounts progressively after repeated failed logins
+package lockout
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+// saveAttempts is how often a failed login is recorded again when concurrent failures keep changing the record
+const saveAttempts = 5
+
+// Store keeps the failed login records, keyed by lower-case email address
+type Store interface {
+	Get(ctx context.Context, email string) (*models.LoginAttempts, error)
+	// Save stores the record, it may be forgotten after keepUntil. It fails with cosmos.ErrConcurrentUpdate
+	// if the record was created, changed or deleted since it was read.
+	Save(ctx context.Context, attempts *models.LoginAttempts, keepUntil time.Time) error
+	Delete(ctx context.Context, email string) error
+}
+
+// Policy configures when and for how long accounts are locked
+type Policy struct {
+	MaxFailures int           // Failures before the first lockout
+	BaseLockout time.Duration // First lockout, doubled with every further failure
+	MaxLockout  time.Duration // Longest lockout
+	ResetAfter  time.Duration // Failures are forgotten after this long without another one
+}
+
+// Guard tracks failed logins per email address and locks the address once the policy says so.
+// Addresses without an account are tracked the same way, so lockouts do not reveal which accounts exist.
+type Guard struct {
+	store  Store
+	policy Policy
+	log    *slog.Logger
+	now    func() time.Time
+}
+
+// NewGuard creates a Guard
+func NewGuard(store Store, policy Policy, log *slog.Logger) *Guard {
+	const module = "lockout"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &Guard{
+		store:  store,
+		policy: policy,
+		log:    log,
+		now:    time.Now,
+	}
+}
+
+// Locked returns how long logins for the email are still locked, or 0
+func (g *Guard) Locked(ctx context.Context, email string) (time.Duration, error) {
+	attempts, err := g.store.Get(ctx, normalize(email))
+	if err != nil || attempts == nil || attempts.LockedUntil == nil {
+		return 0, err
+	}
+	return max(attempts.LockedUntil.Sub(g.now()), 0), nil
+}
+
+// Failed records a failed login from ip and returns how long the email is locked now, or 0.
+// Every failure is logged for security review.
+func (g *Guard) Failed(ctx context.Context, email, ip string) (time.Duration, error) {
+	key := normalize(email)
+
+	// Parallel guesses fail at the same time, so the record is saved only if it did not change
+	// since it was read, and read again otherwise. No failure goes uncounted.
+	var attempts *models.LoginAttempts
+	var lockedFor time.Duration
+	for i := 0; ; i++ {
+		var err error
+		if attempts, lockedFor, err = g.recordFailure(ctx, key); err == nil {
+			break
+		}
+		if !errors.Is(err, cosmos.ErrConcurrentUpdate) || i == saveAttempts-1 {
+			return 0, err
+		}
+	}
+
+	g.log.Warn("login failed",
+		slog.String("event", "login_failed"),
+		slog.String("email", key),
+		slog.String("ip", ip),
+		slog.Int("failures", attempts.Failures),
+		slog.Duration("lockedFor", lockedFor),
+	)
+	if lockedFor > 0 {
+		g.log.Warn("account locked", slog.String("event", "account_locked"), slog.String("email", key), slog.String("ip", ip), slog.Duration("lockedFor", lockedFor))
+	}
+	return lockedFor, nil
+}
+
+// recordFailure reads the record of the key, adds a failure and saves it
+func (g *Guard) recordFailure(ctx context.Context, key string) (*models.LoginAttempts, time.Duration, error) {
+	attempts, err := g.store.Get(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := g.now().UTC()
+	if attempts == nil {
+		attempts = &models.LoginAttempts{ID: key}
+	} else if now.Sub(attempts.LastFailureAt) > g.policy.ResetAfter {
+		attempts = &models.LoginAttempts{ID: key, ETag: attempts.ETag}
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = now
+
+	var lockedFor time.Duration
+	if lockedFor = g.lockoutFor(attempts.Failures); lockedFor > 0 {
+		lockedUntil := now.Add(lockedFor)
+		attempts.LockedUntil = &lockedUntil
+	}
+
+	keepUntil := now.Add(g.policy.ResetAfter)
+	if attempts.LockedUntil != nil && attempts.LockedUntil.After(keepUntil) {
+		keepUntil = *attempts.LockedUntil
+	}
+	if err := g.store.Save(ctx, attempts, keepUntil); err != nil {
+		return nil, 0, err
+	}
+	return attempts, lockedFor, nil
+}
+
+// Succeeded forgets the failed logins of the email after a successful login
+func (g *Guard) Succeeded(ctx context.Context, email string) error {
+	return g.store.Delete(ctx, normalize(email))
+}
+
+// Unlock lifts the lockout of the email and forgets its failed logins
+func (g *Guard) Unlock(ctx context.Context, email string) error {
+	key := normalize(email)
+	if err := g.store.Delete(ctx, key); err != nil {
+		return err
+	}
+	g.log.Info("account unlocked", slog.String("event", "account_unlocked"), slog.String("email", key))
+	return nil
+}
+
+// lockoutFor returns the lockout after the given number of consecutive failures
+func (g *Guard) lockoutFor(failures int) time.Duration {
+	if failures < g.policy.MaxFailures {
+		return 0
+	}
+	lockout := g.policy.BaseLockout
+	for range failures - g.policy.MaxFailures {
+		lockout *= 2
+		if lockout >= g.policy.MaxLockout {
+			return g.policy.MaxLockout
+		}
+	}
+	return min(lockout, g.policy.MaxLockout)
+}
+
+// normalize returns the key of an email address
+func normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// MemoryStore is a Store for a single instance. Its state is lost on restart.
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]memoryEntry
+	version  int // Source of the ETags of saved records
+	now      func() time.Time
+}
+
+// memoryEntry is a record with the time it may be forgotten
+type memoryEntry struct {
+	attempts  models.LoginAttempts
+	keepUntil time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		attempts: make(map[string]memoryEntry),
+		now:      time.Now,
+	}
+}
+
+// Get returns the record of the email, or nil
+func (m *MemoryStore) Get(_ context.Context, email string) (*models.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.attempts[email]
+	if !ok || m.now().After(entry.keepUntil) {
+		return nil, nil
+	}
+	attempts := entry.attempts
+	return &attempts, nil
+}
+
+// Save stores the record until keepUntil, unless it changed since it was read
+func (m *MemoryStore) Save(_ context.Context, attempts *models.LoginAttempts, keepUntil time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop records that may be forgotten
+	now := m.now()
+	for email, entry := range m.attempts {
+		if now.After(entry.keepUntil) {
+			delete(m.attempts, email)
+		}
+	}
+
+	if m.attempts[attempts.ID].attempts.ETag != attempts.ETag {
+		return cosmos.ErrConcurrentUpdate
+	}
+	m.version++
+	attempts.ETag = strconv.Itoa(m.version)
+	m.attempts[attempts.ID] = memoryEntry{attempts: *attempts, keepUntil: keepUntil}
+	return nil
+}
+
+// Delete removes the record of the email
+func (m *MemoryStore) Delete(_ context.Context, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.attempts, email)
+	return nil
+}
+
+// CosmosStore is a Store shared by all instances, kept in the login-attempts container
+type CosmosStore struct {
+	cosmos *cosmos.Service
+}
+
+// NewCosmosStore creates a CosmosStore
+func NewCosmosStore(cosmosService *cosmos.Service) *CosmosStore {
+	return &CosmosStore{cosmos: cosmosService}
+}
+
+// Get returns the record of the email, or nil
+func (c *CosmosStore) Get(ctx context.Context, email string) (*models.LoginAttempts, error) {
+	return c.cosmos.GetLoginAttempts(ctx, email)
+}
+
+// Save stores the record until keepUntil
+func (c *CosmosStore) Save(ctx context.Context, attempts *models.LoginAttempts, keepUntil time.Time) error {
+	return c.cosmos.SaveLoginAttempts(ctx, attempts, keepUntil)
+}
+
+// Delete removes the record of the email
+func (c *CosmosStore) Delete(ctx context.Context, email string) error {
+	return c.cosmos.DeleteLoginAttempts(ctx, email)
+}
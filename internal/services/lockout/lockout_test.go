@@ -0,0 +1,169 @@
+package lockout
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGuard creates a guard with a memory store whose clocks are read from now
+func newTestGuard(now *time.Time) *Guard {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	guard := NewGuard(store, Policy{
+		MaxFailures: 3,
+		BaseLockout: time.Minute,
+		MaxLockout:  5 * time.Minute,
+		ResetAfter:  time.Hour,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	guard.now = func() time.Time { return *now }
+	return guard
+}
+
+func TestGuard_ProgressiveLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&now)
+
+	expected := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, want := range expected {
+		lockedFor, err := guard.Failed(ctx, "Student@University.edu", "203.0.113.7")
+		require.NoError(t, err)
+		assert.Equal(t, want, lockedFor, "failure %d", i+1)
+
+		locked, err := guard.Locked(ctx, "student@university.edu")
+		require.NoError(t, err)
+		assert.Equal(t, want, locked, "failure %d", i+1)
+	}
+
+	// The lockout runs out, but the next failure locks again for the maximum
+	now = now.Add(5 * time.Minute)
+	locked, err := guard.Locked(ctx, "student@university.edu")
+	require.NoError(t, err)
+	assert.Zero(t, locked)
+
+	lockedFor, err := guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, lockedFor)
+}
+
+// racingStore records a failure of another instance between the guard's first read and save
+type racingStore struct {
+	*MemoryStore
+	raced bool
+}
+
+func (r *racingStore) Save(ctx context.Context, attempts *models.LoginAttempts, keepUntil time.Time) error {
+	if !r.raced {
+		r.raced = true
+		other := *attempts
+		other.ETag = ""
+		if current, _ := r.MemoryStore.Get(ctx, attempts.ID); current != nil {
+			other = *current
+			other.Failures++
+		}
+		if err := r.MemoryStore.Save(ctx, &other, keepUntil); err != nil {
+			return err
+		}
+	}
+	return r.MemoryStore.Save(ctx, attempts, keepUntil)
+}
+
+func TestGuard_ConcurrentFailuresAreCounted(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&now)
+	store := &racingStore{MemoryStore: guard.store.(*MemoryStore)}
+	guard.store = store
+
+	_, err := guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+	require.NoError(t, err)
+
+	// The other instance's failure was saved first, so this one was recorded on top of it
+	attempts, err := store.Get(ctx, "student@university.edu")
+	require.NoError(t, err)
+	require.NotNil(t, attempts)
+	assert.Equal(t, 2, attempts.Failures)
+}
+
+func TestGuard_ResetAfter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&now)
+
+	for range 2 {
+		_, err := guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+		require.NoError(t, err)
+	}
+
+	// Failures older than ResetAfter are forgotten
+	now = now.Add(2 * time.Hour)
+	lockedFor, err := guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+	require.NoError(t, err)
+	assert.Zero(t, lockedFor)
+
+	lockedFor, err = guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+	require.NoError(t, err)
+	assert.Zero(t, lockedFor)
+}
+
+func TestGuard_SucceededAndUnlock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&now)
+
+	for range 2 {
+		_, err := guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+		require.NoError(t, err)
+	}
+	require.NoError(t, guard.Succeeded(ctx, "student@university.edu"))
+
+	// A successful login starts the count again
+	lockedFor, err := guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+	require.NoError(t, err)
+	assert.Zero(t, lockedFor)
+
+	for range 2 {
+		_, err = guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+		require.NoError(t, err)
+	}
+	locked, err := guard.Locked(ctx, "student@university.edu")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, locked)
+
+	require.NoError(t, guard.Unlock(ctx, "STUDENT@university.edu"))
+	locked, err = guard.Locked(ctx, "student@university.edu")
+	require.NoError(t, err)
+	assert.Zero(t, locked)
+}
+
+func TestMemoryStore_ForgetsExpiredRecords(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	guard := newTestGuard(&now)
+
+	_, err := guard.Failed(ctx, "student@university.edu", "203.0.113.7")
+	require.NoError(t, err)
+
+	store := guard.store.(*MemoryStore)
+	attempts, err := store.Get(ctx, "student@university.edu")
+	require.NoError(t, err)
+	require.NotNil(t, attempts)
+	assert.Equal(t, 1, attempts.Failures)
+
+	now = now.Add(time.Hour + time.Second)
+	attempts, err = store.Get(ctx, "student@university.edu")
+	require.NoError(t, err)
+	assert.Nil(t, attempts)
+
+	// Saving another record drops the expired one
+	_, err = guard.Failed(ctx, "other@university.edu", "203.0.113.7")
+	require.NoError(t, err)
+	assert.Len(t, store.attempts, 1)
+}
@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// accountEmailData is the template data for emails with a link that acts on the user's account
+type accountEmailData struct {
+	Name      string
+	ActionURL string
+	ExpiresIn int // Minutes until the link expires
+}
+
+// emailChangeEmailData is the template data for the notice sent to the old address when an email change is requested
+type emailChangeEmailData struct {
+	Name     string
+	NewEmail string
+}
+
+// restrictionEmailData is the template data for emails about a restriction an admin put on the account
+type restrictionEmailData struct {
+	Name   string
+	Kind   string // One of the models.Restriction kinds
+	Reason string
+	Until  string // Empty for restrictions without expiry
+}
+
+// SendAccountRestricted emails the user that an admin suspended the account or banned them from an action
+func (n *Notifier) SendAccountRestricted(ctx context.Context, user *models.User, kind string, restriction *models.Restriction) error {
+	data := restrictionEmailData{
+		Name:   user.Name,
+		Kind:   kind,
+		Reason: restriction.Reason,
+	}
+	if restriction.ExpiresAt != nil {
+		data.Until = restriction.ExpiresAt.UTC().Format("2 January 2006 15:04 UTC")
+	}
+	id := "restricted-" + user.ID + "-" + kind + "-" + strconv.FormatInt(restriction.CreatedAt.Unix(), 10)
+	return n.queueEmail(ctx, id, TemplateAccountRestricted, user, data)
+}
+
+// SendPasswordReset emails the user a link to reset their password with the given token
+func (n *Notifier) SendPasswordReset(ctx context.Context, user *models.User, token string, expiresIn time.Duration) error {
+	// The outbox ID must not reveal the token, the hash prefix keeps repeated requests apart
+	return n.queueEmail(ctx, "password-reset-"+user.PasswordResetHash[:16], TemplatePasswordReset, user, accountEmailData{
+		Name:      user.Name,
+		ActionURL: n.frontendURL + "/reset-password?token=" + url.QueryEscape(token),
+		ExpiresIn: int(expiresIn.Minutes()),
+	})
+}
+
+// SendEmailVerification emails the user a link to verify their email address with the given token
+func (n *Notifier) SendEmailVerification(ctx context.Context, user *models.User, token string, expiresIn time.Duration) error {
+	id := "email-verification-" + user.ID
+	if user.VerificationSentAt != nil {
+		id += "-" + strconv.FormatInt(user.VerificationSentAt.Unix(), 10)
+	}
+	return n.queueEmail(ctx, id, TemplateEmailVerification, user, accountEmailData{
+		Name:      user.Name,
+		ActionURL: n.frontendURL + "/verify-email?token=" + url.QueryEscape(token),
+		ExpiresIn: int(expiresIn.Minutes()),
+	})
+}
+
+// SendEmailChange emails a link that confirms the email change to the new address
+func (n *Notifier) SendEmailChange(ctx context.Context, user *models.User, token string, expiresIn time.Duration) error {
+	// queueEmail sends to the user's address, which stays the old one until the change is confirmed
+	recipient := *user
+	recipient.Email = user.PendingEmail
+	return n.queueEmail(ctx, emailChangeID("email-change-", user), TemplateEmailChange, &recipient, accountEmailData{
+		Name:      user.Name,
+		ActionURL: n.frontendURL + "/confirm-email?token=" + url.QueryEscape(token),
+		ExpiresIn: int(expiresIn.Minutes()),
+	})
+}
+
+// SendEmailChangeNotice tells the old address that a change to the pending email was requested
+func (n *Notifier) SendEmailChangeNotice(ctx context.Context, user *models.User) error {
+	return n.queueEmail(ctx, emailChangeID("email-change-notice-", user), TemplateEmailChangeNotice, user, emailChangeEmailData{
+		Name:     user.Name,
+		NewEmail: user.PendingEmail,
+	})
+}
+
+// emailChangeID returns the outbox ID of an email about the user's pending email change
+func emailChangeID(prefix string, user *models.User) string {
+	id := prefix + user.ID
+	if user.EmailChangeSentAt != nil {
+		id += "-" + strconv.FormatInt(user.EmailChangeSentAt.Unix(), 10)
+	}
+	return id
+}
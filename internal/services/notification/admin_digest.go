@@ -0,0 +1,249 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+const (
+	// adminDigestListLimit caps the complaints listed per section; totals are always shown
+	adminDigestListLimit = 10
+	// adminDigestWeekday is the day weekly admin digests are sent on
+	adminDigestWeekday = time.Monday
+)
+
+// adminDigestComplaint is one complaint listed in an admin digest
+type adminDigestComplaint struct {
+	ID          string
+	Description string
+	StatusLabel string
+	LikeCount   int
+	CreatedAt   time.Time
+}
+
+// adminDigestSection is one list in an admin digest
+type adminDigestSection struct {
+	Total int
+	Items []adminDigestComplaint
+}
+
+// adminDigestData is the template data for admin digest emails
+type adminDigestData struct {
+	Name          string
+	Weekly        bool
+	From          time.Time
+	To            time.Time
+	SLAHours      int
+	NewComplaints adminDigestSection
+	StatusChanges adminDigestSection
+	OverdueSLA    adminDigestSection
+	MostLiked     adminDigestSection
+	AdminURL      string
+}
+
+// adminDigestWindow holds the complaints for one digest window, shared by all admins with the same frequency
+type adminDigestWindow struct {
+	from, to      time.Time
+	created       []models.Complaint
+	statusChanged []models.Complaint
+	overdue       []models.Complaint
+	mostLiked     []models.Complaint
+}
+
+// empty reports whether there is nothing to tell admins about
+func (w *adminDigestWindow) empty() bool {
+	return len(w.created) == 0 && len(w.statusChanged) == 0 && len(w.overdue) == 0 && len(w.mostLiked) == 0
+}
+
+// AdminDigestWorker sends admins a daily or weekly summary of complaint activity.
+// Digests are queued in the outbox under an ID derived from the admin and the run,
+// so catching up after a restart never sends the same digest twice.
+type AdminDigestWorker struct {
+	cosmosService *cosmos.Service
+	renderer      *Renderer
+	frontendURL   string
+	hour          int
+	sla           time.Duration
+	log           *slog.Logger
+}
+
+// NewAdminDigestWorker creates a new AdminDigestWorker that runs daily at the given UTC hour.
+// Pending complaints older than sla are reported as breaching it.
+func NewAdminDigestWorker(cosmosService *cosmos.Service, renderer *Renderer, frontendURL string, hour int, sla time.Duration, log *slog.Logger) *AdminDigestWorker {
+	const module = "adminDigestWorker"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &AdminDigestWorker{
+		cosmosService: cosmosService,
+		renderer:      renderer,
+		frontendURL:   frontendURL,
+		hour:          hour,
+		sla:           sla,
+		log:           log,
+	}
+}
+
+// Run catches up on the latest scheduled run, then sends digests every day at the configured hour until ctx is cancelled
+func (w *AdminDigestWorker) Run(ctx context.Context) {
+	w.log.Info("admin digest worker started", slog.Int("hourUTC", w.hour), slog.Duration("sla", w.sla))
+
+	// The app may have been down at the scheduled time; already queued digests are skipped
+	next := nextDigestTime(time.Now().UTC(), w.hour)
+	w.SendDigests(ctx, next.AddDate(0, 0, -1))
+
+	for {
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			w.log.Info("admin digest worker stopped")
+			return
+		case <-timer.C:
+		}
+
+		w.SendDigests(ctx, next)
+		next = nextDigestTime(time.Now().UTC(), w.hour)
+	}
+}
+
+// SendDigests queues the digests for the run at runAt to every admin whose frequency is due
+func (w *AdminDigestWorker) SendDigests(ctx context.Context, runAt time.Time) {
+	admins, err := w.cosmosService.GetUsersByRole(ctx, models.RoleAdmin)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.log.Error("failed to get admins", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	windows := make(map[string]*adminDigestWindow, 2)
+	for i := range admins {
+		if ctx.Err() != nil {
+			return
+		}
+		admin := &admins[i]
+
+		prefs, err := w.cosmosService.GetNotificationPreferences(ctx, admin.ID)
+		if err != nil {
+			w.log.Error("failed to get notification preferences", slog.String("userId", admin.ID), slog.String("error", err.Error()))
+			continue
+		}
+		frequency := prefs.AdminDigestFrequency()
+		from, due := adminDigestWindowStart(frequency, runAt)
+		if !due || admin.Email == "" {
+			continue
+		}
+
+		window, ok := windows[frequency]
+		if !ok {
+			window, err = w.loadWindow(ctx, from, runAt)
+			if err != nil {
+				w.log.Error("failed to load admin digest data", slog.String("frequency", frequency), slog.String("error", err.Error()))
+				return
+			}
+			windows[frequency] = window
+		}
+		if window.empty() {
+			continue
+		}
+
+		if err := w.sendDigest(ctx, admin, frequency, window); err != nil {
+			w.log.Error("failed to send admin digest", slog.String("userId", admin.ID), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// loadWindow fetches the complaint activity in [from, to)
+func (w *AdminDigestWorker) loadWindow(ctx context.Context, from, to time.Time) (*adminDigestWindow, error) {
+	window := &adminDigestWindow{from: from, to: to}
+
+	var err error
+	if window.created, err = w.cosmosService.GetComplaintsCreatedBetween(ctx, from, to); err != nil {
+		return nil, err
+	}
+	if window.statusChanged, err = w.cosmosService.GetComplaintsStatusChangedBetween(ctx, from, to); err != nil {
+		return nil, err
+	}
+	if window.overdue, err = w.cosmosService.GetPendingComplaintsCreatedBefore(ctx, to.Add(-w.sla)); err != nil {
+		return nil, err
+	}
+	if window.mostLiked, err = w.cosmosService.GetMostLikedPendingComplaints(ctx, adminDigestListLimit); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// sendDigest renders one admin's digest and queues it in the outbox
+func (w *AdminDigestWorker) sendDigest(ctx context.Context, admin *models.User, frequency string, window *adminDigestWindow) error {
+	data := adminDigestData{
+		Name:          admin.Name,
+		Weekly:        frequency == models.AdminDigestWeekly,
+		From:          window.from,
+		To:            window.to,
+		SLAHours:      int(w.sla.Hours()),
+		NewComplaints: w.section(window.created, admin.Locale),
+		StatusChanges: w.section(window.statusChanged, admin.Locale),
+		OverdueSLA:    w.section(window.overdue, admin.Locale),
+		MostLiked:     w.section(window.mostLiked, admin.Locale),
+		AdminURL:      w.frontendURL + "/admin/complaints",
+	}
+
+	email, err := w.renderer.Render(TemplateAdminDigest, admin.Locale, admin.Email, data)
+	if err != nil {
+		return err
+	}
+
+	err = w.cosmosService.EnqueueEmail(ctx, &models.OutboxEmail{
+		ID:       adminDigestID(admin.ID, frequency, window.to),
+		To:       email.To,
+		Subject:  email.Subject,
+		HTMLBody: email.HTMLBody,
+		TextBody: email.TextBody,
+	})
+	if errors.Is(err, cosmos.ErrEmailAlreadyQueued) {
+		w.log.Debug("admin digest already sent", slog.String("userId", admin.ID))
+		return nil
+	}
+	return err
+}
+
+// section builds a digest list from complaints in the admin's locale
+func (w *AdminDigestWorker) section(complaints []models.Complaint, locale string) adminDigestSection {
+	section := adminDigestSection{Total: len(complaints)}
+	for i := range complaints[:min(len(complaints), adminDigestListLimit)] {
+		complaint := &complaints[i]
+		section.Items = append(section.Items, adminDigestComplaint{
+			ID:          complaint.ID,
+			Description: truncate(complaint.Description, maxDescriptionLength),
+			StatusLabel: w.renderer.StatusLabel(locale, complaint.Status),
+			LikeCount:   complaint.LikeCount,
+			CreatedAt:   complaint.CreatedAt,
+		})
+	}
+	return section
+}
+
+// adminDigestWindowStart returns the start of the window covered by a run at runAt,
+// and whether admins with the given frequency get a digest from that run
+func adminDigestWindowStart(frequency string, runAt time.Time) (time.Time, bool) {
+	switch frequency {
+	case models.AdminDigestDaily:
+		return runAt.AddDate(0, 0, -1), true
+	case models.AdminDigestWeekly:
+		return runAt.AddDate(0, 0, -7), runAt.Weekday() == adminDigestWeekday
+	default:
+		return time.Time{}, false
+	}
+}
+
+// adminDigestID identifies the digest for one admin and run, used as the outbox email ID
+func adminDigestID(adminID, frequency string, runAt time.Time) string {
+	return "admin-digest-" + adminID + "-" + frequency + "-" + runAt.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,80 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminDigestWindowStart(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name         string
+		frequency    string
+		runAt        time.Time
+		expectedFrom time.Time
+		expectedDue  bool
+	}{
+		{name: "daily", frequency: models.AdminDigestDaily, runAt: tuesday, expectedFrom: monday, expectedDue: true},
+		{name: "weekly on monday", frequency: models.AdminDigestWeekly, runAt: monday, expectedFrom: monday.AddDate(0, 0, -7), expectedDue: true},
+		{name: "weekly on other days", frequency: models.AdminDigestWeekly, runAt: tuesday, expectedDue: false},
+		{name: "off", frequency: models.AdminDigestOff, runAt: monday, expectedDue: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, due := adminDigestWindowStart(tt.frequency, tt.runAt)
+			assert.Equal(t, tt.expectedDue, due)
+			if tt.expectedDue {
+				assert.Equal(t, tt.expectedFrom, from)
+			}
+		})
+	}
+}
+
+func TestAdminDigestID(t *testing.T) {
+	runAt := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	// The same run must always map to the same outbox email, so a restart does not send it again
+	assert.Equal(t, adminDigestID("admin-1", models.AdminDigestDaily, runAt), adminDigestID("admin-1", models.AdminDigestDaily, runAt))
+	assert.NotEqual(t, adminDigestID("admin-1", models.AdminDigestDaily, runAt), adminDigestID("admin-1", models.AdminDigestWeekly, runAt))
+	assert.NotEqual(t, adminDigestID("admin-1", models.AdminDigestDaily, runAt), adminDigestID("admin-1", models.AdminDigestDaily, runAt.AddDate(0, 0, 1)))
+}
+
+func TestRenderer_RenderAdminDigest(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	require.NoError(t, err)
+
+	worker := &AdminDigestWorker{renderer: renderer}
+	complaints := make([]models.Complaint, adminDigestListLimit+2)
+	for i := range complaints {
+		complaints[i] = models.Complaint{ID: "c", Description: "Broken heating", Status: models.StatusPending, LikeCount: 3}
+	}
+
+	data := adminDigestData{
+		Name:          "Admin",
+		Weekly:        true,
+		From:          time.Date(2026, 2, 23, 8, 0, 0, 0, time.UTC),
+		To:            time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+		SLAHours:      72,
+		NewComplaints: worker.section(complaints, "en"),
+		OverdueSLA:    worker.section(complaints[:1], "en"),
+		AdminURL:      "https://portal.example.edu/admin/complaints",
+	}
+	assert.Len(t, data.NewComplaints.Items, adminDigestListLimit)
+
+	email, err := renderer.Render(TemplateAdminDigest, "en", "admin@example.edu", data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Weekly complaint summary", email.Subject)
+	assert.Contains(t, email.TextBody, "New complaints: 12")
+	assert.Contains(t, email.TextBody, "...and 12 in total")
+	assert.Contains(t, email.TextBody, "Pending for more than 72 hours: 1")
+	assert.Contains(t, email.TextBody, "[pending review] Broken heating (3 likes")
+	assert.Contains(t, email.HTMLBody, "https://portal.example.edu/admin/complaints")
+}
@@ -0,0 +1,129 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+// digestData is the template data for digest emails
+type digestData struct {
+	Name          string
+	Items         []models.DigestItem
+	ComplaintsURL string
+}
+
+// DigestWorker sends each user one email per day with the notifications they chose to receive as a digest
+type DigestWorker struct {
+	cosmosService *cosmos.Service
+	renderer      *Renderer
+	frontendURL   string
+	hour          int
+	log           *slog.Logger
+}
+
+// NewDigestWorker creates a new DigestWorker that sends digests daily at the given UTC hour
+func NewDigestWorker(cosmosService *cosmos.Service, renderer *Renderer, frontendURL string, hour int, log *slog.Logger) *DigestWorker {
+	const module = "digestWorker"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &DigestWorker{
+		cosmosService: cosmosService,
+		renderer:      renderer,
+		frontendURL:   frontendURL,
+		hour:          hour,
+		log:           log,
+	}
+}
+
+// Run sends the digests every day at the configured hour until ctx is cancelled
+func (w *DigestWorker) Run(ctx context.Context) {
+	w.log.Info("digest worker started", slog.Int("hourUTC", w.hour))
+
+	for {
+		next := nextDigestTime(time.Now().UTC(), w.hour)
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			w.log.Info("digest worker stopped")
+			return
+		case <-timer.C:
+		}
+
+		w.SendDigests(ctx, next)
+	}
+}
+
+// SendDigests queues a digest email for every user with items created before the cutoff
+func (w *DigestWorker) SendDigests(ctx context.Context, cutoff time.Time) {
+	userIDs, err := w.cosmosService.GetDigestUserIDs(ctx, cutoff)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.log.Error("failed to get digest users", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := w.sendDigest(ctx, userID, cutoff); err != nil {
+			w.log.Error("failed to send digest", slog.String("userId", userID), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// sendDigest queues one user's digest and removes the items it contains
+func (w *DigestWorker) sendDigest(ctx context.Context, userID string, cutoff time.Time) error {
+	items, err := w.cosmosService.GetDigestItems(ctx, userID, cutoff)
+	if err != nil || len(items) == 0 {
+		return err
+	}
+
+	user, err := w.cosmosService.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user != nil && user.Email != "" {
+		email, err := w.renderer.Render(TemplateDigest, user.Locale, user.Email, digestData{
+			Name:          user.Name,
+			Items:         items,
+			ComplaintsURL: w.frontendURL + "/complaints",
+		})
+		if err != nil {
+			return err
+		}
+
+		// One digest per user and day, so a retried run does not queue it twice
+		err = w.cosmosService.EnqueueEmail(ctx, &models.OutboxEmail{
+			ID:       "digest-" + userID + "-" + cutoff.Format("2006-01-02"),
+			To:       email.To,
+			Subject:  email.Subject,
+			HTMLBody: email.HTMLBody,
+			TextBody: email.TextBody,
+		})
+		if err != nil && !errors.Is(err, cosmos.ErrEmailAlreadyQueued) {
+			return err
+		}
+	}
+
+	// Items of deleted users are dropped as well
+	return w.cosmosService.DeleteDigestItems(ctx, items)
+}
+
+// nextDigestTime returns the next time after now at the given UTC hour
+func nextDigestTime(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
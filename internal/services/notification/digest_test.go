@@ -0,0 +1,59 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextDigestTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{
+			name:     "later today",
+			now:      time.Date(2026, 3, 1, 6, 30, 0, 0, time.UTC),
+			expected: time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "exactly at the hour moves to tomorrow",
+			now:      time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+			expected: time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "end of month",
+			now:      time.Date(2026, 3, 31, 22, 0, 0, 0, time.UTC),
+			expected: time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, nextDigestTime(tt.now, 8))
+		})
+	}
+}
+
+func TestRenderer_RenderDigest(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	require.NoError(t, err)
+
+	email, err := renderer.Render(TemplateDigest, "pl", "jane@example.edu", digestData{
+		Name: "Jane",
+		Items: []models.DigestItem{
+			{Summary: "Your complaint is now approved", CreatedAt: time.Date(2026, 3, 1, 9, 15, 0, 0, time.UTC)},
+			{Summary: "Someone liked your complaint", CreatedAt: time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)},
+		},
+		ComplaintsURL: "https://portal.example.edu/complaints",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Twoje podsumowanie powiadomień", email.Subject)
+	assert.Contains(t, email.TextBody, "2026-03-01 09:15 UTC: Your complaint is now approved")
+	assert.Contains(t, email.HTMLBody, "Someone liked your complaint")
+}
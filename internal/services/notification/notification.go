@@ -0,0 +1,36 @@
+// Package notification renders and delivers notifications about complaint activity
+package notification
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Email is a rendered email ready to be sent
+type Email struct {
+	To       string
+	Subject  string
+	HTMLBody string
+	TextBody string
+}
+
+// Sender delivers rendered emails
+type Sender interface {
+	Send(ctx context.Context, email *Email) error
+}
+
+// LogSender is a Sender that only logs emails. It is used when no SMTP server is configured.
+type LogSender struct {
+	log *slog.Logger
+}
+
+// NewLogSender creates a new LogSender
+func NewLogSender(log *slog.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+// Send logs the email instead of delivering it
+func (s *LogSender) Send(_ context.Context, email *Email) error {
+	s.log.Warn("SMTP is not configured, email not delivered", slog.String("to", email.To), slog.String("subject", email.Subject))
+	return nil
+}
@@ -0,0 +1,322 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+// maxDescriptionLength is the number of characters of the complaint description quoted in emails
+const maxDescriptionLength = 200
+
+// Notifier turns complaint events into notifications for the affected users
+type Notifier struct {
+	cosmosService *cosmos.Service
+	renderer      *Renderer
+	frontendURL   string
+	log           *slog.Logger
+}
+
+// NewNotifier creates a new Notifier
+func NewNotifier(cosmosService *cosmos.Service, renderer *Renderer, frontendURL string, log *slog.Logger) *Notifier {
+	const module = "notifier"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &Notifier{
+		cosmosService: cosmosService,
+		renderer:      renderer,
+		frontendURL:   strings.TrimRight(frontendURL, "/"),
+		log:           log,
+	}
+}
+
+// complaintEmailData is the template data for emails about a single complaint
+type complaintEmailData struct {
+	Name          string
+	ComplaintID   string
+	Description   string
+	Status        string
+	StatusLabel   string
+	Comment       string
+	LikeCount     int
+	ComplaintsURL string
+}
+
+// delivery is one notification for a user, routed to the channels their preferences select
+type delivery struct {
+	key       string // Stable ID derived from the triggering event, so redelivered events do not notify twice
+	eventType string
+	user      *models.User
+	complaint *models.Complaint
+	actorID   string
+	message   string // Text for the inbox, digest and webhook
+	template  string // Email template
+	data      complaintEmailData
+	skipEmail bool // Set when the email channel is already covered by another delivery
+	createdAt time.Time
+}
+
+// HandleStatusChangedMessage handles a message from the complaint-status-changed queue, whose body is the complaint ID
+func (n *Notifier) HandleStatusChangedMessage(ctx context.Context, message *azservicebus.ReceivedMessage) error {
+	return n.NotifyStatusChanged(ctx, string(message.Body))
+}
+
+// NotifyStatusChanged tells the complaint owner about its new status and the admin comment added with it
+func (n *Notifier) NotifyStatusChanged(ctx context.Context, complaintID string) error {
+	complaint, err := n.cosmosService.GetComplaintByID(ctx, complaintID)
+	if err != nil {
+		n.log.Error("failed to get complaint for status notification", slog.String("complaintId", complaintID), slog.String("error", err.Error()))
+		return err
+	}
+	if complaint == nil {
+		// The complaint was deleted after its status changed, nobody to notify
+		n.log.Debug("complaint not found for status notification", slog.String("complaintId", complaintID))
+		return nil
+	}
+
+	user, prefs, err := n.recipient(ctx, complaint.UserID)
+	if err != nil || user == nil {
+		return err
+	}
+
+	eventKey := complaint.ID + "-" + strconv.FormatInt(complaint.StatusChangedAt.UnixNano(), 10)
+	comment := statusChangeComment(complaint)
+	data := n.complaintData(user, complaint)
+	if comment != nil {
+		data.Comment = comment.Content
+	}
+
+	if err := n.deliver(ctx, prefs, &delivery{
+		key:       "status-changed-" + eventKey,
+		eventType: models.EventStatusChanged,
+		user:      user,
+		complaint: complaint,
+		message:   "Your complaint is now " + n.renderer.StatusLabel("", complaint.Status),
+		template:  TemplateStatusChanged,
+		data:      data,
+		createdAt: complaint.StatusChangedAt,
+	}); err != nil {
+		return err
+	}
+
+	if comment != nil {
+		// The status change email already quotes the comment, so only email it separately when that one is off
+		if err := n.deliver(ctx, prefs, &delivery{
+			key:       "comment-" + comment.ID,
+			eventType: models.EventNewComment,
+			user:      user,
+			complaint: complaint,
+			actorID:   comment.AdminID,
+			message:   "An administrator commented on your complaint",
+			template:  TemplateNewComment,
+			data:      data,
+			skipEmail: prefs.For(models.EventStatusChanged).Has(models.ChannelEmail),
+			createdAt: comment.CreatedAt,
+		}); err != nil {
+			return err
+		}
+	}
+
+	n.log.Info("status change notifications delivered", slog.String("complaintId", complaintID), slog.String("userId", user.ID), slog.String("status", complaint.Status))
+	return nil
+}
+
+// HandleEscalationMessage handles a message from the complaint-escalations queue, whose body is the complaint ID.
+// The message is scheduled for when the complaint's review deadline passes.
+func (n *Notifier) HandleEscalationMessage(ctx context.Context, message *azservicebus.ReceivedMessage) error {
+	return n.NotifyEscalated(ctx, string(message.Body))
+}
+
+// NotifyEscalated tells the complaint owner that their complaint was not reviewed in time and was escalated.
+// Complaints that were reviewed meanwhile are skipped.
+func (n *Notifier) NotifyEscalated(ctx context.Context, complaintID string) error {
+	complaint, err := n.cosmosService.GetComplaintByID(ctx, complaintID)
+	if err != nil {
+		n.log.Error("failed to get complaint for escalation", slog.String("complaintId", complaintID), slog.String("error", err.Error()))
+		return err
+	}
+	if complaint == nil || complaint.Status != models.StatusPending {
+		n.log.Debug("complaint no longer awaits review, not escalated", slog.String("complaintId", complaintID))
+		return nil
+	}
+
+	user, prefs, err := n.recipient(ctx, complaint.UserID)
+	if err != nil || user == nil {
+		return err
+	}
+
+	if err := n.deliver(ctx, prefs, &delivery{
+		key:       "escalation-" + complaint.ID,
+		eventType: models.EventEscalation,
+		user:      user,
+		complaint: complaint,
+		message:   "Your complaint was not reviewed in time and was escalated to the administrators",
+		template:  TemplateEscalation,
+		data:      n.complaintData(user, complaint),
+		createdAt: time.Now().UTC(),
+	}); err != nil {
+		return err
+	}
+
+	n.log.Info("complaint escalated", slog.String("complaintId", complaintID), slog.String("userId", user.ID))
+	return nil
+}
+
+// NotifyLiked notifies the complaint owner when another user likes their complaint
+func (n *Notifier) NotifyLiked(ctx context.Context, complaint *models.Complaint, likerID string) error {
+	if complaint.UserID == likerID {
+		return nil
+	}
+
+	user, prefs, err := n.recipient(ctx, complaint.UserID)
+	if err != nil || user == nil {
+		return err
+	}
+
+	// One notification per liker, so unliking and liking again does not notify twice
+	return n.deliver(ctx, prefs, &delivery{
+		key:       "liked-" + complaint.ID + "-" + likerID,
+		eventType: models.EventComplaintLiked,
+		user:      user,
+		complaint: complaint,
+		actorID:   likerID,
+		message:   "Someone liked your complaint",
+		template:  TemplateComplaintLiked,
+		data:      n.complaintData(user, complaint),
+		createdAt: time.Now().UTC(),
+	})
+}
+
+// recipient loads the user to notify and their notification preferences.
+// A nil user without error means the user no longer exists.
+func (n *Notifier) recipient(ctx context.Context, userID string) (*models.User, *models.NotificationPreferences, error) {
+	user, err := n.cosmosService.GetUserByID(ctx, userID)
+	if err != nil {
+		n.log.Error("failed to get user to notify", slog.String("userId", userID), slog.String("error", err.Error()))
+		return nil, nil, err
+	}
+	if user == nil {
+		n.log.Debug("user to notify not found", slog.String("userId", userID))
+		return nil, nil, nil
+	}
+
+	prefs, err := n.cosmosService.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		n.log.Error("failed to get notification preferences", slog.String("userId", userID), slog.String("error", err.Error()))
+		return nil, nil, err
+	}
+	if prefs == nil {
+		prefs = models.DefaultNotificationPreferences(userID)
+	}
+
+	return user, prefs, nil
+}
+
+// complaintData builds the common email template data for a complaint
+func (n *Notifier) complaintData(user *models.User, complaint *models.Complaint) complaintEmailData {
+	return complaintEmailData{
+		Name:          user.Name,
+		ComplaintID:   complaint.ID,
+		Description:   truncate(complaint.Description, maxDescriptionLength),
+		Status:        complaint.Status,
+		StatusLabel:   n.renderer.StatusLabel(user.Locale, complaint.Status),
+		LikeCount:     complaint.LikeCount,
+		ComplaintsURL: n.frontendURL + "/complaints",
+	}
+}
+
+// deliver sends a notification through every channel the user enabled for its event type
+func (n *Notifier) deliver(ctx context.Context, prefs *models.NotificationPreferences, d *delivery) error {
+	pref := prefs.For(d.eventType)
+
+	if pref.Has(models.ChannelInApp) {
+		if err := n.cosmosService.CreateNotification(ctx, &models.Notification{
+			ID:          d.key,
+			UserID:      d.user.ID,
+			Type:        d.eventType,
+			ComplaintID: d.complaint.ID,
+			ActorID:     d.actorID,
+			Status:      d.complaint.Status,
+			Message:     d.message,
+			CreatedAt:   d.createdAt,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if pref.Has(models.ChannelEmail) && !d.skipEmail && d.user.Email != "" {
+		if pref.IsDigest() {
+			if err := n.cosmosService.AddDigestItem(ctx, &models.DigestItem{
+				ID:          d.key,
+				UserID:      d.user.ID,
+				Type:        d.eventType,
+				ComplaintID: d.complaint.ID,
+				Summary:     d.message,
+				CreatedAt:   d.createdAt,
+			}); err != nil {
+				return err
+			}
+		} else if err := n.queueEmail(ctx, d.key, d.template, d.user, d.data); err != nil {
+			return err
+		}
+	}
+
+	if pref.Has(models.ChannelWebhook) && prefs.WebhookURL != "" {
+		if err := n.queueWebhook(ctx, prefs.WebhookURL, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queueEmail renders the named template for the user and puts it in the outbox under the given ID
+func (n *Notifier) queueEmail(ctx context.Context, id, templateName string, user *models.User, data any) error {
+	email, err := n.renderer.Render(templateName, user.Locale, user.Email, data)
+	if err != nil {
+		n.log.Error("failed to render email", slog.String("template", templateName), slog.String("userId", user.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	if err := n.cosmosService.EnqueueEmail(ctx, &models.OutboxEmail{
+		ID:       id,
+		To:       email.To,
+		Subject:  email.Subject,
+		HTMLBody: email.HTMLBody,
+		TextBody: email.TextBody,
+	}); err != nil {
+		if errors.Is(err, cosmos.ErrEmailAlreadyQueued) {
+			return nil
+		}
+		n.log.Error("failed to queue email", slog.String("template", templateName), slog.String("userId", user.ID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// statusChangeComment returns the admin comment added together with the latest status change, if any
+func statusChangeComment(complaint *models.Complaint) *models.Comment {
+	for i := len(complaint.Comments) - 1; i >= 0; i-- {
+		if !complaint.Comments[i].CreatedAt.Before(complaint.StatusChangedAt) {
+			return &complaint.Comments[i]
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most maxLength characters, adding an ellipsis when it was cut
+func truncate(s string, maxLength int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLength {
+		return s
+	}
+	return string(runes[:maxLength]) + "…"
+}
@@ -0,0 +1,154 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+const (
+	// outboxBatchSize is the maximum number of emails sent per poll
+	outboxBatchSize = 20
+	// outboxLease is how long a worker owns an email while sending it; expired leases are picked up again
+	outboxLease = 2 * time.Minute
+	// outboxSendTimeout bounds a single delivery attempt
+	outboxSendTimeout = 30 * time.Second
+	// Retry delays grow exponentially from outboxBaseRetryDelay up to outboxMaxRetryDelay
+	outboxBaseRetryDelay = 30 * time.Second
+	outboxMaxRetryDelay  = time.Hour
+	// outboxRetention is how long sent and failed emails are kept without their bodies, so an event processed
+	// again still finds its email and is not sent twice
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// Outbox periodically delivers queued emails and retries failed deliveries with exponential backoff
+type Outbox struct {
+	cosmosService *cosmos.Service
+	sender        Sender
+	interval      time.Duration
+	maxAttempts   int
+	log           *slog.Logger
+}
+
+// NewOutbox creates a new Outbox worker
+func NewOutbox(cosmosService *cosmos.Service, sender Sender, interval time.Duration, maxAttempts int, log *slog.Logger) *Outbox {
+	const module = "emailOutbox"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &Outbox{
+		cosmosService: cosmosService,
+		sender:        sender,
+		interval:      interval,
+		maxAttempts:   maxAttempts,
+		log:           log,
+	}
+}
+
+// Run delivers due emails every interval until ctx is cancelled
+func (o *Outbox) Run(ctx context.Context) {
+	o.log.Info("email outbox worker started", slog.Duration("interval", o.interval))
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		o.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			o.log.Info("email outbox worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue sends one batch of due emails
+func (o *Outbox) deliverDue(ctx context.Context) {
+	emails, err := o.cosmosService.GetDueOutboxEmails(ctx, time.Now(), outboxBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			o.log.Error("failed to get due outbox emails", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	for i := range emails {
+		if ctx.Err() != nil {
+			return
+		}
+		o.deliver(ctx, &emails[i])
+	}
+}
+
+// deliver claims a single email, sends it and records the outcome
+func (o *Outbox) deliver(ctx context.Context, email *models.OutboxEmail) {
+	// Claim the email so that other instances skip it while it is being sent
+	email.Status = models.EmailStatusSending
+	email.LockedUntil = time.Now().UTC().Add(outboxLease)
+	if err := o.cosmosService.UpdateOutboxEmail(ctx, email); err != nil {
+		if !errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			o.log.Error("failed to claim outbox email", slog.String("emailId", email.ID), slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	err := o.sender.Send(sendCtx, &Email{
+		To:       email.To,
+		Subject:  email.Subject,
+		HTMLBody: email.HTMLBody,
+		TextBody: email.TextBody,
+	})
+	cancel()
+
+	now := time.Now().UTC()
+	email.Attempts++
+	email.LockedUntil = time.Time{}
+	if err != nil {
+		email.LastError = err.Error()
+		if email.Attempts >= o.maxAttempts {
+			email.Status = models.EmailStatusFailed
+			o.log.Error("giving up on outbox email", slog.String("emailId", email.ID), slog.Int("attempts", email.Attempts), slog.String("error", err.Error()))
+		} else {
+			email.Status = models.EmailStatusPending
+			email.NextAttemptAt = now.Add(retryDelay(email.Attempts))
+			o.log.Warn("failed to send outbox email, will retry", slog.String("emailId", email.ID), slog.Int("attempts", email.Attempts), slog.Time("nextAttemptAt", email.NextAttemptAt), slog.String("error", err.Error()))
+		}
+	} else {
+		email.Status = models.EmailStatusSent
+		email.SentAt = now
+		email.LastError = ""
+		o.log.Info("outbox email sent", slog.String("emailId", email.ID), slog.Int("attempts", email.Attempts))
+	}
+	// The bodies carry reset, verification and email change links, so they are not kept once the email is done
+	if email.Status != models.EmailStatusPending {
+		email.HTMLBody = ""
+		email.TextBody = ""
+		email.TTL = int(outboxRetention.Seconds())
+	}
+
+	// Record the outcome even if shutdown has started, otherwise a sent email would be sent again
+	updateCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := o.cosmosService.UpdateOutboxEmail(updateCtx, email); err != nil {
+		o.log.Error("failed to record outbox email delivery", slog.String("emailId", email.ID), slog.String("status", email.Status), slog.String("error", err.Error()))
+	}
+}
+
+// retryDelay returns the backoff before the next attempt after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := outboxBaseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxMaxRetryDelay {
+			return outboxMaxRetryDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,29 @@
+package notification
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 30 * time.Second},
+		{attempts: 2, expected: time.Minute},
+		{attempts: 3, expected: 2 * time.Minute},
+		{attempts: 5, expected: 8 * time.Minute},
+		{attempts: 8, expected: time.Hour},
+		{attempts: 50, expected: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run("attempts="+strconv.Itoa(tt.attempts), func(t *testing.T) {
+			assert.Equal(t, tt.expected, retryDelay(tt.attempts))
+		})
+	}
+}
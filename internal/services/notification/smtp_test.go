@@ -0,0 +1,188 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedMail is a message accepted by smtpSink
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpSink is a minimal local SMTP server that accepts every message
+type smtpSink struct {
+	listener net.Listener
+	messages chan receivedMail
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sink := &smtpSink{listener: listener, messages: make(chan receivedMail, 10)}
+	go sink.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return sink
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP sink")
+	var current receivedMail
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			current = receivedMail{from: strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			current.to = append(current.to, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			current.data = data.String()
+			s.messages <- current
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	sink := newSMTPSink(t)
+	sender := NewSMTPSender("127.0.0.1", sink.port(), "", "", "Portal <no-reply@portal.example.edu>")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := sender.Send(ctx, &Email{
+		To:       "jane@example.edu",
+		Subject:  "Статус вашої скарги: схвалено",
+		HTMLBody: "<p>Hello Jane</p>",
+		TextBody: "Hello Jane",
+	})
+	require.NoError(t, err)
+
+	var received receivedMail
+	select {
+	case received = <-sink.messages:
+	case <-ctx.Done():
+		t.Fatal("sink did not receive the message")
+	}
+
+	assert.Equal(t, "no-reply@portal.example.edu", received.from)
+	assert.Equal(t, []string{"jane@example.edu"}, received.to)
+
+	message, err := mail.ReadMessage(strings.NewReader(received.data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Статус вашої скарги: схвалено", subject)
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	var contentTypes, bodies []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		contentTypes = append(contentTypes, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+
+	assert.Equal(t, []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"}, contentTypes)
+	assert.Equal(t, []string{"Hello Jane", "<p>Hello Jane</p>"}, bodies)
+}
+
+func TestSMTPSender_InvalidAddresses(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{name: "invalid sender", from: "not an address", to: "jane@example.edu"},
+		{name: "invalid recipient", from: "no-reply@portal.example.edu", to: "not an address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := NewSMTPSender("127.0.0.1", 1, "", "", tt.from)
+			err := sender.Send(context.Background(), &Email{To: tt.to, Subject: "subject"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSMTPSender_ServerUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	sender := NewSMTPSender("127.0.0.1", port, "", "", "no-reply@portal.example.edu")
+	err = sender.Send(context.Background(), &Email{To: "jane@example.edu", Subject: "subject"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.html templates/*.txt
+var templateFS embed.FS
+
+// Template names
+const (
+	TemplateStatusChanged     = "status_changed"
+	TemplateNewComment        = "new_comment"
+	TemplateComplaintLiked    = "complaint_liked"
+	TemplateEscalation        = "escalation"
+	TemplateDigest            = "digest"
+	TemplateAdminDigest       = "admin_digest"
+	TemplatePasswordReset     = "password_reset"
+	TemplateEmailVerification = "email_verification"
+	TemplateAccountRestricted = "account_restricted"
+	TemplateEmailChange       = "email_change"
+	TemplateEmailChangeNotice = "email_change_notice"
+)
+
+// subjects holds the localized subject line templates for each email template
+var subjects = map[string]map[string]string{
+	"en": {
+		TemplateStatusChanged:     "Your complaint is now {{.StatusLabel}}",
+		TemplateNewComment:        "New comment on your complaint",
+		TemplateComplaintLiked:    "Someone liked your complaint",
+		TemplateEscalation:        "Your complaint was escalated",
+		TemplateDigest:            "Your notification digest",
+		TemplateAdminDigest:       "{{if .Weekly}}Weekly{{else}}Daily{{end}} complaint summary",
+		TemplatePasswordReset:     "Reset your password",
+		TemplateEmailVerification: "Verify your email address",
+		TemplateAccountRestricted: "{{if eq .Kind \"suspension\"}}Your account has been suspended{{else}}Your account has been restricted{{end}}",
+		TemplateEmailChange:       "Confirm your new email address",
+		TemplateEmailChangeNotice: "Your email address is being changed",
+	},
+	"uk": {
+		TemplateStatusChanged:     "Статус вашої скарги: {{.StatusLabel}}",
+		TemplateNewComment:        "Новий коментар до вашої скарги",
+		TemplateComplaintLiked:    "Вашу скаргу вподобали",
+		TemplateEscalation:        "Вашу скаргу передано адміністраторам",
+		TemplateDigest:            "Ваш дайджест сповіщень",
+		TemplateAdminDigest:       "{{if .Weekly}}Тижневий{{else}}Щоденний{{end}} підсумок скарг",
+		TemplatePasswordReset:     "Скидання пароля",
+		TemplateEmailVerification: "Підтвердьте адресу електронної пошти",
+		TemplateAccountRestricted: "{{if eq .Kind \"suspension\"}}Ваш обліковий запис заблоковано{{else}}Ваш обліковий запис обмежено{{end}}",
+		TemplateEmailChange:       "Підтвердьте нову адресу електронної пошти",
+		TemplateEmailChangeNotice: "Адресу вашої електронної пошти змінюють",
+	},
+	"pl": {
+		TemplateStatusChanged:     "Status Twojej skargi: {{.StatusLabel}}",
+		TemplateNewComment:        "Nowy komentarz do Twojej skargi",
+		TemplateComplaintLiked:    "Ktoś polubił Twoją skargę",
+		TemplateEscalation:        "Twoja skarga została eskalowana",
+		TemplateDigest:            "Twoje podsumowanie powiadomień",
+		TemplateAdminDigest:       "{{if .Weekly}}Tygodniowe{{else}}Dzienne{{end}} podsumowanie skarg",
+		TemplatePasswordReset:     "Resetowanie hasła",
+		TemplateEmailVerification: "Potwierdź adres e-mail",
+		TemplateAccountRestricted: "{{if eq .Kind \"suspension\"}}Twoje konto zostało zawieszone{{else}}Twoje konto zostało ograniczone{{end}}",
+		TemplateEmailChange:       "Potwierdź nowy adres e-mail",
+		TemplateEmailChangeNotice: "Twój adres e-mail jest zmieniany",
+	},
+}
+
+// statusLabels holds the localized, human-readable complaint statuses
+var statusLabels = map[string]map[string]string{
+	"en": {
+		"pending":  "pending review",
+		"approved": "approved",
+		"rejected": "rejected",
+	},
+	"uk": {
+		"pending":  "на розгляді",
+		"approved": "схвалено",
+		"rejected": "відхилено",
+	},
+	"pl": {
+		"pending":  "w trakcie rozpatrywania",
+		"approved": "zatwierdzona",
+		"rejected": "odrzucona",
+	},
+}
+
+// Renderer renders emails from the embedded HTML and text templates
+type Renderer struct {
+	html          *htmltemplate.Template
+	text          *texttemplate.Template
+	subjects      map[string]map[string]*texttemplate.Template
+	defaultLocale string
+}
+
+// NewRenderer parses the embedded templates. Subjects fall back to defaultLocale for unsupported languages.
+func NewRenderer(defaultLocale string) (*Renderer, error) {
+	if _, ok := subjects[defaultLocale]; !ok {
+		return nil, fmt.Errorf("unsupported default locale %q", defaultLocale)
+	}
+
+	html, err := htmltemplate.ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := texttemplate.ParseFS(templateFS, "templates/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	parsedSubjects := make(map[string]map[string]*texttemplate.Template, len(subjects))
+	for locale, bySubject := range subjects {
+		parsedSubjects[locale] = make(map[string]*texttemplate.Template, len(bySubject))
+		for name, subject := range bySubject {
+			tmpl, err := texttemplate.New(locale + "/" + name).Parse(subject)
+			if err != nil {
+				return nil, err
+			}
+			parsedSubjects[locale][name] = tmpl
+		}
+	}
+
+	return &Renderer{
+		html:          html,
+		text:          text,
+		subjects:      parsedSubjects,
+		defaultLocale: defaultLocale,
+	}, nil
+}
+
+// Render renders the named template for the recipient's locale
+func (r *Renderer) Render(name, locale, to string, data any) (*Email, error) {
+	subjectTmpl, ok := r.subjects[r.ResolveLocale(locale)][name]
+	if !ok {
+		subjectTmpl, ok = r.subjects[r.defaultLocale][name]
+		if !ok {
+			return nil, fmt.Errorf("no subject defined for template %q", name)
+		}
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := r.html.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return nil, err
+	}
+	if err := r.text.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return nil, err
+	}
+
+	return &Email{
+		To:       to,
+		Subject:  subject.String(),
+		HTMLBody: html.String(),
+		TextBody: text.String(),
+	}, nil
+}
+
+// ResolveLocale maps a user locale such as "uk-UA" to a supported locale, falling back to the default
+func (r *Renderer) ResolveLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if _, ok := subjects[locale]; ok {
+		return locale
+	}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		if _, ok := subjects[base]; ok {
+			return base
+		}
+	}
+	return r.defaultLocale
+}
+
+// StatusLabel returns the localized label for a complaint status
+func (r *Renderer) StatusLabel(locale, status string) string {
+	if label, ok := statusLabels[r.ResolveLocale(locale)][status]; ok {
+		return label
+	}
+	return status
+}
@@ -0,0 +1,236 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRenderer_UnsupportedDefaultLocale(t *testing.T) {
+	renderer, err := NewRenderer("xx")
+	assert.Error(t, err)
+	assert.Nil(t, renderer)
+}
+
+func TestRenderer_ResolveLocale(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		locale   string
+		expected string
+	}{
+		{name: "supported locale", locale: "uk", expected: "uk"},
+		{name: "region variant", locale: "pl-PL", expected: "pl"},
+		{name: "mixed case", locale: "UK-ua", expected: "uk"},
+		{name: "unsupported locale", locale: "de", expected: "en"},
+		{name: "empty locale", locale: "", expected: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, renderer.ResolveLocale(tt.locale))
+		})
+	}
+}
+
+func TestRenderer_RenderStatusChanged(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		locale          string
+		status          string
+		comment         string
+		expectedSubject string
+	}{
+		{
+			name:            "english subject with comment",
+			locale:          "en",
+			status:          models.StatusApproved,
+			comment:         "We will fix the heating next week",
+			expectedSubject: "Your complaint is now approved",
+		},
+		{
+			name:            "ukrainian subject",
+			locale:          "uk",
+			status:          models.StatusRejected,
+			expectedSubject: "Статус вашої скарги: відхилено",
+		},
+		{
+			name:            "unknown locale falls back to english",
+			locale:          "fr",
+			status:          models.StatusPending,
+			expectedSubject: "Your complaint is now pending review",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := complaintEmailData{
+				Name:          "Jane <Doe>",
+				ComplaintID:   "complaint-1",
+				Description:   "The heating in dorm B is broken",
+				Status:        tt.status,
+				StatusLabel:   renderer.StatusLabel(tt.locale, tt.status),
+				Comment:       tt.comment,
+				ComplaintsURL: "https://portal.example.edu/complaints",
+			}
+
+			email, err := renderer.Render(TemplateStatusChanged, tt.locale, "jane@example.edu", data)
+			require.NoError(t, err)
+
+			assert.Equal(t, "jane@example.edu", email.To)
+			assert.Equal(t, tt.expectedSubject, email.Subject)
+			assert.Contains(t, email.TextBody, "The heating in dorm B is broken")
+			assert.Contains(t, email.HTMLBody, "https://portal.example.edu/complaints")
+			// HTML output must be escaped, text output must not
+			assert.Contains(t, email.HTMLBody, "Jane &lt;Doe&gt;")
+			assert.Contains(t, email.TextBody, "Jane <Doe>")
+
+			if tt.comment != "" {
+				assert.Contains(t, email.TextBody, tt.comment)
+				assert.Contains(t, email.HTMLBody, tt.comment)
+			} else {
+				assert.NotContains(t, email.TextBody, "Comment from the administrator")
+			}
+		})
+	}
+}
+
+func TestRenderer_RenderEscalation(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	require.NoError(t, err)
+
+	email, err := renderer.Render(TemplateEscalation, "uk", "jane@example.edu", complaintEmailData{
+		Name:          "Jane",
+		Description:   "Broken heating in dorm 3",
+		ComplaintsURL: "https://portal.example.edu/complaints",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Вашу скаргу передано адміністраторам", email.Subject)
+	assert.Contains(t, email.TextBody, "escalated to the administrators")
+	assert.Contains(t, email.HTMLBody, "Broken heating in dorm 3")
+}
+
+func TestRenderer_RenderPasswordReset(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	require.NoError(t, err)
+
+	data := accountEmailData{
+		Name:      "Jane",
+		ActionURL: "https://portal.example.edu/reset-password?token=ABC&x=1",
+		ExpiresIn: 60,
+	}
+
+	email, err := renderer.Render(TemplatePasswordReset, "pl", "jane@example.edu", data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Resetowanie hasła", email.Subject)
+	assert.Contains(t, email.TextBody, "https://portal.example.edu/reset-password?token=ABC&x=1")
+	assert.Contains(t, email.HTMLBody, `href="https://portal.example.edu/reset-password?token=ABC&amp;x=1"`)
+	assert.Contains(t, email.TextBody, "expires in 60 minutes")
+}
+
+func TestRenderer_RenderAccountRestricted(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	require.NoError(t, err)
+
+	email, err := renderer.Render(TemplateAccountRestricted, "en", "jane@example.edu", restrictionEmailData{
+		Name:   "Jane",
+		Kind:   models.RestrictionPosting,
+		Reason: "Repeated spam",
+		Until:  "1 March 2026 12:00 UTC",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Your account has been restricted", email.Subject)
+	assert.Contains(t, email.TextBody, "You can no longer submit complaints until 1 March 2026 12:00 UTC.")
+	assert.Contains(t, email.TextBody, "Reason: Repeated spam")
+
+	email, err = renderer.Render(TemplateAccountRestricted, "uk", "jane@example.edu", restrictionEmailData{Name: "Jane", Kind: models.RestrictionSuspension})
+	require.NoError(t, err)
+	assert.Equal(t, "Ваш обліковий запис заблоковано", email.Subject)
+	assert.Contains(t, email.HTMLBody, "until an administrator lifts the restriction")
+	assert.NotContains(t, email.TextBody, "Reason:")
+}
+
+func TestRenderer_RenderEmailChange(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	require.NoError(t, err)
+
+	email, err := renderer.Render(TemplateEmailChange, "en", "jane.new@example.edu", accountEmailData{
+		Name:      "Jane",
+		ActionURL: "https://portal.example.edu/confirm-email?token=ABC",
+		ExpiresIn: 2880,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "jane.new@example.edu", email.To)
+	assert.Equal(t, "Confirm your new email address", email.Subject)
+	assert.Contains(t, email.TextBody, "https://portal.example.edu/confirm-email?token=ABC")
+
+	email, err = renderer.Render(TemplateEmailChangeNotice, "uk", "jane@example.edu", emailChangeEmailData{Name: "Jane", NewEmail: "jane.new@example.edu"})
+	require.NoError(t, err)
+	assert.Equal(t, "Адресу вашої електронної пошти змінюють", email.Subject)
+	assert.Contains(t, email.HTMLBody, "jane.new@example.edu")
+}
+
+func TestStatusChangeComment(t *testing.T) {
+	changedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		complaint *models.Complaint
+		expected  string
+	}{
+		{
+			name:      "no comments",
+			complaint: &models.Complaint{StatusChangedAt: changedAt},
+			expected:  "",
+		},
+		{
+			name: "comment added with the status change",
+			complaint: &models.Complaint{
+				StatusChangedAt: changedAt,
+				Comments: []models.Comment{
+					{Content: "old comment", CreatedAt: changedAt.Add(-time.Hour)},
+					{Content: "new comment", CreatedAt: changedAt},
+				},
+			},
+			expected: "new comment",
+		},
+		{
+			name: "only older comments",
+			complaint: &models.Complaint{
+				StatusChangedAt: changedAt,
+				Comments: []models.Comment{
+					{Content: "old comment", CreatedAt: changedAt.Add(-time.Hour)},
+				},
+			},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := statusChangeComment(tt.complaint)
+			if tt.expected == "" {
+				assert.Nil(t, comment)
+				return
+			}
+			if assert.NotNil(t, comment) {
+				assert.Equal(t, tt.expected, comment.Content)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "абв…", truncate("абвгд", 3))
+}
@@ -0,0 +1,57 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+)
+
+// webhookPayload is the JSON body posted to a user's webhook URL
+type webhookPayload struct {
+	ID          string    `json:"id"`
+	Event       string    `json:"event"`
+	ComplaintID string    `json:"complaintId,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Message     string    `json:"message"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// queueWebhook stores the notification as a delivery to the user's webhook URL.
+// The webhook worker sends it in the background, so a slow receiver does not hold up event processing.
+func (n *Notifier) queueWebhook(ctx context.Context, url string, d *delivery) error {
+	payload, err := json.Marshal(&webhookPayload{
+		ID:          d.key,
+		Event:       d.eventType,
+		ComplaintID: d.complaint.ID,
+		Status:      d.complaint.Status,
+		Message:     d.message,
+		CreatedAt:   d.createdAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if err := n.cosmosService.CreateWebhookDelivery(ctx, &models.WebhookDelivery{
+		ID:             d.key,
+		SubscriptionID: models.UserWebhookID(d.user.ID),
+		URL:            url,
+		Event:          d.eventType,
+		Payload:        string(payload),
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}); err != nil {
+		if errors.Is(err, cosmos.ErrDeliveryAlreadyQueued) {
+			return nil
+		}
+		n.log.Error("failed to queue user webhook", slog.String("userId", d.user.ID), slog.String("event", d.eventType), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+// Package oidc signs users in with an OpenID Connect identity provider using the authorization code flow with PKCE
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keyRefreshInterval is the minimum time between two JWKS downloads caused by unknown key IDs
+const keyRefreshInterval = time.Minute
+
+var (
+	ErrNotAllowed      = errors.New("user is not in a group allowed to sign in")
+	ErrInvalidIDToken  = errors.New("invalid ID token")
+	ErrInvalidState    = errors.New("invalid login state")
+	errMissingMetadata = errors.New("provider metadata is incomplete")
+)
+
+// Config configures a Provider
+type Config struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string   // Empty for public clients, which rely on PKCE alone
+	RedirectURL   string   // The portal's callback URL registered at the provider
+	Scopes        []string // Requested in addition to "openid"
+	GroupsClaim   string   // ID token claim listing the user's groups
+	AdminGroups   []string // Members of these groups become admins
+	StudentGroups []string // Members of these groups may sign in as students; empty allows everyone else
+}
+
+// Provider is an OpenID Connect identity provider. Its metadata and keys are discovered on first use,
+// so the portal starts even while the provider is unreachable.
+type Provider struct {
+	config Config
+	client *http.Client
+	log    *slog.Logger
+
+	mu            sync.Mutex
+	metadata      *metadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// metadata is the part of the discovery document the portal uses
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Identity is the verified user information from an ID token
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool // Only set if the provider sends email_verified, which some (such as Azure AD) never do
+	Name          string
+	Username      string
+	Groups        []string
+	MFA           bool // The provider reports a multi-factor login in the amr claim
+}
+
+// LoginState is kept by the browser between the redirect to the provider and the callback
+type LoginState struct {
+	State    string // Returned by the provider, protects the callback against CSRF
+	Nonce    string // Must be echoed in the ID token, protects against token replay
+	Verifier string // PKCE code verifier, only its hash is sent with the authorization request
+}
+
+// NewProvider creates a Provider. A nil client uses a client with a 10 second timeout.
+func NewProvider(config Config, client *http.Client, log *slog.Logger) (*Provider, error) {
+	if config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC issuer URL, client ID and redirect URL are required")
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	const module = "oidc"
+	return &Provider{
+		config: config,
+		client: client,
+		log:    log.With(slog.String("module", module)),
+	}, nil
+}
+
+// NewLoginState returns random values for one login
+func NewLoginState() LoginState {
+	return LoginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+	}
+}
+
+// Encode returns the state as a cookie value
+func (s LoginState) Encode() string {
+	return s.State + "." + s.Nonce + "." + s.Verifier
+}
+
+// DecodeLoginState parses a value returned by Encode
+func DecodeLoginState(value string) (LoginState, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return LoginState{}, ErrInvalidState
+	}
+	return LoginState{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, nil
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to for login
+func (p *Provider) AuthCodeURL(ctx context.Context, state LoginState) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(append([]string{"openid"}, p.config.Scopes...), " "))
+	params.Set("state", state.State)
+	params.Set("nonce", state.Nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return meta.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// tokenResponse is the token endpoint response
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems the authorization code from the callback and returns the verified identity
+func (p *Provider) Exchange(ctx context.Context, code string, state LoginState) (*Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", state.Verifier)
+	form.Set("client_id", p.config.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		// client_secret_basic, both parts form-encoded as RFC 6749 section 2.3.1 requires
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("token response: status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("token request failed: status %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no ID token", ErrInvalidIDToken)
+	}
+
+	return p.verify(ctx, meta, token.IDToken, state.Nonce)
+}
+
+// Role returns the portal role for the groups of a user, or ErrNotAllowed
+func (p *Provider) Role(groups []string) (string, error) {
+	inAny := func(allowed []string) bool {
+		return slices.ContainsFunc(groups, func(group string) bool { return slices.Contains(allowed, group) })
+	}
+	switch {
+	case inAny(p.config.AdminGroups):
+		return models.RoleAdmin, nil
+	case len(p.config.StudentGroups) == 0 || inAny(p.config.StudentGroups):
+		return models.RoleStudent, nil
+	default:
+		return "", ErrNotAllowed
+	}
+}
+
+// verify checks the signature, issuer, audience, expiry and nonce of an ID token and returns its identity
+func (p *Provider) verify(ctx context.Context, meta *metadata, idToken, nonce string) (*Identity, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, meta, kid)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	identity := &Identity{
+		Subject:  subject,
+		Email:    stringClaim(claims, "email"),
+		Name:     stringClaim(claims, "name"),
+		Username: stringClaim(claims, "preferred_username"),
+		Groups:   stringsClaim(claims, p.config.GroupsClaim),
+	}
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	for _, method := range stringsClaim(claims, "amr") {
+		if method == "mfa" || method == "otp" || method == "hwk" {
+			identity.MFA = true
+		}
+	}
+	return identity, nil
+}
+
+// discover downloads the discovery document once
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var meta metadata
+	issuer := strings.TrimSuffix(p.config.IssuerURL, "/")
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", meta.Issuer, p.config.IssuerURL)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errMissingMetadata
+	}
+
+	p.log.Info("OIDC provider discovered", slog.String("issuer", meta.Issuer))
+	p.metadata = &meta
+	return p.metadata, nil
+}
+
+// jwk is a JSON Web Key of the provider
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// key returns the provider's public key with the ID, downloading the JWKS again when the key is unknown,
+// which is how providers announce rotated keys
+func (p *Provider) key(ctx context.Context, meta *metadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	p.keysFetchedAt = time.Now()
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(k)
+		if err != nil {
+			p.log.Warn("skipping unsupported provider key", slog.String("kid", k.KeyID), slog.String("error", err.Error()))
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	p.keys = keys
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key ID %q", kid)
+}
+
+// getJSON downloads and decodes a JSON document
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// parseJWK converts an RSA, P-256 or Ed25519 JSON Web Key into a public key
+func parseJWK(k jwk) (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		// Uncompressed point encoding, which ecdsa.ParseUncompressedPublicKey validates
+		point := append([]byte{4}, append(leftPad(x, 32), leftPad(y, 32)...)...)
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// leftPad pads b with leading zeros to size bytes
+func leftPad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// stringClaim returns a string claim, or ""
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// stringsClaim returns a claim that is a list of strings or a single string
+func stringsClaim(claims jwt.MapClaims, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// randomString returns 32 random bytes, base64url encoded (43 characters, as PKCE verifiers require)
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,280 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockProvider is a local OpenID Connect provider that issues ID tokens for the claims set by the test
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]authorization
+	claims jwt.MapClaims // Extra claims of the next ID token
+}
+
+// authorization is a code issued by the mock's authorization endpoint
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockProvider{key: key, codes: make(map[string]authorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// authorize plays the user logging in at the provider and returns the code sent to the callback
+func (m *mockProvider) authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+	require.Equal(t, "code", query.Get("response_type"))
+
+	code = "code-" + query.Get("state")[:8]
+	m.mu.Lock()
+	m.codes[code] = authorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	m.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// token is the mock's token endpoint. It checks the client credentials and the PKCE verifier.
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if id != "portal" || secret != "s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	auth, ok := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	extra := m.claims
+	m.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.server.URL,
+		"aud":   "portal",
+		"sub":   "campus-123",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": auth.nonce,
+		"email": "student@university.edu",
+		"name":  "Test Student",
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "mock-key"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+}
+
+func newTestProvider(t *testing.T, issuer string) *Provider {
+	t.Helper()
+	provider, err := NewProvider(Config{
+		IssuerURL:     issuer,
+		ClientID:      "portal",
+		ClientSecret:  "s3cret",
+		RedirectURL:   "https://portal.example/api/auth/oidc/callback",
+		Scopes:        []string{"email", "profile"},
+		AdminGroups:   []string{"portal-admins"},
+		StudentGroups: []string{"students"},
+	}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	return provider
+}
+
+func TestProviderLogin(t *testing.T) {
+	ctx := context.Background()
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock.server.URL)
+
+	mock.claims = jwt.MapClaims{"groups": []string{"students"}, "email_verified": true, "amr": []string{"pwd", "mfa"}}
+	state := NewLoginState()
+	authURL, err := provider.AuthCodeURL(ctx, state)
+	require.NoError(t, err)
+	assert.Contains(t, authURL, mock.server.URL+"/authorize?")
+	assert.Contains(t, authURL, "scope=openid+email+profile")
+
+	code, returnedState := mock.authorize(t, authURL)
+	assert.Equal(t, state.State, returnedState)
+
+	identity, err := provider.Exchange(ctx, code, state)
+	require.NoError(t, err)
+	assert.Equal(t, "campus-123", identity.Subject)
+	assert.Equal(t, "student@university.edu", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "Test Student", identity.Name)
+	assert.Equal(t, []string{"students"}, identity.Groups)
+	assert.True(t, identity.MFA)
+
+	role, err := provider.Role(identity.Groups)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleStudent, role)
+
+	// Codes are single-use at the provider
+	_, err = provider.Exchange(ctx, code, state)
+	assert.Error(t, err)
+
+	// Without the email_verified claim the email is not trusted
+	mock.claims = jwt.MapClaims{"groups": []string{"students"}}
+	state = NewLoginState()
+	authURL, err = provider.AuthCodeURL(ctx, state)
+	require.NoError(t, err)
+	code, _ = mock.authorize(t, authURL)
+	identity, err = provider.Exchange(ctx, code, state)
+	require.NoError(t, err)
+	assert.False(t, identity.EmailVerified)
+}
+
+func TestProviderRejectsTamperedLogins(t *testing.T) {
+	ctx := context.Background()
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock.server.URL)
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		modify func(state *LoginState)
+	}{
+		{name: "wrong PKCE verifier", modify: func(state *LoginState) { state.Verifier = randomString() }},
+		{name: "wrong nonce", modify: func(state *LoginState) { state.Nonce = randomString() }},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "another-client"}},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example"}},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "no subject", claims: jwt.MapClaims{"sub": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.claims = tt.claims
+			state := NewLoginState()
+			authURL, err := provider.AuthCodeURL(ctx, state)
+			require.NoError(t, err)
+			code, _ := mock.authorize(t, authURL)
+
+			if tt.modify != nil {
+				tt.modify(&state)
+			}
+			_, err = provider.Exchange(ctx, code, state)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestProviderDiscoveryIssuerMismatch(t *testing.T) {
+	mock := newMockProvider(t)
+	provider := newTestProvider(t, mock.server.URL+"/tenant")
+
+	_, err := provider.AuthCodeURL(context.Background(), NewLoginState())
+	assert.Error(t, err)
+}
+
+func TestProviderRole(t *testing.T) {
+	provider := newTestProvider(t, "https://idp.example")
+
+	tests := []struct {
+		name    string
+		groups  []string
+		want    string
+		wantErr error
+	}{
+		{name: "admin group", groups: []string{"students", "portal-admins"}, want: models.RoleAdmin},
+		{name: "student group", groups: []string{"students"}, want: models.RoleStudent},
+		{name: "no allowed group", groups: []string{"alumni"}, wantErr: ErrNotAllowed},
+		{name: "no groups", wantErr: ErrNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := provider.Role(tt.groups)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, role)
+		})
+	}
+
+	// Without student groups everyone who is not an admin signs in as a student
+	provider.config.StudentGroups = nil
+	role, err := provider.Role(nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleStudent, role)
+}
+
+func TestLoginStateEncoding(t *testing.T) {
+	state := NewLoginState()
+	assert.Len(t, state.Verifier, 43)
+
+	decoded, err := DecodeLoginState(state.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, state, decoded)
+
+	for _, value := range []string{"", "a.b", "a..c", "a.b.c.d"} {
+		_, err := DecodeLoginState(value)
+		assert.ErrorIs(t, err, ErrInvalidState, value)
+	}
+}
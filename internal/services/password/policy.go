@@ -0,0 +1,147 @@
+// Package password decides whether a new password is strong enough
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MaxLength is the longest password in bytes; bcrypt ignores everything after it
+const MaxLength = 72
+
+// Errors wrapped by the PolicyError returned from Policy.Check
+var (
+	ErrTooShort      = errors.New("password is too short")
+	ErrTooLong       = errors.New("password is too long")
+	ErrTooFewClasses = errors.New("password has too few character classes")
+	ErrBreached      = errors.New("password is in the breached password list")
+)
+
+// PolicyError is a rule the password breaks. Its message can be shown to users.
+type PolicyError struct {
+	Err     error
+	Message string
+}
+
+func (e *PolicyError) Error() string { return e.Message }
+
+func (e *PolicyError) Unwrap() error { return e.Err }
+
+//go:embed breached.txt
+var breachedList string
+
+// breached returns the bundled breached passwords, parsed on first use
+var breached = sync.OnceValue(func() map[string]struct{} {
+	passwords := make(map[string]struct{})
+	_ = readBreached(strings.NewReader(breachedList), passwords)
+	return passwords
+})
+
+// readBreached adds the passwords listed one per line in r to passwords. Blank lines and lines starting with # are
+// skipped.
+func readBreached(r io.Reader, passwords map[string]struct{}) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Policy lists the rules new passwords must follow
+type Policy struct {
+	minLength     int
+	minClasses    int
+	checkBreached bool
+	extra         map[string]struct{} // Breached passwords added with LoadBreached
+}
+
+// NewPolicy creates a Policy. minClasses is how many of lower case letters, upper case letters, digits and
+// other characters a password must mix. checkBreached rejects passwords from the bundled breached list.
+func NewPolicy(minLength, minClasses int, checkBreached bool) (*Policy, error) {
+	if minLength < 1 || minLength > MaxLength {
+		return nil, fmt.Errorf("minimum password length must be between 1 and %d", MaxLength)
+	}
+	if minClasses < 0 || minClasses > 4 {
+		return nil, errors.New("minimum password character classes must be between 0 and 4")
+	}
+	return &Policy{minLength: minLength, minClasses: minClasses, checkBreached: checkBreached}, nil
+}
+
+// Check returns a *PolicyError if the password breaks a rule of the policy
+func (p *Policy) Check(password string) error {
+	if len([]rune(password)) < p.minLength {
+		return &PolicyError{Err: ErrTooShort, Message: fmt.Sprintf("Password must be at least %d characters long", p.minLength)}
+	}
+	if len(password) > MaxLength {
+		return &PolicyError{Err: ErrTooLong, Message: fmt.Sprintf("Password must be at most %d bytes long", MaxLength)}
+	}
+	if classes(password) < p.minClasses {
+		return &PolicyError{Err: ErrTooFewClasses, Message: fmt.Sprintf("Password must mix at least %d of lower case letters, upper case letters, digits and symbols", p.minClasses)}
+	}
+	if p.checkBreached {
+		if p.isBreached(strings.ToLower(password)) {
+			return &PolicyError{Err: ErrBreached, Message: "This password has appeared in a data breach, choose another one"}
+		}
+	}
+	return nil
+}
+
+// LoadBreached adds a breached password list to the bundled one, such as a top-N list from public breach corpora.
+// The list has one password per line; blank lines and lines starting with # are skipped.
+func (p *Policy) LoadBreached(r io.Reader) error {
+	extra := make(map[string]struct{})
+	if err := readBreached(r, extra); err != nil {
+		return fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	if p.extra == nil {
+		p.extra = extra
+		return nil
+	}
+	for password := range extra {
+		p.extra[password] = struct{}{}
+	}
+	return nil
+}
+
+// isBreached reports whether the lower case password is in the bundled or a loaded breached list
+func (p *Policy) isBreached(password string) bool {
+	if _, ok := breached()[password]; ok {
+		return true
+	}
+	_, ok := p.extra[password]
+	return ok
+}
+
+// classes counts the character classes in the password
+func classes(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			n++
+		}
+	}
+	return n
+}
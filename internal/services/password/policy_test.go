@@ -0,0 +1,75 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := NewPolicy(10, 3, true)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "strong", password: "Correct-horse-7"},
+		{name: "three classes without symbols", password: "Correcthorse7"},
+		{name: "unicode letters", password: "Ćwiczenie-2024"},
+		{name: "too short", password: "Ab1-", wantErr: ErrTooShort},
+		{name: "length counts characters", password: "Łódź-Łódź", wantErr: ErrTooShort},
+		{name: "too long", password: "Aa1-" + strings.Repeat("x", MaxLength), wantErr: ErrTooLong},
+		{name: "too few classes", password: "correcthorsebattery", wantErr: ErrTooFewClasses},
+		{name: "breached", password: "Password1234", wantErr: ErrBreached},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.password)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			var policyErr *PolicyError
+			require.ErrorAs(t, err, &policyErr)
+			assert.NotEmpty(t, policyErr.Message)
+		})
+	}
+}
+
+func TestPolicyBreachedList(t *testing.T) {
+	policy, err := NewPolicy(8, 0, true)
+	require.NoError(t, err)
+	assert.ErrorIs(t, policy.Check("QWERTYUIOP"), ErrBreached)
+	assert.ErrorIs(t, policy.Check("p@ssw0rd"), ErrBreached)
+	for _, breachedPassword := range []string{"password1", "iloveyou1", "1q2w3e4r5t", "Sunshine123", "zaq1xsw2"} {
+		assert.ErrorIs(t, policy.Check(breachedPassword), ErrBreached, breachedPassword)
+	}
+
+	unchecked, err := NewPolicy(8, 0, false)
+	require.NoError(t, err)
+	assert.NoError(t, unchecked.Check("password"))
+}
+
+func TestPolicyLoadBreached(t *testing.T) {
+	policy, err := NewPolicy(8, 0, true)
+	require.NoError(t, err)
+	require.NoError(t, policy.LoadBreached(strings.NewReader("# top passwords\n\nTrombone-42\n")))
+	assert.ErrorIs(t, policy.Check("trombone-42"), ErrBreached)
+	assert.ErrorIs(t, policy.Check("password1"), ErrBreached)
+	assert.NoError(t, policy.Check("Tuba-forty-two"))
+}
+
+func TestNewPolicyValidation(t *testing.T) {
+	_, err := NewPolicy(0, 1, true)
+	assert.Error(t, err)
+	_, err = NewPolicy(MaxLength+1, 1, true)
+	assert.Error(t, err)
+	_, err = NewPolicy(8, 5, true)
+	assert.Error(t, err)
+}
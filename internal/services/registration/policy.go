@@ -0,0 +1,122 @@
+// Package registration decides which email addresses may register and with which role
+package registration
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// Errors returned by Policy.Check
+var (
+	ErrInvalidEmail      = errors.New("invalid email address")
+	ErrDomainNotAllowed  = errors.New("email domain is not allowed to register")
+	ErrInvalidDomainRule = errors.New("invalid email domain pattern")
+)
+
+// Policy restricts registration to allowed email domains and picks the role of new accounts by domain.
+// A pattern is either a domain ("university.edu") or a wildcard for its subdomains ("*.university.edu").
+type Policy struct {
+	allowed []string
+	roles   map[string]string // Pattern to role
+}
+
+// NewPolicy creates a Policy. An empty allowlist allows every domain. roles maps domain patterns to the role
+// of accounts registered with them; other accounts are students.
+func NewPolicy(allowed []string, roles map[string]string) (*Policy, error) {
+	p := &Policy{roles: make(map[string]string, len(roles))}
+	for _, pattern := range allowed {
+		pattern = normalizePattern(pattern)
+		if pattern == "" {
+			continue
+		}
+		if err := validatePattern(pattern); err != nil {
+			return nil, err
+		}
+		p.allowed = append(p.allowed, pattern)
+	}
+	for pattern, role := range roles {
+		pattern = normalizePattern(pattern)
+		if err := validatePattern(pattern); err != nil {
+			return nil, err
+		}
+		if !models.ValidRole(role) {
+			return nil, fmt.Errorf("invalid role %q for email domain %q", role, pattern)
+		}
+		p.roles[pattern] = role
+	}
+	return p, nil
+}
+
+// Check validates the email address and returns its normalized form and the role for a new account
+func (p *Policy) Check(email string) (normalized, role string, err error) {
+	normalized, domain, err := NormalizeEmail(email)
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(p.allowed) > 0 && !matchesAny(domain, p.allowed) {
+		return "", "", ErrDomainNotAllowed
+	}
+
+	// The most specific matching pattern wins
+	role, longest := models.RoleStudent, -1
+	for pattern, patternRole := range p.roles {
+		if matches(domain, pattern) && len(pattern) > longest {
+			role, longest = patternRole, len(pattern)
+		}
+	}
+	return normalized, role, nil
+}
+
+// NormalizeEmail validates a bare email address and returns it in lower case together with its domain
+func NormalizeEmail(email string) (normalized, domain string, err error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return "", "", ErrInvalidEmail
+	}
+
+	normalized = strings.ToLower(address.Address)
+	at := strings.LastIndexByte(normalized, '@')
+	domain = normalized[at+1:]
+	if !strings.Contains(domain, ".") {
+		return "", "", ErrInvalidEmail
+	}
+	return normalized, domain, nil
+}
+
+// matchesAny reports whether the domain matches one of the patterns
+func matchesAny(domain string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matches(domain, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the domain matches the pattern. "*.example.edu" matches subdomains only.
+func matches(domain, pattern string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(domain, suffix)
+	}
+	return domain == pattern
+}
+
+// normalizePattern lower-cases a pattern and strips a leading "@"
+func normalizePattern(pattern string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(pattern)), "@")
+}
+
+// validatePattern rejects patterns with misplaced wildcards
+func validatePattern(pattern string) error {
+	domain := strings.TrimPrefix(pattern, "*.")
+	if domain == "" || strings.Contains(domain, "*") || strings.Contains(domain, "@") || !strings.Contains(domain, ".") {
+		return fmt.Errorf("%w: %q", ErrInvalidDomainRule, pattern)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package registration
+
+import (
+	"testing"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := NewPolicy(
+		[]string{"university.edu", "*.university.edu", "@partner.org"},
+		map[string]string{"*.university.edu": models.RoleStudent, "staff.university.edu": models.RoleAdmin},
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		email     string
+		wantEmail string
+		wantRole  string
+		wantErr   error
+	}{
+		{name: "exact domain", email: "jane@university.edu", wantEmail: "jane@university.edu", wantRole: models.RoleStudent},
+		{name: "subdomain", email: "jane@cs.university.edu", wantEmail: "jane@cs.university.edu", wantRole: models.RoleStudent},
+		{name: "most specific role wins", email: "dean@staff.university.edu", wantEmail: "dean@staff.university.edu", wantRole: models.RoleAdmin},
+		{name: "normalized", email: " Jane@University.EDU ", wantEmail: "jane@university.edu", wantRole: models.RoleStudent},
+		{name: "at prefix in pattern", email: "bob@partner.org", wantEmail: "bob@partner.org", wantRole: models.RoleStudent},
+		{name: "other domain", email: "jane@gmail.com", wantErr: ErrDomainNotAllowed},
+		{name: "lookalike domain", email: "jane@evil-university.edu", wantErr: ErrDomainNotAllowed},
+		{name: "domain as subdomain of attacker", email: "jane@university.edu.evil.com", wantErr: ErrDomainNotAllowed},
+		{name: "display name", email: "Jane <jane@university.edu>", wantErr: ErrInvalidEmail},
+		{name: "not an email", email: "jane", wantErr: ErrInvalidEmail},
+		{name: "no dot in domain", email: "jane@localhost", wantErr: ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, role, err := policy.Check(tt.email)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantEmail, email)
+			assert.Equal(t, tt.wantRole, role)
+		})
+	}
+}
+
+func TestPolicyAllowsAllWithoutAllowlist(t *testing.T) {
+	policy, err := NewPolicy(nil, nil)
+	require.NoError(t, err)
+
+	_, role, err := policy.Check("jane@gmail.com")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleStudent, role)
+}
+
+func TestNewPolicyRejectsInvalidRules(t *testing.T) {
+	_, err := NewPolicy([]string{"univ*.edu"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidDomainRule)
+
+	_, err = NewPolicy([]string{"*"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidDomainRule)
+
+	_, err = NewPolicy(nil, map[string]string{"university.edu": "superuser"})
+	assert.Error(t, err)
+}
@@ -2,14 +2,35 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 )
 
+// ErrServiceBusClosed is returned when sending after the service was closed
+var ErrServiceBusClosed = errors.New("service bus service is closed")
+
+// queueSender is the part of *azservicebus.Sender used by ServiceBusService
+type queueSender interface {
+	SendMessage(ctx context.Context, message *azservicebus.Message, options *azservicebus.SendMessageOptions) error
+	NewMessageBatch(ctx context.Context, options *azservicebus.MessageBatchOptions) (*azservicebus.MessageBatch, error)
+	SendMessageBatch(ctx context.Context, batch *azservicebus.MessageBatch, options *azservicebus.SendMessageBatchOptions) error
+	Close(ctx context.Context) error
+}
+
 type ServiceBusService struct {
 	client *azservicebus.Client
 	log    *slog.Logger
+
+	// Senders are cached per queue, since opening one sets up a new AMQP link
+	newSender func(queueName string) (queueSender, error)
+	mu        sync.Mutex
+	senders   map[string]queueSender
+	closed    bool
 }
 
 // NewServiceBusService creates a new ServiceBusService with the given connection string
@@ -24,33 +45,289 @@ func NewServiceBusService(connectionString string, log *slog.Logger) (*ServiceBu
 	return &ServiceBusService{
 		client: client,
 		log:    log,
+		newSender: func(queueName string) (queueSender, error) {
+			return client.NewSender(queueName, nil)
+		},
+		senders: make(map[string]queueSender),
 	}, nil
 }
 
-// SendMessage sends a message to the specified queue
+// Application property keys set on published messages
+const (
+	PropertyEventType     = "eventType"
+	PropertySchemaVersion = "schemaVersion"
+)
+
+// Event types published for complaints
+const (
+	EventComplaintCreated       = "complaint.created"
+	EventComplaintStatusChanged = "complaint.status_changed"
+	EventComplaintEscalationDue = "complaint.escalation_due"
+)
+
+// OutgoingMessage is a message to publish along with its broker properties. Only Body is required.
+type OutgoingMessage struct {
+	Body []byte
+	// MessageID lets the broker drop duplicates when duplicate detection is enabled on the queue
+	MessageID     string
+	CorrelationID string
+	// SessionID groups messages, e.g. per complaint, so session-enabled queues process them in order
+	SessionID     string
+	EventType     string
+	SchemaVersion string
+	// Properties holds additional application properties
+	Properties map[string]any
+	// TimeToLive overrides the queue's default message TTL when set
+	TimeToLive time.Duration
+	// ScheduledAt delays delivery until the given time when set
+	ScheduledAt time.Time
+}
+
+// toServiceBusMessage converts the message to its azservicebus form
+func (m *OutgoingMessage) toServiceBusMessage() *azservicebus.Message {
+	message := &azservicebus.Message{
+		Body: m.Body,
+	}
+	if m.MessageID != "" {
+		message.MessageID = &m.MessageID
+	}
+	if m.CorrelationID != "" {
+		message.CorrelationID = &m.CorrelationID
+	}
+	if m.SessionID != "" {
+		message.SessionID = &m.SessionID
+	}
+	if m.TimeToLive > 0 {
+		message.TimeToLive = &m.TimeToLive
+	}
+	if !m.ScheduledAt.IsZero() {
+		scheduledAt := m.ScheduledAt.UTC()
+		message.ScheduledEnqueueTime = &scheduledAt
+	}
+
+	if len(m.Properties) > 0 || m.EventType != "" || m.SchemaVersion != "" {
+		message.ApplicationProperties = make(map[string]any, len(m.Properties)+2)
+		for key, value := range m.Properties {
+			message.ApplicationProperties[key] = value
+		}
+		if m.EventType != "" {
+			message.ApplicationProperties[PropertyEventType] = m.EventType
+		}
+		if m.SchemaVersion != "" {
+			message.ApplicationProperties[PropertySchemaVersion] = m.SchemaVersion
+		}
+	}
+	return message
+}
+
+// SendMessage sends a message with only a body to the specified queue
 func (s *ServiceBusService) SendMessage(ctx context.Context, queueName, messageBody string) error {
-	sender, err := s.client.NewSender(queueName, nil)
+	return s.Publish(ctx, queueName, &OutgoingMessage{Body: []byte(messageBody)})
+}
+
+// Publish sends a message with its properties to the specified queue
+func (s *ServiceBusService) Publish(ctx context.Context, queueName string, message *OutgoingMessage) error {
+	sbMessage := message.toServiceBusMessage()
+
+	err := s.withSender(ctx, queueName, func(sender queueSender) error {
+		return sender.SendMessage(ctx, sbMessage, nil)
+	})
 	if err != nil {
-		s.log.Error("failed to create service bus sender", slog.String("queue", queueName), slog.String("error", err.Error()))
+		s.log.Error("failed to send message to service bus", slog.String("queue", queueName), slog.String("messageId", message.MessageID), slog.String("error", err.Error()))
 		return err
 	}
-	defer func(sender *azservicebus.Sender, ctx context.Context) {
-		err := sender.Close(ctx)
+
+	s.log.Info("message sent to service bus", slog.String("queue", queueName), slog.String("messageId", message.MessageID), slog.String("eventType", message.EventType))
+	return nil
+}
+
+// SendMessages sends messages to the specified queue in as few batches as the queue's size limit allows
+func (s *ServiceBusService) SendMessages(ctx context.Context, queueName string, messages []*OutgoingMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	sbMessages := make([]*azservicebus.Message, len(messages))
+	for i, message := range messages {
+		sbMessages[i] = message.toServiceBusMessage()
+	}
+
+	err := s.withSender(ctx, queueName, func(sender queueSender) error {
+		return sendBatches(ctx, sender, sbMessages)
+	})
+	if err != nil {
+		s.log.Error("failed to send message batch to service bus", slog.String("queue", queueName), slog.Int("count", len(messages)), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.log.Info("messages sent to service bus", slog.String("queue", queueName), slog.Int("count", len(messages)))
+	return nil
+}
+
+// sendBatches adds messages to a batch and sends it whenever the next message does not fit
+func sendBatches(ctx context.Context, sender queueSender, messages []*azservicebus.Message) error {
+	batch, err := sender.NewMessageBatch(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(messages); {
+		err := batch.AddMessage(messages[i], nil)
+		if errors.Is(err, azservicebus.ErrMessageTooLarge) {
+			if batch.NumMessages() == 0 {
+				return fmt.Errorf("message %d does not fit in a batch: %w", i, err)
+			}
+			// Send the full batch and retry the message in a new one
+			if err := sender.SendMessageBatch(ctx, batch, nil); err != nil {
+				return err
+			}
+			if batch, err = sender.NewMessageBatch(ctx, nil); err != nil {
+				return err
+			}
+			continue
+		}
 		if err != nil {
-			s.log.Error("failed to close service bus sender", slog.String("error", err.Error()))
+			return err
 		}
-	}(sender, ctx)
+		i++
+	}
 
-	message := &azservicebus.Message{
-		Body: []byte(messageBody),
+	return sender.SendMessageBatch(ctx, batch, nil)
+}
+
+// withSender calls send with the cached sender for the queue.
+// When the sender's connection is lost it is replaced with a new one and send is retried once.
+func (s *ServiceBusService) withSender(ctx context.Context, queueName string, send func(queueSender) error) error {
+	sender, err := s.sender(queueName)
+	if err != nil {
+		return err
+	}
+
+	err = send(sender)
+	var sbErr *azservicebus.Error
+	if !errors.As(err, &sbErr) || sbErr.Code != azservicebus.CodeConnectionLost {
+		return err
+	}
+
+	s.log.Warn("service bus connection lost, reconnecting sender", slog.String("queue", queueName), slog.String("error", err.Error()))
+	s.dropSender(ctx, queueName, sender)
+
+	if sender, err = s.sender(queueName); err != nil {
+		return err
+	}
+	return send(sender)
+}
+
+// sender returns the cached sender for the queue, creating it on first use
+func (s *ServiceBusService) sender(queueName string) (queueSender, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrServiceBusClosed
+	}
+	if sender, ok := s.senders[queueName]; ok {
+		return sender, nil
 	}
 
-	err = sender.SendMessage(ctx, message, nil)
+	sender, err := s.newSender(queueName)
 	if err != nil {
-		s.log.Error("failed to send message to service bus", slog.String("queue", queueName), slog.String("error", err.Error()))
+		s.log.Error("failed to create service bus sender", slog.String("queue", queueName), slog.String("error", err.Error()))
+		return nil, err
+	}
+	s.senders[queueName] = sender
+	return sender, nil
+}
+
+// dropSender removes a broken sender from the cache and closes it.
+// Another request may already have replaced it, in which case the replacement is kept.
+func (s *ServiceBusService) dropSender(ctx context.Context, queueName string, sender queueSender) {
+	s.mu.Lock()
+	if s.senders[queueName] == sender {
+		delete(s.senders, queueName)
+	}
+	s.mu.Unlock()
+
+	if err := sender.Close(ctx); err != nil {
+		s.log.Debug("failed to close broken service bus sender", slog.String("queue", queueName), slog.String("error", err.Error()))
+	}
+}
+
+// Close closes every cached sender and the client. Sending afterwards fails with ErrServiceBusClosed.
+func (s *ServiceBusService) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	senders := s.senders
+	s.senders = make(map[string]queueSender)
+	s.mu.Unlock()
+
+	var errs []error
+	for queueName, sender := range senders {
+		if err := sender.Close(ctx); err != nil {
+			s.log.Error("failed to close service bus sender", slog.String("queue", queueName), slog.String("error", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+	if err := s.client.Close(ctx); err != nil {
+		s.log.Error("failed to close service bus client", slog.String("error", err.Error()))
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// MessageHandler processes a single message received from a queue.
+// Returning an error abandons the message so Service Bus redelivers it.
+type MessageHandler func(ctx context.Context, message *azservicebus.ReceivedMessage) error
+
+// Consume receives messages from the specified queue and passes them to handle until ctx is cancelled.
+// Messages are completed when handle succeeds and abandoned otherwise.
+func (s *ServiceBusService) Consume(ctx context.Context, queueName string, handle MessageHandler) error {
+	receiver, err := s.client.NewReceiverForQueue(queueName, nil)
+	if err != nil {
+		s.log.Error("failed to create service bus receiver", slog.String("queue", queueName), slog.String("error", err.Error()))
 		return err
 	}
+	defer func() {
+		// The consume context is already cancelled at this point, so close with a fresh one
+		closeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := receiver.Close(closeCtx); err != nil {
+			s.log.Error("failed to close service bus receiver", slog.String("queue", queueName), slog.String("error", err.Error()))
+		}
+	}()
 
-	s.log.Info("message sent to service bus", slog.String("queue", queueName))
-	return nil
+	s.log.Info("consuming service bus queue", slog.String("queue", queueName))
+
+	for {
+		messages, err := receiver.ReceiveMessages(ctx, 10, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				s.log.Info("stopped consuming service bus queue", slog.String("queue", queueName))
+				return nil
+			}
+			s.log.Error("failed to receive messages from service bus", slog.String("queue", queueName), slog.String("error", err.Error()))
+
+			// Back off before retrying so a broken link does not spin
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, message := range messages {
+			if err := handle(ctx, message); err != nil {
+				s.log.Error("failed to handle service bus message", slog.String("queue", queueName), slog.String("messageId", message.MessageID), slog.String("error", err.Error()))
+				if err := receiver.AbandonMessage(ctx, message, nil); err != nil {
+					s.log.Error("failed to abandon service bus message", slog.String("queue", queueName), slog.String("messageId", message.MessageID), slog.String("error", err.Error()))
+				}
+				continue
+			}
+
+			if err := receiver.CompleteMessage(ctx, message, nil); err != nil {
+				s.log.Error("failed to complete service bus message", slog.String("queue", queueName), slog.String("messageId", message.MessageID), slog.String("error", err.Error()))
+			}
+		}
+	}
 }
@@ -1,10 +1,17 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewServiceBusService(t *testing.T) {
@@ -79,3 +86,162 @@ func TestServiceBusService_SendMessage(t *testing.T) {
 		assert.Nil(t, service.client)
 	})
 }
+
+// fakeSender records messages sent through a cached sender
+type fakeSender struct {
+	mu       sync.Mutex
+	sent     []string
+	failures []error // Returned by the next SendMessage calls, in order
+	closed   bool
+}
+
+func (f *fakeSender) SendMessage(_ context.Context, message *azservicebus.Message, _ *azservicebus.SendMessageOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failures) > 0 {
+		err := f.failures[0]
+		f.failures = f.failures[1:]
+		return err
+	}
+	f.sent = append(f.sent, string(message.Body))
+	return nil
+}
+
+func (f *fakeSender) NewMessageBatch(context.Context, *azservicebus.MessageBatchOptions) (*azservicebus.MessageBatch, error) {
+	return nil, errors.New("batches are not supported by fakeSender")
+}
+
+func (f *fakeSender) SendMessageBatch(context.Context, *azservicebus.MessageBatch, *azservicebus.SendMessageBatchOptions) error {
+	return errors.New("batches are not supported by fakeSender")
+}
+
+func (f *fakeSender) Close(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// newTestServiceBusService returns a service whose senders are fakes, along with every sender it created
+func newTestServiceBusService(t *testing.T, failures ...error) (*ServiceBusService, *[]*fakeSender) {
+	client, err := azservicebus.NewClientFromConnectionString("Endpoint=sb://test.servicebus.windows.net/;SharedAccessKeyName=test;SharedAccessKey=dGVzdA==", nil)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	created := &[]*fakeSender{}
+	service := &ServiceBusService{
+		client:  client,
+		log:     slog.Default(),
+		senders: make(map[string]queueSender),
+		newSender: func(string) (queueSender, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			sender := &fakeSender{}
+			if len(*created) == 0 {
+				sender.failures = failures
+			}
+			*created = append(*created, sender)
+			return sender, nil
+		},
+	}
+	return service, created
+}
+
+func TestServiceBusService_SenderCache(t *testing.T) {
+	t.Run("reuses one sender per queue", func(t *testing.T) {
+		service, created := newTestServiceBusService(t)
+
+		var wg sync.WaitGroup
+		for i := range 20 {
+			wg.Go(func() {
+				assert.NoError(t, service.SendMessage(context.Background(), "new-complaints", strconv.Itoa(i)))
+			})
+		}
+		wg.Wait()
+		require.NoError(t, service.SendMessage(context.Background(), "complaint-status-changed", "c-1"))
+
+		require.Len(t, *created, 2)
+		assert.Len(t, (*created)[0].sent, 20)
+		assert.Equal(t, []string{"c-1"}, (*created)[1].sent)
+	})
+
+	t.Run("reconnects when the connection is lost", func(t *testing.T) {
+		service, created := newTestServiceBusService(t, &azservicebus.Error{Code: azservicebus.CodeConnectionLost})
+
+		require.NoError(t, service.SendMessage(context.Background(), "new-complaints", "c-1"))
+
+		require.Len(t, *created, 2)
+		assert.True(t, (*created)[0].closed, "broken sender should be closed")
+		assert.Equal(t, []string{"c-1"}, (*created)[1].sent)
+	})
+
+	t.Run("other errors are returned without reconnecting", func(t *testing.T) {
+		service, created := newTestServiceBusService(t, azservicebus.ErrMessageTooLarge)
+
+		assert.ErrorIs(t, service.SendMessage(context.Background(), "new-complaints", "c-1"), azservicebus.ErrMessageTooLarge)
+		assert.Len(t, *created, 1)
+	})
+
+	t.Run("close closes every sender", func(t *testing.T) {
+		service, created := newTestServiceBusService(t)
+		require.NoError(t, service.SendMessage(context.Background(), "new-complaints", "c-1"))
+		require.NoError(t, service.SendMessage(context.Background(), "complaint-status-changed", "c-1"))
+
+		require.NoError(t, service.Close(context.Background()))
+		for _, sender := range *created {
+			assert.True(t, sender.closed)
+		}
+		assert.ErrorIs(t, service.SendMessage(context.Background(), "new-complaints", "c-2"), ErrServiceBusClosed)
+	})
+
+	t.Run("empty batch is a no-op", func(t *testing.T) {
+		service, created := newTestServiceBusService(t)
+		assert.NoError(t, service.SendMessages(context.Background(), "new-complaints", nil))
+		assert.Empty(t, *created)
+	})
+}
+
+func TestOutgoingMessage_ToServiceBusMessage(t *testing.T) {
+	t.Run("body only", func(t *testing.T) {
+		message := (&OutgoingMessage{Body: []byte("c-1")}).toServiceBusMessage()
+
+		assert.Equal(t, []byte("c-1"), message.Body)
+		assert.Nil(t, message.MessageID)
+		assert.Nil(t, message.SessionID)
+		assert.Nil(t, message.TimeToLive)
+		assert.Nil(t, message.ScheduledEnqueueTime)
+		assert.Nil(t, message.ApplicationProperties)
+	})
+
+	t.Run("all properties", func(t *testing.T) {
+		scheduledAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+		message := (&OutgoingMessage{
+			Body:          []byte("c-1"),
+			MessageID:     "c-1-created",
+			CorrelationID: "req-1",
+			SessionID:     "c-1",
+			EventType:     EventComplaintCreated,
+			SchemaVersion: "1",
+			Properties:    map[string]any{"source": "api"},
+			TimeToLive:    time.Hour,
+			ScheduledAt:   scheduledAt,
+		}).toServiceBusMessage()
+
+		require.NotNil(t, message.MessageID)
+		assert.Equal(t, "c-1-created", *message.MessageID)
+		require.NotNil(t, message.CorrelationID)
+		assert.Equal(t, "req-1", *message.CorrelationID)
+		require.NotNil(t, message.SessionID)
+		assert.Equal(t, "c-1", *message.SessionID)
+		require.NotNil(t, message.TimeToLive)
+		assert.Equal(t, time.Hour, *message.TimeToLive)
+		require.NotNil(t, message.ScheduledEnqueueTime)
+		assert.True(t, scheduledAt.Equal(*message.ScheduledEnqueueTime))
+		assert.Equal(t, time.UTC, message.ScheduledEnqueueTime.Location())
+		assert.Equal(t, map[string]any{
+			"source":              "api",
+			PropertyEventType:     EventComplaintCreated,
+			PropertySchemaVersion: "1",
+		}, message.ApplicationProperties)
+	})
+}
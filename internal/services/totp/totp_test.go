@@ -0,0 +1,113 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA-1 secret of the RFC 6238 test vectors, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		// The RFC lists 8-digit codes, these are their last 6 digits
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "time %d", tt.unix)
+	}
+}
+
+func TestCode_InvalidSecret(t *testing.T) {
+	_, err := Code("not base32!", 1)
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+
+	_, err = Code("", 1)
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := Step(now)
+
+	previous, err := Code(rfcSecret, current-1)
+	require.NoError(t, err)
+	tooOld, err := Code(rfcSecret, current-2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		code  string
+		valid bool
+		step  int64
+	}{
+		{"current step", "081804", true, current},
+		{"with spaces", " 081 804 ", true, current},
+		{"previous step within skew", previous, true, current - 1},
+		{"outside skew", tooOld, false, 0},
+		{"wrong code", "123456", false, 0},
+		{"too short", "08180", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, valid := Validate(rfcSecret, tt.code, now)
+			assert.Equal(t, tt.valid, valid)
+			assert.Equal(t, tt.step, step)
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32) // 20 bytes in base32 without padding
+
+	code, err := Code(secret, Step(time.Now()))
+	require.NoError(t, err)
+	_, valid := Validate(secret, code, time.Now())
+	assert.True(t, valid)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI(rfcSecret, "Student Complaint Portal", "admin@university.edu")
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", parsed.Scheme)
+	assert.Equal(t, "totp", parsed.Host)
+	assert.Equal(t, "/Student Complaint Portal:admin@university.edu", parsed.Path)
+	assert.Equal(t, rfcSecret, parsed.Query().Get("secret"))
+	assert.Equal(t, "Student Complaint Portal", parsed.Query().Get("issuer"))
+	assert.Equal(t, "6", parsed.Query().Get("digits"))
+	assert.Equal(t, "30", parsed.Query().Get("period"))
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes := GenerateRecoveryCodes(10)
+	require.Len(t, codes, 10)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		assert.False(t, seen[code], "duplicate recovery code")
+		seen[code] = true
+		assert.Equal(t, code, NormalizeRecoveryCode(" "+strings.ToUpper(code)+" "))
+	}
+}
@@ -0,0 +1,294 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/services/cosmos"
+	"github.com/google/uuid"
+)
+
+const (
+	// batchSize is the maximum number of deliveries attempted per poll
+	batchSize = 20
+	// lease is how long a worker owns a delivery while sending it; expired leases are picked up again
+	lease = 2 * time.Minute
+	// sendTimeout bounds a single delivery attempt
+	sendTimeout = 10 * time.Second
+	// maxRecordedAttempts is how many of the most recent attempts are kept on a delivery
+	maxRecordedAttempts = 10
+	// Retry delays grow exponentially from baseRetryDelay up to maxRetryDelay
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = time.Hour
+)
+
+// Service queues complaint events for webhook subscriptions and delivers them in the background
+type Service struct {
+	cosmosService *cosmos.Service
+	sender        *Sender
+	interval      time.Duration
+	maxAttempts   int
+	log           *slog.Logger
+}
+
+// NewService creates a new webhook Service
+func NewService(cosmosService *cosmos.Service, sender *Sender, interval time.Duration, maxAttempts int, log *slog.Logger) *Service {
+	const module = "webhooks"
+	log = log.With(
+		slog.String("module", module),
+	)
+	return &Service{
+		cosmosService: cosmosService,
+		sender:        sender,
+		interval:      interval,
+		maxAttempts:   maxAttempts,
+		log:           log,
+	}
+}
+
+// PublishComplaint queues a complaint event for every active subscription that wants it
+func (s *Service) PublishComplaint(ctx context.Context, eventType string, complaint *models.Complaint) error {
+	return s.Publish(ctx, eventType, ComplaintData{
+		ID:              complaint.ID,
+		UserID:          complaint.UserID,
+		Description:     complaint.Description,
+		Status:          complaint.Status,
+		LikeCount:       complaint.LikeCount,
+		CreatedAt:       complaint.CreatedAt,
+		StatusChangedAt: complaint.StatusChangedAt,
+	})
+}
+
+// Publish queues an event for every active subscription that wants it
+func (s *Service) Publish(ctx context.Context, eventType string, data any) error {
+	subscriptions, err := s.cosmosService.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	event := Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: now,
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if !Matches(subscription, eventType) {
+			continue
+		}
+
+		if err := s.cosmosService.CreateWebhookDelivery(ctx, &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			Event:          eventType,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	s.log.Debug("webhook event published", slog.String("eventId", event.ID), slog.String("event", eventType))
+	return nil
+}
+
+// Matches reports whether a subscription receives events of the given type
+func Matches(subscription *models.WebhookSubscription, eventType string) bool {
+	return subscription.Active && (len(subscription.Events) == 0 || slices.Contains(subscription.Events, eventType))
+}
+
+// Run delivers due webhooks every interval until ctx is cancelled
+func (s *Service) Run(ctx context.Context) {
+	s.log.Info("webhook delivery worker started", slog.Duration("interval", s.interval))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("webhook delivery worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Redeliver immediately sends a delivery again, regardless of its status, and returns it with the new attempt recorded
+func (s *Service) Redeliver(ctx context.Context, subscriptionID, deliveryID string) (*models.WebhookDelivery, error) {
+	subscription, err := s.cosmosService.GetWebhookSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, cosmos.ErrWebhookNotFound
+	}
+
+	delivery, err := s.cosmosService.GetWebhookDelivery(ctx, subscriptionID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, cosmos.ErrDeliveryNotFound
+	}
+
+	// A manual redelivery starts a fresh series of retries
+	delivery.AttemptCount = 0
+	if err := s.deliver(ctx, subscription, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// deliverDue attempts one batch of due deliveries
+func (s *Service) deliverDue(ctx context.Context) {
+	deliveries, err := s.cosmosService.GetDueWebhookDeliveries(ctx, time.Now(), batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error("failed to get due webhook deliveries", slog.String("error", err.Error()))
+		}
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	subscriptions := make(map[string]*models.WebhookSubscription)
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		delivery := &deliveries[i]
+
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok && delivery.URL != "" {
+			subscription, err = s.personalSubscription(ctx, delivery.SubscriptionID)
+			if err != nil {
+				s.log.Error("failed to get personal webhook secret", slog.String("webhookId", delivery.SubscriptionID), slog.String("error", err.Error()))
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		} else if !ok {
+			subscription, err = s.cosmosService.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+			if err != nil {
+				s.log.Error("failed to get webhook subscription", slog.String("webhookId", delivery.SubscriptionID), slog.String("error", err.Error()))
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		if subscription == nil || !subscription.Active {
+			// Deliveries for removed or disabled subscriptions are not attempted, but can be redelivered later
+			delivery.Status = models.WebhookDeliveryFailed
+			delivery.LockedUntil = time.Time{}
+			if err := s.cosmosService.UpdateWebhookDelivery(ctx, delivery); err != nil && !errors.Is(err, cosmos.ErrConcurrentUpdate) {
+				s.log.Error("failed to cancel webhook delivery", slog.String("deliveryId", delivery.ID), slog.String("error", err.Error()))
+			}
+			continue
+		}
+
+		if err := s.deliver(ctx, subscription, delivery); err != nil && !errors.Is(err, cosmos.ErrConcurrentUpdate) {
+			s.log.Error("failed to deliver webhook", slog.String("deliveryId", delivery.ID), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// personalSubscription stands in for the subscription of a user's personal webhook deliveries, which carry their own
+// URL. Its secret is the one in the user's notification preferences, so deliveries are signed like admin webhooks.
+func (s *Service) personalSubscription(ctx context.Context, subscriptionID string) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{ID: subscriptionID, Active: true}
+	userID, ok := models.UserIDFromWebhookID(subscriptionID)
+	if !ok {
+		return subscription, nil
+	}
+	prefs, err := s.cosmosService.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs != nil {
+		subscription.Secret = prefs.WebhookSecret
+	}
+	return subscription, nil
+}
+
+// deliver claims a delivery, sends it and records the attempt.
+// An error means the delivery could not be claimed or its outcome could not be saved, not that the receiver failed.
+func (s *Service) deliver(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	// Claim the delivery so that other instances skip it while it is being sent
+	delivery.Status = models.WebhookDeliveryDelivering
+	delivery.LockedUntil = time.Now().UTC().Add(lease)
+	if err := s.cosmosService.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	started := time.Now()
+	statusCode, err := s.sender.Send(sendCtx, subscription, delivery)
+	cancel()
+
+	now := time.Now().UTC()
+	attempt := models.WebhookAttempt{
+		At:         now,
+		StatusCode: statusCode,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	delivery.AttemptCount++
+	delivery.LockedUntil = time.Time{}
+	if err != nil {
+		attempt.Error = err.Error()
+		if delivery.AttemptCount >= s.maxAttempts {
+			delivery.Status = models.WebhookDeliveryFailed
+			s.log.Error("giving up on webhook delivery", slog.String("deliveryId", delivery.ID), slog.Int("attempts", delivery.AttemptCount), slog.String("error", err.Error()))
+		} else {
+			delivery.Status = models.WebhookDeliveryPending
+			delivery.NextAttemptAt = now.Add(retryDelay(delivery.AttemptCount))
+			s.log.Warn("failed to deliver webhook, will retry", slog.String("deliveryId", delivery.ID), slog.Int("attempts", delivery.AttemptCount), slog.Time("nextAttemptAt", delivery.NextAttemptAt), slog.String("error", err.Error()))
+		}
+	} else {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = now
+		s.log.Info("webhook delivered", slog.String("deliveryId", delivery.ID), slog.String("webhookId", subscription.ID), slog.Int("statusCode", statusCode))
+	}
+	delivery.Attempts = recordAttempt(delivery.Attempts, attempt)
+
+	// Record the outcome even if shutdown has started, otherwise a delivered event would be sent again
+	updateCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	return s.cosmosService.UpdateWebhookDelivery(updateCtx, delivery)
+}
+
+// recordAttempt appends an attempt, keeping only the most recent ones
+func recordAttempt(attempts []models.WebhookAttempt, attempt models.WebhookAttempt) []models.WebhookAttempt {
+	attempts = append(attempts, attempt)
+	if len(attempts) > maxRecordedAttempts {
+		attempts = attempts[len(attempts)-maxRecordedAttempts:]
+	}
+	return attempts
+}
+
+// retryDelay returns the backoff before the next attempt after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
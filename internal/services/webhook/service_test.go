@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription models.WebhookSubscription
+		event        string
+		expected     bool
+	}{
+		{
+			name:         "no filter receives everything",
+			subscription: models.WebhookSubscription{Active: true},
+			event:        models.WebhookEventComplaintDeleted,
+			expected:     true,
+		},
+		{
+			name:         "filtered event",
+			subscription: models.WebhookSubscription{Active: true, Events: []string{models.WebhookEventComplaintCreated}},
+			event:        models.WebhookEventComplaintCreated,
+			expected:     true,
+		},
+		{
+			name:         "event outside filter",
+			subscription: models.WebhookSubscription{Active: true, Events: []string{models.WebhookEventComplaintCreated}},
+			event:        models.WebhookEventComplaintLiked,
+			expected:     false,
+		},
+		{
+			name:         "inactive subscription",
+			subscription: models.WebhookSubscription{Active: false},
+			event:        models.WebhookEventComplaintCreated,
+			expected:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Matches(&tt.subscription, tt.event))
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 30 * time.Second},
+		{attempts: 2, expected: time.Minute},
+		{attempts: 4, expected: 4 * time.Minute},
+		{attempts: 8, expected: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run("attempts="+strconv.Itoa(tt.attempts), func(t *testing.T) {
+			assert.Equal(t, tt.expected, retryDelay(tt.attempts))
+		})
+	}
+}
+
+func TestRecordAttempt(t *testing.T) {
+	var attempts []models.WebhookAttempt
+	for i := range maxRecordedAttempts + 3 {
+		attempts = recordAttempt(attempts, models.WebhookAttempt{StatusCode: 500 + i})
+	}
+
+	assert.Len(t, attempts, maxRecordedAttempts)
+	assert.Equal(t, 503, attempts[0].StatusCode)
+	assert.Equal(t, 500+maxRecordedAttempts+2, attempts[len(attempts)-1].StatusCode)
+}
@@ -0,0 +1,124 @@
+// Package webhook pushes complaint events to admin-registered HTTP endpoints.
+//
+// Each event is stored as a delivery per matching subscription and sent by a background
+// worker, which signs the body with the subscription secret and retries failures with
+// exponential backoff. The same worker sends the personal webhooks users set in their
+// notification preferences, which carry their own URL and secret and are only sent to public addresses.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event is the JSON body of a delivery
+type Event struct {
+	ID        string    `json:"id"` // Shared by the deliveries of one event to every subscription
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// ComplaintData is the event data for complaint events
+type ComplaintData struct {
+	ID              string    `json:"id"`
+	UserID          string    `json:"userId"`
+	Description     string    `json:"description"`
+	Status          string    `json:"status"`
+	LikeCount       int       `json:"likeCount"`
+	CreatedAt       time.Time `json:"createdAt"`
+	StatusChangedAt time.Time `json:"statusChangedAt,omitzero"`
+}
+
+// Sign returns the signature header value for a body sent at the given unix timestamp:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret.
+// Including the timestamp lets receivers reject replayed deliveries.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a random signing secret for a subscription
+func NewSecret() string {
+	return "whsec_" + rand.Text()
+}
+
+// Sender posts signed deliveries over HTTP
+type Sender struct {
+	client       *http.Client
+	publicClient *http.Client // For personal webhooks, which users may point anywhere
+}
+
+// NewSender creates a Sender. Redirects are not followed, so they count as failed attempts.
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		publicClient: newPublicClient(timeout),
+	}
+}
+
+// Send posts the delivery payload to the subscription URL and returns the response status code.
+// Any non-2xx response is returned as an error along with its status code.
+// Personal deliveries go to their own URL, and only to public addresses.
+func (s *Sender) Send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	client, target := s.client, subscription.URL
+	if delivery.URL != "" {
+		client, target = s.publicClient, delivery.URL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "StudentComplaintPortal-Webhook/1.0")
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if subscription.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
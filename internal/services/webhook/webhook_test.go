@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Vadym-H/Student-Complaint-Portal/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+
+	signature := Sign("secret", 1700000000, body)
+	assert.Equal(t, signature, Sign("secret", 1700000000, body))
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+
+	assert.NotEqual(t, signature, Sign("other-secret", 1700000000, body))
+	assert.NotEqual(t, signature, Sign("secret", 1700000001, body))
+	assert.NotEqual(t, signature, Sign("secret", 1700000000, []byte(`{"id":"2"}`)))
+}
+
+func TestSender_Send(t *testing.T) {
+	subscription := &models.WebhookSubscription{ID: "sub-1", Secret: "s3cret"}
+	delivery := &models.WebhookDelivery{
+		ID:      "delivery-1",
+		Event:   models.WebhookEventComplaintCreated,
+		Payload: `{"id":"event-1","type":"complaint.created"}`,
+	}
+
+	t.Run("signed delivery", func(t *testing.T) {
+		var received *http.Request
+		var receivedBody []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			receivedBody, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		subscription.URL = server.URL
+		statusCode, err := NewSender(5*time.Second).Send(context.Background(), subscription, delivery)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, statusCode)
+
+		require.NotNil(t, received)
+		assert.Equal(t, http.MethodPost, received.Method)
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		assert.Equal(t, "delivery-1", received.Header.Get(HeaderDelivery))
+		assert.Equal(t, models.WebhookEventComplaintCreated, received.Header.Get(HeaderEvent))
+		assert.Equal(t, delivery.Payload, string(receivedBody))
+
+		// The receiver must be able to verify the signature with the shared secret
+		timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), time.Unix(timestamp, 0), time.Minute)
+		assert.Equal(t, Sign("s3cret", timestamp, receivedBody), received.Header.Get(HeaderSignature))
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		subscription.URL = server.URL
+		statusCode, err := NewSender(5*time.Second).Send(context.Background(), subscription, delivery)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, statusCode)
+	})
+
+	t.Run("redirects are not followed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/moved" {
+				t.Error("redirect was followed")
+			}
+			http.Redirect(w, r, "/moved", http.StatusTemporaryRedirect)
+		}))
+		defer server.Close()
+
+		subscription.URL = server.URL
+		statusCode, err := NewSender(5*time.Second).Send(context.Background(), subscription, delivery)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusTemporaryRedirect, statusCode)
+	})
+
+	t.Run("unreachable receiver", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		subscription.URL = server.URL
+		server.Close()
+
+		statusCode, err := NewSender(5*time.Second).Send(context.Background(), subscription, delivery)
+		assert.Error(t, err)
+		assert.Zero(t, statusCode)
+	})
+
+	t.Run("personal webhook to a private address", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("request reached a loopback receiver")
+		}))
+		defer server.Close()
+
+		personal := &models.WebhookDelivery{ID: "delivery-2", URL: server.URL, Payload: `{"id":"event-2"}`}
+		statusCode, err := NewSender(5*time.Second).Send(context.Background(), &models.WebhookSubscription{ID: models.UserWebhookID("user-1")}, personal)
+		assert.ErrorIs(t, err, ErrNonPublicAddress)
+		assert.Zero(t, statusCode)
+	})
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, isPublic(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestCheckPublicURL(t *testing.T) {
+	assert.NoError(t, CheckPublicURL(context.Background(), "https://93.184.216.34/hook"))
+	assert.ErrorIs(t, CheckPublicURL(context.Background(), "https://127.0.0.1/hook"), ErrNonPublicAddress)
+	assert.ErrorIs(t, CheckPublicURL(context.Background(), "https://[::1]:8443/hook"), ErrNonPublicAddress)
+	assert.ErrorIs(t, CheckPublicURL(context.Background(), "https://localhost/hook"), ErrNonPublicAddress)
+}
@@ -34,6 +34,11 @@ resource "random_string" "suffix" {
   upper   = false
 }
 
+# Key encryption key for the access token signing keys stored in Cosmos DB
+resource "random_bytes" "jwt_key_encryption_key" {
+  length = 32
+}
+
 # Cosmos DB Account
 resource "azurerm_cosmosdb_account" "main" {
   name                = "${var.project_name}-db-${random_string.suffix.result}"
@@ -87,6 +92,140 @@ resource "azurerm_cosmosdb_sql_container" "complaints" {
   partition_key_paths = ["/userId"]
 }
 
+# Container: email-outbox (emails waiting to be delivered by the outbox worker, removed by per-item TTL a week
+# after they are sent or given up, and after 30 days at the latest)
+resource "azurerm_cosmosdb_sql_container" "email_outbox" {
+  name                = "email-outbox"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/id"]
+  default_ttl         = 2592000
+}
+
+# Container: notifications (in-app notification inbox)
+resource "azurerm_cosmosdb_sql_container" "notifications" {
+  name                = "notifications"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/userId"]
+}
+
+# Container: notification-preferences (per-user notification channels and delivery)
+resource "azurerm_cosmosdb_sql_container" "notification_preferences" {
+  name                = "notification-preferences"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/userId"]
+}
+
+# Container: digest-items (notifications waiting for the daily digest email)
+resource "azurerm_cosmosdb_sql_container" "digest_items" {
+  name                = "digest-items"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/userId"]
+}
+
+# Container: webhook-subscriptions (admin-registered outgoing webhooks)
+resource "azurerm_cosmosdb_sql_container" "webhook_subscriptions" {
+  name                = "webhook-subscriptions"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/id"]
+}
+
+# Container: webhook-deliveries (queued and attempted webhook deliveries)
+resource "azurerm_cosmosdb_sql_container" "webhook_deliveries" {
+  name                = "webhook-deliveries"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/subscriptionId"]
+}
+
+# Container: refresh-tokens (hashed refresh tokens, removed by per-item TTL after expiry)
+resource "azurerm_cosmosdb_sql_container" "refresh_tokens" {
+  name                = "refresh-tokens"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/id"]
+  default_ttl         = -1
+}
+
+# Container: signing-keys (access token signing keys, removed by per-item TTL once they stop verifying)
+resource "azurerm_cosmosdb_sql_container" "signing_keys" {
+  name                = "signing-keys"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/id"]
+  default_ttl         = -1
+}
+
+# Container: email-denylist (email addresses that may not register, managed by admins)
+resource "azurerm_cosmosdb_sql_container" "email_denylist" {
+  name                = "email-denylist"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/id"]
+}
+
+# Container: revoked-tokens (JWT IDs of revoked access tokens, removed by per-item TTL after expiry)
+resource "azurerm_cosmosdb_sql_container" "revoked_tokens" {
+  name                = "revoked-tokens"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/id"]
+  default_ttl         = -1
+}
+
+# Container: login-attempts (failed logins per email address, removed by per-item TTL once forgotten)
+resource "azurerm_cosmosdb_sql_container" "login_attempts" {
+  name                = "login-attempts"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/id"]
+  default_ttl         = -1
+}
+
+# Container: audit-log (admin actions on user accounts, partitioned by the affected user)
+resource "azurerm_cosmosdb_sql_container" "audit_log" {
+  name                = "audit-log"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/targetId"]
+}
+
+# Container: sessions (logins per device, partitioned by user, removed by per-item TTL once their refresh token expires)
+resource "azurerm_cosmosdb_sql_container" "sessions" {
+  name                = "sessions"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/userId"]
+  default_ttl         = -1
+}
+
+# Container: api-keys (hashes of personal API keys, removed by per-item TTL once they expire)
+resource "azurerm_cosmosdb_sql_container" "api_keys" {
+  name                = "api-keys"
+  resource_group_name = azurerm_resource_group.main.name
+  account_name        = azurerm_cosmosdb_account.main.name
+  database_name       = azurerm_cosmosdb_sql_database.main.name
+  partition_key_paths = ["/id"]
+  default_ttl         = -1
+}
+
 # Service Bus Namespace
 resource "azurerm_servicebus_namespace" "main" {
   name                = "${var.project_name}-bus-${random_string.suffix.result}"
@@ -120,6 +259,17 @@ resource "azurerm_servicebus_queue" "status_changed" {
   max_size_in_megabytes                = 1024
 }
 
+# Queue 3: For complaint escalations, scheduled for when a complaint's review deadline passes.
+# Each message carries its own TTL, and expired escalations are dropped rather than dead-lettered.
+resource "azurerm_servicebus_queue" "escalations" {
+  name         = "complaint-escalations"
+  namespace_id = azurerm_servicebus_namespace.main.id
+
+  default_message_ttl                  = "P14D"
+  dead_lettering_on_message_expiration = false
+  max_size_in_megabytes                = 1024
+}
+
 # ============================================================================
 # Azure Container Registry - for storing Docker images
 # ============================================================================
@@ -183,7 +333,8 @@ resource "azurerm_linux_web_app" "main" {
     "COSMOS_KEY"             = azurerm_cosmosdb_account.main.primary_key
     "COSMOS_DATABASE"        = azurerm_cosmosdb_sql_database.main.name
     "SERVICE_BUS_CONNECTION" = azurerm_servicebus_namespace.main.default_primary_connection_string
-    "JWT_SECRET"             = var.jwt_secret
+    "JWT_ALGORITHM"          = "EdDSA"
+    "JWT_KEY_ENCRYPTION_KEY" = random_bytes.jwt_key_encryption_key.base64
   }
 
   site_config {
@@ -16,16 +16,6 @@ variable "location" {
   default     = "Poland Central"
 }
 
-variable "jwt_secret" {
-  description = "JWT secret key for token signing (minimum 32 characters - keep this secret!)"
-  type        = string
-  sensitive   = true
-  validation {
-    condition     = length(var.jwt_secret) >= 32
-    error_message = "JWT secret must be at least 32 characters long."
-  }
-}
-
 variable "custom_domain" {
   description = "Custom domain for the app (e.g., complaints.yourdomain.com). Leave empty to skip."
   type        = string